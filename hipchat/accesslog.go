@@ -0,0 +1,72 @@
+package hipchat
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// WithAccessLog logs every request to the lifecycle endpoints to logger, with
+// its method, path, status, duration and, when known, the oauthId it concerns.
+func WithAccessLog(logger *log.Logger) IntegrationOption {
+	return func(i *Integration) {
+		i.accessLog = logger
+	}
+}
+
+// lifecycleWriter records what is needed to log and audit a lifecycle request.
+type lifecycleWriter struct {
+	http.ResponseWriter
+	status  int
+	code    string
+	oAuthID string
+	record  *InstallRecord
+}
+
+func (w *lifecycleWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *lifecycleWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// noteOAuthID records the oauthId a lifecycle request concerns.
+func noteOAuthID(w http.ResponseWriter, oAuthID string) {
+	if lw, ok := w.(*lifecycleWriter); ok {
+		lw.oAuthID = oAuthID
+	}
+}
+
+// noteRecord records the installation a lifecycle request concerns.
+func noteRecord(w http.ResponseWriter, record *InstallRecord) {
+	if lw, ok := w.(*lifecycleWriter); ok {
+		lw.oAuthID = record.OAuthID
+		lw.record = record
+	}
+}
+
+// observe runs the handler of a lifecycle request, then writes the access log
+// and records the audit event of the request.
+func (i *Integration) observe(eventType string, handler http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	lw := &lifecycleWriter{ResponseWriter: w}
+	handler(lw, r)
+	if lw.status == 0 {
+		lw.status = http.StatusOK
+	}
+
+	if i.accessLog != nil {
+		oAuthID := lw.oAuthID
+		if oAuthID == "" {
+			oAuthID = "-"
+		}
+		i.accessLog.Printf("%s %s %d %v oauthId=%s", r.Method, r.URL.Path, lw.status, time.Since(start), oAuthID)
+	}
+
+	i.recordEvent(eventType, r, lw)
+}
@@ -0,0 +1,57 @@
+package hipchat
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithAccessLog(t *testing.T) {
+	hipchat := newFakeHipChat(map[string]string{"oauth": "secret"})
+	defer hipchat.Close()
+	var buf bytes.Buffer
+	_, handler := newLifecycleIntegration(newMemoryStore(), hipchat, WithAccessLog(log.New(&buf, "", 0)))
+
+	serveLifecycle(handler, "POST", "/installed", &InstallRecord{
+		OAuthID:         "oauth",
+		OAuthSecret:     "secret",
+		GroupID:         2,
+		CapabilitiesURL: "https://api.hipchat.com/v2/capabilities",
+	})
+	serveLifecycle(handler, "DELETE", "/installed/oauth", nil)
+	serveLifecycle(handler, "DELETE", "/installed/unknown", nil)
+
+	want := []struct {
+		method  string
+		path    string
+		status  int
+		oAuthID string
+	}{
+		{"POST", "/installed", http.StatusOK, "oauth"},
+		{"DELETE", "/installed/oauth", http.StatusOK, "oauth"},
+		{"DELETE", "/installed/unknown", http.StatusNotFound, "unknown"},
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("Access log is %q, want %d lines", buf.String(), len(want))
+	}
+	line := regexp.MustCompile(`^(\S+) (\S+) (\d+) (\S+) oauthId=(\S+)$`)
+	for n, w := range want {
+		m := line.FindStringSubmatch(lines[n])
+		if m == nil {
+			t.Errorf("Access log line %q is malformed", lines[n])
+			continue
+		}
+		if m[1] != w.method || m[2] != w.path || m[3] != strconv.Itoa(w.status) || m[5] != w.oAuthID {
+			t.Errorf("Access log line is %q, want %s %s %d oauthId=%s", lines[n], w.method, w.path, w.status, w.oAuthID)
+		}
+		if d, err := time.ParseDuration(m[4]); err != nil || d <= 0 {
+			t.Errorf("Access log line %q has duration %s, want a positive duration", lines[n], m[4])
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package hipchat
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ActionPayload represents the parameters HipChat passes to the target of an
+// action, which the target page posts to the route of the action, e.g.:
+//
+//	HipChat.register({
+//		"receive-parameters": function(parameters) {
+//			HipChat.auth.withToken(function(err, token) {
+//				$.ajax({type: "POST", url: "/ticket", contentType: "application/json",
+//					headers: {Authorization: "JWT " + token}, data: JSON.stringify(parameters)});
+//			});
+//		}
+//	});
+type ActionPayload struct {
+	// Message is the message a message action was invoked on.
+	Message *ActionMessage `json:"message,omitempty"`
+	// Input is the text of the chat input an input action was invoked with.
+	Input string `json:"input,omitempty"`
+}
+
+// ActionMessage represents the message a message action was invoked on.
+type ActionMessage struct {
+	ID            string `json:"id"`
+	Body          string `json:"body"`
+	Date          string `json:"date,omitempty"`
+	MessageFormat string `json:"message_format,omitempty"`
+	From          *User  `json:"from,omitempty"`
+}
+
+// ActionHandler handles the invocation of an action, for the installation and
+// user given by params.
+type ActionHandler func(w http.ResponseWriter, r *http.Request, params *SignedParams, payload *ActionPayload)
+
+// AddAction declares an action in the descriptor, and serves at path,
+// relative to the routes of the integration, the callback its target posts
+// the ActionPayload to. handler is only called for the requests signed by
+// HipChat, see RequireSignedParams, with the decoded payload. Like web
+// panels, actions must be added before the integration serves requests.
+func (i *Integration) AddAction(path string, action ActionModule, handler ActionHandler) {
+	i.addModuleRoute(moduleRoute{
+		method: "POST",
+		path:   path,
+		handler: i.RequireSignedParams(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			params, _ := SignedParamsFromContext(r.Context())
+			i.limitBody(w, r)
+			payload := new(ActionPayload)
+			if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+				if isBodyTooLarge(err) {
+					writeError(w, http.StatusRequestEntityTooLarge, ErrorCodePayloadTooLarge, "The request is too large.")
+					return
+				}
+				writeError(w, http.StatusBadRequest, ErrorCodeBadPayload, err.Error())
+				return
+			}
+			handler(w, r, params, payload)
+		})),
+		declare: func(b *DescriptorBuilder, url string) {
+			b.AddAction(action)
+		},
+	})
+}
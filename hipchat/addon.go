@@ -0,0 +1,60 @@
+package hipchat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// AddOnService gives access to the add-on related methods of the API.
+type AddOnService struct {
+	client *Client
+}
+
+// UpdateRoomUI pushes glance updates to the users of a room. It requires a
+// token of the add-on installed in that room.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/update_room_addon_ui
+func (a *AddOnService) UpdateRoomUI(room string, update *RoomAddOnUIUpdateReq) (*http.Response, error) {
+	return a.UpdateRoomUIContext(context.Background(), room, update)
+}
+
+// UpdateRoomUIContext is like UpdateRoomUI, with ctx controlling the request.
+func (a *AddOnService) UpdateRoomUIContext(ctx context.Context, room string, update *RoomAddOnUIUpdateReq) (*http.Response, error) {
+	return a.updateUI(ctx, fmt.Sprintf("addon/ui/room/%s", room), update)
+}
+
+// UpdateGroupUI pushes glance updates to all the rooms of the group the
+// add-on is installed in. It requires a token of a globally installed add-on.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/update_global_addon_ui
+func (a *AddOnService) UpdateGroupUI(update *RoomAddOnUIUpdateReq) (*http.Response, error) {
+	return a.UpdateGroupUIContext(context.Background(), update)
+}
+
+// UpdateGroupUIContext is like UpdateGroupUI, with ctx controlling the request.
+func (a *AddOnService) UpdateGroupUIContext(ctx context.Context, update *RoomAddOnUIUpdateReq) (*http.Response, error) {
+	return a.updateUI(ctx, "addon/ui", update)
+}
+
+// UpdateUserUI pushes glance updates to a single user, who sees them in every
+// room the add-on is installed in.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/update_user_addon_ui
+func (a *AddOnService) UpdateUserUI(user string, update *RoomAddOnUIUpdateReq) (*http.Response, error) {
+	return a.UpdateUserUIContext(context.Background(), user, update)
+}
+
+// UpdateUserUIContext is like UpdateUserUI, with ctx controlling the request.
+func (a *AddOnService) UpdateUserUIContext(ctx context.Context, user string, update *RoomAddOnUIUpdateReq) (*http.Response, error) {
+	return a.updateUI(ctx, fmt.Sprintf("addon/ui/user/%s", user), update)
+}
+
+func (a *AddOnService) updateUI(ctx context.Context, urlStr string, update *RoomAddOnUIUpdateReq) (*http.Response, error) {
+	req, err := a.client.NewRequest("POST", urlStr, nil, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.client.DoContext(ctx, req, nil)
+}
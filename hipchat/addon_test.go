@@ -0,0 +1,62 @@
+package hipchat
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func testAddOnUIUpdate(t *testing.T, path string, update func(*RoomAddOnUIUpdateReq) (*http.Response, error)) {
+	setup()
+	defer teardown()
+
+	glance := NewGlanceUpdate("g", "3 <b>builds</b>")
+	glance.SetLozenge(LozengeTypeSuccess, "ok")
+	glance.SetMetadata("failed", false)
+	args := &RoomAddOnUIUpdateReq{Glances: []GlanceUpdate{glance}}
+
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		var got interface{}
+		json.NewDecoder(r.Body).Decode(&got)
+		var want interface{}
+		json.Unmarshal([]byte(`{"glance":[{"key":"g","content":{
+			"label":{"type":"html","value":"3 <b>builds</b>"},
+			"status":{"type":"lozenge","value":{"type":"success","label":"ok"}},
+			"metadata":{"failed":false}
+		}}]}`), &want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Request body %+v, want %+v", got, want)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if _, err := update(args); err != nil {
+		t.Fatalf("AddOn UI update returns an error %v", err)
+	}
+}
+
+func TestAddOnUpdateRoomUI(t *testing.T) {
+	testAddOnUIUpdate(t, "/addon/ui/room/1", func(req *RoomAddOnUIUpdateReq) (*http.Response, error) {
+		return client.AddOn.UpdateRoomUI("1", req)
+	})
+}
+
+func TestAddOnUpdateGroupUI(t *testing.T) {
+	testAddOnUIUpdate(t, "/addon/ui", func(req *RoomAddOnUIUpdateReq) (*http.Response, error) {
+		return client.AddOn.UpdateGroupUI(req)
+	})
+}
+
+func TestAddOnUpdateUserUI(t *testing.T) {
+	testAddOnUIUpdate(t, "/addon/ui/user/u@example.com", func(req *RoomAddOnUIUpdateReq) (*http.Response, error) {
+		return client.AddOn.UpdateUserUI("u@example.com", req)
+	})
+}
+
+func TestRoomAddOnUIUpdate(t *testing.T) {
+	testAddOnUIUpdate(t, "/addon/ui/room/1", func(req *RoomAddOnUIUpdateReq) (*http.Response, error) {
+		return client.Room.RoomAddOnUIUpdate("1", req)
+	})
+}
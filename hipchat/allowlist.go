@@ -0,0 +1,158 @@
+package hipchat
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultHipChatHost is the domain of HipChat cloud, accepted in strict mode
+// unless other HipChat hosts are configured.
+const DefaultHipChatHost = "hipchat.com"
+
+// WithHipChatHosts sets the HipChat domains that capabilitiesUrl must belong to
+// in strict mode, replacing DefaultHipChatHost. Use it to accept installations
+// from a HipChat Server or Data Center host, e.g. "hipchat.example.com".
+// Subdomains of the given hosts are accepted as well.
+func WithHipChatHosts(hosts ...string) IntegrationOption {
+	return func(i *Integration) {
+		i.hipChatHosts = hosts
+	}
+}
+
+// WithAllowedHosts only accepts installations whose capabilitiesUrl is served
+// from one of the given hosts, e.g. "api.hipchat.com". Updates and removals
+// are checked against the capabilitiesUrl of the stored installation when
+// they carry none.
+func WithAllowedHosts(hosts ...string) IntegrationOption {
+	return func(i *Integration) {
+		if i.allowedHosts == nil {
+			i.allowedHosts = make(map[string]bool)
+		}
+		for _, host := range hosts {
+			i.allowedHosts[strings.ToLower(host)] = true
+		}
+	}
+}
+
+// WithAllowedCIDRs only accepts /installed and /updated requests, and the
+// removal of installations, coming from addresses in the given CIDR ranges,
+// e.g. "10.0.0.0/8". The address of a request is taken from
+// http.Request.RemoteAddr.
+// It panics if a range cannot be parsed.
+func WithAllowedCIDRs(cidrs ...string) IntegrationOption {
+	return func(i *Integration) {
+		for _, cidr := range cidrs {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				panic(err)
+			}
+			i.allowedNetworks = append(i.allowedNetworks, network)
+		}
+	}
+}
+
+// ErrTenantNotAllowed is returned by ParseSignedParams for tokens of an
+// installation or group that is not allowed.
+var ErrTenantNotAllowed = errors.New("tenant is not allowed")
+
+// WithAllowedIssuers only accepts signed requests whose token was issued for
+// one of the given installations, identified by their oauthId.
+func WithAllowedIssuers(oauthIDs ...string) IntegrationOption {
+	return func(i *Integration) {
+		if i.allowedIssuers == nil {
+			i.allowedIssuers = make(map[string]bool)
+		}
+		for _, oauthID := range oauthIDs {
+			i.allowedIssuers[oauthID] = true
+		}
+	}
+}
+
+// WithAllowedGroups only accepts signed requests coming from one of the given
+// HipChat groups. The group of tokens without a group_id is read from the
+// installation record in the Store.
+func WithAllowedGroups(groupIDs ...uint32) IntegrationOption {
+	return func(i *Integration) {
+		if i.allowedGroups == nil {
+			i.allowedGroups = make(map[uint32]bool)
+		}
+		for _, groupID := range groupIDs {
+			i.allowedGroups[groupID] = true
+		}
+	}
+}
+
+// checkTenant verifies that signed params come from an allowed installation
+// and group.
+func (i *Integration) checkTenant(params *SignedParams) error {
+	if i.allowedIssuers != nil && !i.allowedIssuers[params.Issuer] {
+		return ErrTenantNotAllowed
+	}
+	if i.allowedGroups != nil {
+		groupID := params.GroupID
+		if groupID == 0 {
+			record, err := i.Store.GetCredentialsByOAuthID(params.Issuer)
+			if err != nil {
+				return err
+			}
+			if record == nil {
+				return ErrTenantNotAllowed
+			}
+			groupID = uint32(record.GroupID)
+		}
+		if !i.allowedGroups[groupID] {
+			return ErrTenantNotAllowed
+		}
+	}
+	return nil
+}
+
+// checkSource verifies that a lifecycle request for an installation with the
+// given capabilitiesUrl comes from an allowed source.
+func (i *Integration) checkSource(r *http.Request, capabilitiesURL string) error {
+	if len(i.allowedNetworks) > 0 {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		ip := net.ParseIP(host)
+		allowed := false
+		for _, network := range i.allowedNetworks {
+			if ip != nil && network.Contains(ip) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("Requests from %v are not allowed", r.RemoteAddr)
+		}
+	}
+
+	if len(i.allowedHosts) > 0 {
+		u, err := url.Parse(capabilitiesURL)
+		if err != nil || !i.allowedHosts[strings.ToLower(u.Hostname())] {
+			return fmt.Errorf("Capabilities URL %q is not allowed", capabilitiesURL)
+		}
+	}
+	return nil
+}
+
+// checkHipChatHost verifies that capabilitiesURL is served by a HipChat host.
+func (i *Integration) checkHipChatHost(capabilitiesURL string) error {
+	u, err := url.Parse(capabilitiesURL)
+	if err != nil {
+		return err
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range i.hipChatHosts {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return nil
+		}
+	}
+	return fmt.Errorf("Capabilities URL %q is not served by a HipChat host", capabilitiesURL)
+}
@@ -0,0 +1,53 @@
+package hipchat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAllowedTenants(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []IntegrationOption
+		groupID    interface{}
+		wantStatus int
+	}{
+		{"no allowlist", nil, 2, http.StatusOK},
+		{"allowed issuer", []IntegrationOption{WithAllowedIssuers("other", "oauth")}, 2, http.StatusOK},
+		{"other issuer", []IntegrationOption{WithAllowedIssuers("other")}, 2, http.StatusForbidden},
+		{"allowed group", []IntegrationOption{WithAllowedGroups(2)}, 2, http.StatusOK},
+		{"other group", []IntegrationOption{WithAllowedGroups(5)}, 2, http.StatusForbidden},
+		{"allowed group of the installation", []IntegrationOption{WithAllowedGroups(2)}, nil, http.StatusOK},
+		{"other group of the installation", []IntegrationOption{WithAllowedGroups(5)}, nil, http.StatusForbidden},
+		{"allowed issuer of other group", []IntegrationOption{WithAllowedIssuers("oauth"), WithAllowedGroups(5)}, 2, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params []*SignedParams
+			_, handler := newSignedIntegration(&params, tt.opts...)
+			r := httptest.NewRequest("GET", "/panel", nil)
+			claims := testClaims("oauth")
+			claimsContext := claims["context"].(map[string]interface{})
+			if tt.groupID == nil {
+				delete(claimsContext, "group_id")
+			} else {
+				claimsContext["group_id"] = tt.groupID
+			}
+			signTestRequest(t, r, claims, "secret")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("GET /panel answered %d %s, want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if w.Code == http.StatusForbidden && !strings.Contains(w.Body.String(), ErrorCodeForbiddenTenant) {
+				t.Errorf("GET /panel answered %s, want %s", w.Body, ErrorCodeForbiddenTenant)
+			}
+			if reached := len(params) == 1; reached != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("Request reached the module is %v", reached)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package hipchat
+
+import (
+	"net/http"
+	"time"
+)
+
+// Types of AuditEvent.
+const (
+	AuditEventInstalled = "installed"
+	AuditEventUpdated   = "updated"
+	AuditEventRemoved   = "removed"
+)
+
+// AuditResultOK is the Result of successful AuditEvents. Failed events carry
+// the error code of the response instead, e.g. ErrorCodeStoreError.
+const AuditResultOK = "ok"
+
+// AuditEvent records a lifecycle request received for an installation.
+type AuditEvent struct {
+	Type    string
+	Time    time.Time
+	OAuthID string
+	GroupID uint64
+	// RoomID is nil for global installations, or when the installation is unknown.
+	RoomID *uint64
+	// Source is the remote address the request came from.
+	Source string
+	Result string
+}
+
+// AuditStore is implemented by Stores that keep an audit trail of installations.
+// When the Store of an Integration is an AuditStore, every install, update and
+// removal request is recorded.
+type AuditStore interface {
+	RecordEvent(event *AuditEvent) error
+	// ListEvents returns the events of a group, oldest first.
+	ListEvents(groupID uint32) ([]*AuditEvent, error)
+}
+
+// recordEvent saves the audit event of a lifecycle request, if the Store supports it.
+func (i *Integration) recordEvent(eventType string, r *http.Request, lw *lifecycleWriter) {
+	store, ok := i.Store.(AuditStore)
+	if !ok {
+		return
+	}
+
+	event := &AuditEvent{
+		Type:    eventType,
+		Time:    time.Now(),
+		OAuthID: lw.oAuthID,
+		Source:  r.RemoteAddr,
+		Result:  AuditResultOK,
+	}
+	if lw.record != nil {
+		event.GroupID = lw.record.GroupID
+		event.RoomID = lw.record.RoomID
+	}
+	if lw.status >= 400 {
+		event.Result = lw.code
+	}
+
+	if err := store.RecordEvent(event); err != nil {
+		i.logger.Printf("Error recording %v event for %v: %v", eventType, event.OAuthID, err)
+	}
+}
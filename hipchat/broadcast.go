@@ -0,0 +1,190 @@
+package hipchat
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DefaultBroadcastConcurrency is the number of installations updated at once
+// by BroadcastGlanceUpdate when no concurrency is given.
+const DefaultBroadcastConcurrency = 4
+
+// ListingStore is implemented by Stores that can enumerate their
+// installations. BroadcastGlanceUpdate requires the Store of the Integration
+// to be a ListingStore.
+type ListingStore interface {
+	// ListCredentials returns all the installations of the add-on.
+	ListCredentials() ([]*InstallRecord, error)
+}
+
+// BroadcastError reports the failure to reach one installation, or one room of
+// a global installation.
+type BroadcastError struct {
+	Record *InstallRecord
+	// Room is the room that could not be reached, when it is not the room of
+	// the installation.
+	Room string
+	Err  error
+}
+
+func (e *BroadcastError) Error() string {
+	switch {
+	case e.Room != "":
+		return fmt.Sprintf("room %s: %v", e.Room, e.Err)
+	case e.Record.IsGlobal():
+		return fmt.Sprintf("group %v: %v", e.Record.GroupID, e.Err)
+	}
+	return fmt.Sprintf("room %v: %v", *e.Record.RoomID, e.Err)
+}
+
+func (e *BroadcastError) Unwrap() error {
+	return e.Err
+}
+
+// broadcastTarget is an installation a broadcast is sent to, or with room set,
+// a room of a global installation.
+type broadcastTarget struct {
+	record *InstallRecord
+	room   string
+}
+
+// BroadcastGlanceUpdate pushes update to every installation of the add-on: to
+// the room of room installations, and to all the rooms of the group of global
+// installations. At most concurrency installations, or
+// DefaultBroadcastConcurrency if it is zero or less, are updated at once.
+//
+// An error is returned if the installations cannot be listed. Otherwise every
+// installation is attempted and the failed ones are reported, in no
+// particular order, as BroadcastErrors.
+func (i *Integration) BroadcastGlanceUpdate(update *RoomAddOnUIUpdateReq, concurrency int) ([]*BroadcastError, error) {
+	records, err := i.listInstallations()
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]broadcastTarget, len(records))
+	for n, record := range records {
+		targets[n] = broadcastTarget{record: record}
+	}
+
+	return i.fanOut(targets, concurrency, func(t broadcastTarget, client *Client) error {
+		var err error
+		if t.record.IsGlobal() {
+			_, err = client.AddOn.UpdateGroupUI(update)
+		} else {
+			_, err = client.AddOn.UpdateRoomUI(fmt.Sprint(*t.record.RoomID), update)
+		}
+		return err
+	}), nil
+}
+
+// NotifyAll sends a notification to every room the add-on is installed in:
+// the room of room installations, and all the rooms of the group of global
+// installations, each room being notified once. At most concurrency requests,
+// or DefaultBroadcastConcurrency if it is zero or less, are made at once.
+//
+// An error is returned if the installations cannot be listed. Otherwise every
+// room is attempted and the failures, including groups whose rooms cannot be
+// listed, are reported in no particular order as BroadcastErrors.
+func (i *Integration) NotifyAll(notifReq *NotificationRequest, concurrency int) ([]*BroadcastError, error) {
+	records, err := i.listInstallations()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu      sync.Mutex
+		targets []broadcastTarget
+		globals []broadcastTarget
+	)
+	installed := make(map[string]bool)
+	for _, record := range records {
+		if record.IsGlobal() {
+			globals = append(globals, broadcastTarget{record: record})
+			continue
+		}
+		targets = append(targets, broadcastTarget{record: record})
+		installed[tokenKey(record.GroupID, record.RoomID)] = true
+	}
+
+	failed := i.fanOut(globals, concurrency, func(t broadcastTarget, client *Client) error {
+		it := client.Room.Iterate(nil)
+		for it.Next() {
+			roomID := uint64(it.Room().ID)
+			if installed[tokenKey(t.record.GroupID, &roomID)] {
+				continue
+			}
+			mu.Lock()
+			targets = append(targets, broadcastTarget{record: t.record, room: fmt.Sprint(roomID)})
+			mu.Unlock()
+		}
+		return it.Err()
+	})
+
+	failed = append(failed, i.fanOut(targets, concurrency, func(t broadcastTarget, client *Client) error {
+		room := t.room
+		if room == "" {
+			room = fmt.Sprint(*t.record.RoomID)
+		}
+		_, err := client.Room.Notification(room, notifReq)
+		return err
+	})...)
+	return failed, nil
+}
+
+// listInstallations returns all the installations of the add-on.
+func (i *Integration) listInstallations() ([]*InstallRecord, error) {
+	store, ok := i.Store.(ListingStore)
+	if !ok {
+		return nil, fmt.Errorf("Store %T cannot list installations", i.Store)
+	}
+	return store.ListCredentials()
+}
+
+// fanOut calls send for each target, with a client authenticated as its
+// installation, running at most concurrency calls at once.
+func (i *Integration) fanOut(targets []broadcastTarget, concurrency int, send func(t broadcastTarget, client *Client) error) []*BroadcastError {
+	if concurrency <= 0 {
+		concurrency = DefaultBroadcastConcurrency
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []*BroadcastError
+	)
+	sem := make(chan struct{}, concurrency)
+	for _, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(t broadcastTarget) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			client, err := i.installationClient(t.record)
+			if err == nil {
+				err = send(t, client)
+			}
+			if err != nil {
+				mu.Lock()
+				failed = append(failed, &BroadcastError{Record: t.record, Room: t.room, Err: err})
+				mu.Unlock()
+			}
+		}(target)
+	}
+	wg.Wait()
+	return failed
+}
+
+// installationClient returns a client authenticated with the token of an
+// installation.
+func (i *Integration) installationClient(record *InstallRecord) (*Client, error) {
+	token, ok := i.tokens.Get(tokenKey(record.GroupID, record.RoomID))
+	if !ok {
+		var err error
+		if token, err = i.getToken(record); err != nil {
+			return nil, err
+		}
+	}
+	return i.newClient(token)
+}
@@ -0,0 +1,173 @@
+package hipchat
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type listingStore struct {
+	Store
+	records []*InstallRecord
+}
+
+func (s *listingStore) ListCredentials() ([]*InstallRecord, error) {
+	return s.records, nil
+}
+
+// rewriteTransport sends all requests to the server at target.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestBroadcastGlanceUpdate(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths = make(map[string]string)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths[r.URL.Path] = r.Header.Get("Authorization")
+		mu.Unlock()
+		if r.URL.Path == "/v2/addon/ui/room/3" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	room1, room3 := uint64(1), uint64(3)
+	store := &listingStore{records: []*InstallRecord{
+		{OAuthID: "a", GroupID: 10, RoomID: &room1},
+		{OAuthID: "b", GroupID: 20},
+		{OAuthID: "c", GroupID: 30, RoomID: &room3},
+	}}
+	tokens := NewMemoryTokenCache()
+	tokens.Set("10:1", "t1", time.Time{})
+	tokens.Set("20:", "t2", time.Time{})
+	tokens.Set("30:3", "t3", time.Time{})
+	i := NewIntegration(store, WithTokenCache(tokens),
+		WithHTTPClient(&http.Client{Transport: rewriteTransport{target}}))
+
+	failed, err := i.BroadcastGlanceUpdate(&RoomAddOnUIUpdateReq{Glances: []GlanceUpdate{NewGlanceUpdate("g", "l")}}, 2)
+	if err != nil {
+		t.Fatalf("BroadcastGlanceUpdate returned an error %v", err)
+	}
+	want := map[string]string{
+		"/v2/addon/ui/room/1": "Bearer t1",
+		"/v2/addon/ui":        "Bearer t2",
+		"/v2/addon/ui/room/3": "Bearer t3",
+	}
+	for path, auth := range want {
+		if paths[path] != auth {
+			t.Errorf("%s was called with Authorization %q, want %q", path, paths[path], auth)
+		}
+	}
+	if len(failed) != 1 || failed[0].Record.OAuthID != "c" {
+		t.Fatalf("BroadcastGlanceUpdate failed %v, want only room 3", failed)
+	}
+}
+
+func TestBroadcastGlanceUpdate_APIBaseURL(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := &listingStore{records: []*InstallRecord{{OAuthID: "b", GroupID: 20}}}
+	tokens := NewMemoryTokenCache()
+	tokens.Set("20:", "t2", time.Time{})
+	i := NewIntegration(store, WithTokenCache(tokens), WithAPIBaseURL(server.URL+"/chat/v2"))
+
+	failed, err := i.BroadcastGlanceUpdate(&RoomAddOnUIUpdateReq{}, 0)
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("BroadcastGlanceUpdate returned %v, %v", failed, err)
+	}
+	if want := "/chat/v2/addon/ui"; path != want {
+		t.Errorf("BroadcastGlanceUpdate called %v, want %v", path, want)
+	}
+}
+
+func TestBroadcastGlanceUpdate_NotListingStore(t *testing.T) {
+	i := NewIntegration(nil)
+	if _, err := i.BroadcastGlanceUpdate(&RoomAddOnUIUpdateReq{}, 0); err == nil {
+		t.Error("BroadcastGlanceUpdate did not return an error")
+	}
+}
+
+func TestBroadcastError(t *testing.T) {
+	room := uint64(3)
+	err := &BroadcastError{Record: &InstallRecord{GroupID: 1, RoomID: &room}, Err: errTest}
+	if err.Error() != "room 3: test" || !errors.Is(err, errTest) {
+		t.Errorf("BroadcastError is %q", err)
+	}
+}
+
+var errTest = errors.New("test")
+
+func TestNotifyAll(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		notified = make(map[string]string)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/v2/room":
+			fmt.Fprint(w, `{"items":[{"id":4}, {"id":5}], "links":{}}`)
+		case r.URL.Path == "/v2/room/5/notification":
+			w.WriteHeader(http.StatusForbidden)
+		case strings.HasSuffix(r.URL.Path, "/notification"):
+			notified[r.URL.Path] = r.Header.Get("Authorization")
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	room1, room4 := uint64(1), uint64(4)
+	store := &listingStore{records: []*InstallRecord{
+		{OAuthID: "a", GroupID: 10, RoomID: &room1},
+		{OAuthID: "b", GroupID: 20},
+		{OAuthID: "c", GroupID: 20, RoomID: &room4},
+	}}
+	tokens := NewMemoryTokenCache()
+	tokens.Set("10:1", "t1", time.Time{})
+	tokens.Set("20:", "t2", time.Time{})
+	tokens.Set("20:4", "t4", time.Time{})
+	i := NewIntegration(store, WithTokenCache(tokens), WithAPIBaseURL(server.URL+"/v2/"))
+
+	failed, err := i.NotifyAll(&NotificationRequest{Message: "Released!"}, 0)
+	if err != nil {
+		t.Fatalf("NotifyAll returned an error %v", err)
+	}
+	want := map[string]string{
+		"/v2/room/1/notification": "Bearer t1",
+		"/v2/room/4/notification": "Bearer t4",
+	}
+	if !reflect.DeepEqual(notified, want) {
+		t.Errorf("NotifyAll notified %v, want %v", notified, want)
+	}
+	if len(failed) != 1 || failed[0].Room != "5" || !errors.Is(failed[0], ErrForbidden) {
+		t.Errorf("NotifyAll failed %v, want only room 5", failed)
+	}
+}
@@ -1,15 +1,23 @@
 package hipchat
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"path"
 	"strings"
+	"sync"
+	"time"
 
 	gorillaMux "github.com/gorilla/mux"
-	"github.com/dgrijalva/jwt-go"
 )
 
 // InstallRecord represents the structure sent to /installed for unmarshalling.
@@ -18,36 +26,167 @@ type InstallRecord struct {
 	OAuthID         string `json:"oauthId"`
 	OAuthSecret     string `json:"oauthSecret"`
 	GroupID         uint64 `json:"groupId"`
-	RoomID          uint64 `json:"roomId"`
+	// RoomID is nil when the integration is installed globally for the group.
+	RoomID *uint64 `json:"roomId,omitempty"`
 }
 
+// IsGlobal reports whether the record is a group-level installation.
+func (r *InstallRecord) IsGlobal() bool {
+	return r.RoomID == nil
+}
+
+// Equal reports whether both records describe the same installation.
+func (r *InstallRecord) Equal(other *InstallRecord) bool {
+	if r.IsGlobal() != other.IsGlobal() {
+		return false
+	}
+	if !r.IsGlobal() && *r.RoomID != *other.RoomID {
+		return false
+	}
+	return r.CapabilitiesURL == other.CapabilitiesURL &&
+		r.OAuthID == other.OAuthID &&
+		r.OAuthSecret == other.OAuthSecret &&
+		r.GroupID == other.GroupID
+}
+
+// validateInstallRecord checks that an installation payload has all the fields
+// required to complete the installation.
+func validateInstallRecord(r *InstallRecord) error {
+	switch {
+	case r.OAuthID == "":
+		return errors.New("Missing oauthId")
+	case r.OAuthSecret == "":
+		return errors.New("Missing oauthSecret")
+	case r.CapabilitiesURL == "":
+		return errors.New("Missing capabilitiesUrl")
+	case r.GroupID == 0:
+		return errors.New("Missing groupId")
+	}
+	return nil
+}
+
+// tokenKey returns the key of an installation's token, "groupid:roomid" for room
+// installations or "groupid:" for global installations.
+func tokenKey(groupID uint64, roomID *uint64) string {
+	if roomID == nil {
+		return fmt.Sprintf("%v:", groupID)
+	}
+	return fmt.Sprintf("%v:%v", groupID, *roomID)
+}
+
+// DefaultMaxBodyBytes is the default limit on the size of request bodies read
+// by the lifecycle handlers and ParseSignedParams.
+const DefaultMaxBodyBytes = 1 << 20
+
 // Integration stores state shared by callback handler functions
 type Integration struct {
-	Store                 Store
+	Store Store
+	// bodyLimit limits the size of incoming request bodies. Larger payloads
+	// are rejected with 413 Request Entity Too Large. Zero means DefaultMaxBodyBytes.
+	bodyLimit int64
+	// synchronous makes the lifecycle handlers complete installations and run
+	// callbacks before responding, instead of in background goroutines.
+	synchronous bool
+	// callbackTimeout bounds how long a synchronous callback is waited for.
+	// Zero means no timeout. Callbacks that overrun it are not stopped.
+	callbackTimeout       time.Duration
+	pending               sync.WaitGroup
+	mu                    sync.Mutex
+	closing               bool
+	installing            map[string]*installLock
 	installationCallbacks []func()
 	updatedCallbacks      []func()
-	removedCallbacks      []func()
+	removedCallbacks      []func(oAuthID string, record *InstallRecord)
+	preSaveHooks          []func(record *InstallRecord) error
+	postSaveHooks         []func(record *InstallRecord)
+	preDeleteHooks        []func(oAuthID string, record *InstallRecord) error
+	postDeleteHooks       []func(oAuthID string, record *InstallRecord)
 	handler               http.Handler
-	tokens                map[string]string // Key is "groupid:roomid"
+	router                *gorillaMux.Router
+	moduleRoutes          []moduleRoute
+	settings              SettingsStore
+	tokens                TokenCache
+	scopes                []string
+	baseURL               string
+	forwardedProto        bool
+	apiBaseURL            string
+	rateLimiter           *RateLimiter
+	routePrefix           string
+	strict                bool
+	hipChatHosts          []string
+	logger                *log.Logger
+	httpClient            *http.Client
+	tlsConfig             *tls.Config
+	proxyURL              *url.URL
+	accessLog             *log.Logger
+	allowedHosts          map[string]bool
+	allowedNetworks       []*net.IPNet
+	checkAPI              bool
+	readinessTimeout      time.Duration
+	addOnKey              string
+	addOnName             string
+	addOnDescription      string
+	allowGlobal           bool
+	allowRoom             bool
+	descriptorModules     func(b *DescriptorBuilder, baseURL string)
+	descriptorFunc        func(r *http.Request) *Descriptor
+	skipQSH               bool
+	clockSkew             time.Duration
+	replayGuard           ReplayGuard
+	sessionSecret         []byte
+	keyResolver           KeyResolver
+	tokenCookie           string
+	allowedIssuers        map[string]bool
+	allowedGroups         map[uint32]bool
 }
 
-// NewIntegration returns a pointer to a Integration that uses the provided Store.
-func NewIntegration(store Store) *Integration {
+// NewIntegration returns a pointer to a Integration that uses the provided Store,
+// configured by the given options.
+func NewIntegration(store Store, opts ...IntegrationOption) *Integration {
 	c := Integration{
-		Store: store,
+		Store:                 store,
 		installationCallbacks: make([]func(), 0),
 		updatedCallbacks:      make([]func(), 0),
-		removedCallbacks:      make([]func(), 0),
-		tokens:                make(map[string]string),
+		removedCallbacks:      make([]func(string, *InstallRecord), 0),
+		tokens:                NewMemoryTokenCache(),
+		settings:              NewMemorySettingsStore(),
+		scopes:                []string{},
+		logger:                log.New(os.Stderr, "", log.LstdFlags),
+		httpClient:            http.DefaultClient,
+		hipChatHosts:          []string{DefaultHipChatHost},
+		apiBaseURL:            defaultBaseURL,
+		allowGlobal:           true,
+		allowRoom:             true,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.tlsConfig != nil || c.proxyURL != nil {
+		httpClient, err := withTransport(c.httpClient, func(t *http.Transport) {
+			if c.tlsConfig != nil {
+				t.TLSClientConfig = c.tlsConfig
+			}
+			if c.proxyURL != nil {
+				t.Proxy = http.ProxyURL(c.proxyURL)
+			}
+		})
+		if err != nil {
+			panic(err)
+		}
+		c.httpClient = httpClient
 	}
 
 	mux := gorillaMux.NewRouter()
-	mux.Path("/installed").Methods("POST").HandlerFunc(c.handleInstalled)
-	//mux.HandleFunc("/installed", c.handleInstalled)
-	mux.Path("/installed/{oAuthId}").Methods("DELETE").HandlerFunc(c.handleRemoved)
-	mux.HandleFunc("/updated", c.handleUpdated)
+	router := mux
+	if c.routePrefix != "" {
+		router = mux.PathPrefix(c.routePrefix).Subrouter()
+	}
+	for _, route := range c.Routes() {
+		router.Path(route.Pattern).Methods(route.Method).HandlerFunc(route.Handler)
+	}
 
 	c.handler = mux
+	c.router = router
 
 	return &c
 }
@@ -57,6 +196,61 @@ func (i *Integration) GetHandler() http.Handler {
 	return i.handler
 }
 
+// Route describes a single lifecycle endpoint served by an Integration.
+type Route struct {
+	Method string
+	// Pattern is the path of the endpoint, with parameters in {name} form.
+	Pattern string
+	Handler http.HandlerFunc
+}
+
+// EchoPattern returns the route pattern using echo's :name parameter syntax.
+func (r Route) EchoPattern() string {
+	segments := strings.Split(r.Pattern, "/")
+	for n, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[n] = ":" + segment[1:len(segment)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Routes returns the endpoints served by the integration, so they can be
+// registered on an existing router instead of mounting GetHandler.
+//
+// For example, with echo:
+//
+//	for _, route := range integration.Routes() {
+//		e.Add(route.Method, route.EchoPattern(), echo.WrapHandler(route.Handler))
+//	}
+func (i *Integration) Routes() []Route {
+	routes := []Route{
+		{Method: "POST", Pattern: "/installed", Handler: i.HandleInstalled},
+		{Method: "DELETE", Pattern: "/installed/{oAuthId}", Handler: i.HandleRemoved},
+		{Method: "POST", Pattern: "/updated", Handler: i.HandleUpdated},
+		{Method: "GET", Pattern: "/capabilities", Handler: i.HandleDescriptor},
+		{Method: "GET", Pattern: "/atlassian-connect.json", Handler: i.HandleDescriptor},
+		{Method: "GET", Pattern: "/healthz", Handler: i.HandleHealthz},
+		{Method: "GET", Pattern: "/readyz", Handler: i.HandleReadyz},
+	}
+	for _, m := range i.moduleRoutes {
+		routes = append(routes, m.route())
+	}
+	return routes
+}
+
+// ChiRouter is the subset of chi.Router used by RegisterChi.
+type ChiRouter interface {
+	MethodFunc(method, pattern string, h http.HandlerFunc)
+}
+
+// RegisterChi registers the lifecycle endpoints of the integration on a chi router.
+func (i *Integration) RegisterChi(r ChiRouter) {
+	for _, route := range i.Routes() {
+		r.MethodFunc(route.Method, route.Pattern, route.Handler)
+	}
+}
+
 // AddInstallationCallback adds a callback that will be called when the integration is installed.
 func (i *Integration) AddInstallationCallback(callback func()) {
 	i.installationCallbacks = append(i.installationCallbacks, callback)
@@ -68,86 +262,495 @@ func (i *Integration) AddUpdatedCallback(callback func()) {
 }
 
 // AddRemovedCallback adds a callback that will be called when the integration is uninstalled.
-func (i *Integration) AddRemovedCallback(callback func()) {
+// The callback receives the oauthId of the removed installation and its record, as
+// loaded from the Store before deletion.
+func (i *Integration) AddRemovedCallback(callback func(oAuthID string, record *InstallRecord)) {
 	i.removedCallbacks = append(i.removedCallbacks, callback)
 }
 
+// AddPreSaveHook adds a hook that runs before an installation is saved to the Store.
+// The hook may modify the record; returning an error vetoes the installation,
+// which is answered with 403 Forbidden.
+func (i *Integration) AddPreSaveHook(hook func(record *InstallRecord) error) {
+	i.preSaveHooks = append(i.preSaveHooks, hook)
+}
+
+// AddPostSaveHook adds a hook that runs after an installation was saved to the Store.
+func (i *Integration) AddPostSaveHook(hook func(record *InstallRecord)) {
+	i.postSaveHooks = append(i.postSaveHooks, hook)
+}
+
+// AddPreDeleteHook adds a hook that runs before an installation is deleted from
+// the Store. Returning an error vetoes the deletion.
+func (i *Integration) AddPreDeleteHook(hook func(oAuthID string, record *InstallRecord) error) {
+	i.preDeleteHooks = append(i.preDeleteHooks, hook)
+}
+
+// AddPostDeleteHook adds a hook that runs after an installation was deleted from the Store.
+func (i *Integration) AddPostDeleteHook(hook func(oAuthID string, record *InstallRecord)) {
+	i.postDeleteHooks = append(i.postDeleteHooks, hook)
+}
+
+// Error codes of the JSON error responses written by the lifecycle handlers.
+const (
+	ErrorCodeAPIUnavailable   = "api_unavailable"
+	ErrorCodeBadCSRFToken     = "bad_csrf_token"
+	ErrorCodeBadDescriptor    = "bad_descriptor"
+	ErrorCodeBadPayload       = "bad_payload"
+	ErrorCodeForbiddenSource  = "forbidden_source"
+	ErrorCodeForbiddenTenant  = "forbidden_tenant"
+	ErrorCodeInvalidToken     = "invalid_token"
+	ErrorCodeMethodNotAllowed = "method_not_allowed"
+	ErrorCodeMissingToken     = "missing_token"
+	ErrorCodeNotFound         = "not_found"
+	ErrorCodePayloadTooLarge  = "payload_too_large"
+	ErrorCodeRejected         = "rejected"
+	ErrorCodeShuttingDown     = "shutting_down"
+	ErrorCodeStoreError       = "store_error"
+	ErrorCodeStoreUnavailable = "store_unavailable"
+	ErrorCodeTokenExpired     = "token_expired"
+	ErrorCodeUnexpectedHost   = "unexpected_host"
+)
+
+// LifecycleError is the error reported in the body of failed lifecycle requests,
+// as {"error": {"code": ..., "message": ...}}.
+type LifecycleError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeError writes a JSON error response with a machine-readable code.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	if lw, ok := w.(*lifecycleWriter); ok {
+		lw.code = code
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error LifecycleError `json:"error"`
+	}{LifecycleError{Code: code, Message: message}})
+}
+
+// maxBodyBytes returns the configured request body limit.
+func (i *Integration) maxBodyBytes() int64 {
+	if i.bodyLimit <= 0 {
+		return DefaultMaxBodyBytes
+	}
+	return i.bodyLimit
+}
+
+// limitBody caps the number of bytes that can be read from the request body.
+func (i *Integration) limitBody(w http.ResponseWriter, r *http.Request) {
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, i.maxBodyBytes())
+	}
+}
+
+// isBodyTooLarge reports whether err was caused by exceeding the body limit.
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// HandleInstalled handles the POST HipChat sends when the integration is installed.
+func (c *Integration) HandleInstalled(w http.ResponseWriter, r *http.Request) {
+	c.observe(AuditEventInstalled, c.handleInstalled, w, r)
+}
+
 func (c *Integration) handleInstalled(w http.ResponseWriter, r *http.Request) {
+	if !c.begin() {
+		writeError(w, http.StatusServiceUnavailable, ErrorCodeShuttingDown, "The integration is shutting down.")
+		return
+	}
+	defer c.pending.Done()
+
 	// Note - this URL receives a DELETE request at /installed/oauth_id when the add-on is removed.
 
 	if r.Method == "POST" {
 		// TODO - validate request.
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			log.Printf("Error reading installation data: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprintln(w, "An unknown error occurred.")
+		i := c.readInstallRecord(w, r)
+		if i == nil {
 			return
 		}
-		var i InstallRecord
-		err = json.Unmarshal(body, &i)
-		if err != nil {
-			log.Printf("Error deserializing installation data: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprintln(w, "There was an error deserializing the data.")
+		noteRecord(w, i)
+		if err := c.checkSource(r, i.CapabilitiesURL); err != nil {
+			c.logger.Printf("Installation of %v refused: %v", i.OAuthID, err)
+			writeError(w, http.StatusForbidden, ErrorCodeForbiddenSource, err.Error())
 			return
 		}
+		if c.strict {
+			if err := validateInstallRecord(i); err != nil {
+				c.logger.Printf("Invalid installation data: %v", err)
+				writeError(w, http.StatusBadRequest, ErrorCodeBadPayload, err.Error())
+				return
+			}
+			if err := c.checkHipChatHost(i.CapabilitiesURL); err != nil {
+				c.logger.Printf("Installation of %v rejected: %v", i.OAuthID, err)
+				writeError(w, http.StatusBadRequest, ErrorCodeUnexpectedHost, err.Error())
+				return
+			}
+		}
 
-		err = c.Store.SaveCredentials(&i)
+		// HipChat retries the installation if we respond slowly, so a re-post
+		// of an installation we already have is acknowledged but not re-run.
+		// Retries may arrive while the first post is handled, the lock makes
+		// them see the installation it saved.
+		unlock := c.lockInstallation(i.OAuthID)
+		defer unlock()
+		existing, err := c.Store.GetCredentialsByOAuthID(i.OAuthID)
 		if err != nil {
-			log.Printf("Error saving credentials to Store: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprintln(w, "There was an error saving these credentials")
+			c.logger.Printf("Error loading credentials from Store: %v", err)
+			writeError(w, http.StatusInternalServerError, ErrorCodeStoreError, "There was an error loading these credentials.")
+			return
+		}
+		if existing != nil && existing.Equal(i) {
+			c.logger.Printf("Ignoring duplicate installation of %v", i.OAuthID)
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintln(w, "OK")
 			return
 		}
 
+		// A reinstall with new credentials must not be served the token of
+		// the previous installation.
+		if existing != nil {
+			c.tokens.Delete(tokenKey(existing.GroupID, existing.RoomID))
+		}
+		c.tokens.Delete(tokenKey(i.GroupID, i.RoomID))
+		if !c.saveCredentials(w, i) {
+			return
+		}
+
+		if c.synchronous {
+			if err := c.CompleteInstallation(i); err != nil {
+				// HipChat retries failed installations: undo the save so that
+				// the retry is not taken for a duplicate.
+				c.restoreCredentials(i, existing)
+				writeError(w, http.StatusServiceUnavailable, ErrorCodeAPIUnavailable, "The installation could not be completed.")
+				return
+			}
+		} else {
+			c.goTracked(func() { _ = c.CompleteInstallation(i) })
+		}
+
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprintln(w, "OK")
-
-		go c.CompleteInstallation(&i)
 	} else {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		fmt.Fprintf(w, "Method %s not supported at %s", r.Method, r.URL.Path)
+		writeError(w, http.StatusMethodNotAllowed, ErrorCodeMethodNotAllowed,
+			fmt.Sprintf("Method %s not supported at %s", r.Method, r.URL.Path))
 		return
 	}
 
 }
 
-func (i *Integration) CompleteInstallation(record *InstallRecord) {
-	log.Println("Completing installation")
+// readInstallRecord decodes the installation payload of a lifecycle request.
+// On failure it writes the error response and returns nil.
+func (c *Integration) readInstallRecord(w http.ResponseWriter, r *http.Request) *InstallRecord {
+	c.limitBody(w, r)
+	body, err := ioutil.ReadAll(r.Body)
+	if isBodyTooLarge(err) {
+		c.logger.Printf("Installation data too large: %v", err)
+		writeError(w, http.StatusRequestEntityTooLarge, ErrorCodePayloadTooLarge, "The installation data is too large.")
+		return nil
+	}
+	if err != nil {
+		c.logger.Printf("Error reading installation data: %v", err)
+		writeError(w, http.StatusBadRequest, ErrorCodeBadPayload, "The installation data could not be read.")
+		return nil
+	}
+
+	record := &InstallRecord{}
+	err = json.Unmarshal(body, record)
+	if err != nil {
+		c.logger.Printf("Error deserializing installation data: %v", err)
+		writeError(w, http.StatusBadRequest, ErrorCodeBadPayload, "There was an error deserializing the data.")
+		return nil
+	}
+	return record
+}
+
+// saveCredentials saves an installation to the Store, running the persistence
+// hooks around it. On failure it writes the error response and returns false.
+func (c *Integration) saveCredentials(w http.ResponseWriter, record *InstallRecord) bool {
+	for _, hook := range c.preSaveHooks {
+		if err := hook(record); err != nil {
+			c.logger.Printf("Installation of %v rejected: %v", record.OAuthID, err)
+			writeError(w, http.StatusForbidden, ErrorCodeRejected, err.Error())
+			return false
+		}
+	}
+
+	err := c.Store.SaveCredentials(record)
+	if err != nil {
+		c.logger.Printf("Error saving credentials to Store: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrorCodeStoreError, "There was an error saving these credentials.")
+		return false
+	}
+
+	for _, hook := range c.postSaveHooks {
+		hook(record)
+	}
+	return true
+}
+
+// restoreCredentials puts back the installation saved before record, or
+// deletes record if there was none.
+func (c *Integration) restoreCredentials(record, previous *InstallRecord) {
+	var err error
+	if previous != nil {
+		err = c.Store.SaveCredentials(previous)
+	} else {
+		err = c.Store.DeleteCredentials(record.OAuthID)
+	}
+	if err != nil {
+		c.logger.Printf("Error restoring credentials of %v: %v", record.OAuthID, err)
+	}
+}
+
+// CompleteInstallation obtains a token for a newly saved installation and then
+// runs the installation callbacks. The callbacks are not run if no token could
+// be obtained.
+func (i *Integration) CompleteInstallation(record *InstallRecord) error {
+	i.logger.Println("Completing installation")
 
 	_, err := i.getToken(record)
 	if err != nil {
-		log.Printf("Error requesting token: %v", err)
-		return
+		i.logger.Printf("Error requesting token: %v", err)
+		return err
 	}
 
-	for _, callback := range i.installationCallbacks {
-		go callback()
+	i.runCallbacks(i.installationCallbacks)
+	return nil
+}
+
+// Wait blocks until all background installation work and callbacks have finished.
+func (i *Integration) Wait() {
+	i.pending.Wait()
+}
+
+// Shutdown stops accepting lifecycle requests and waits for in-flight requests,
+// installations and callbacks to finish, or for ctx to be done.
+func (i *Integration) Shutdown(ctx context.Context) error {
+	i.mu.Lock()
+	i.closing = true
+	i.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		i.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// begin registers an in-flight lifecycle request. It returns false once
+// Shutdown has been called.
+func (i *Integration) begin() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closing {
+		return false
+	}
+	i.pending.Add(1)
+	return true
+}
+
+// installLock serializes the lifecycle requests of an installation.
+type installLock struct {
+	sync.Mutex
+	// waiters is the number of requests holding or waiting for the lock,
+	// guarded by Integration.mu.
+	waiters int
+}
+
+// lockInstallation blocks until no other lifecycle request for oauthID is
+// being handled, and returns the function releasing the lock.
+func (i *Integration) lockInstallation(oauthID string) func() {
+	i.mu.Lock()
+	if i.installing == nil {
+		i.installing = make(map[string]*installLock)
+	}
+	l := i.installing[oauthID]
+	if l == nil {
+		l = &installLock{}
+		i.installing[oauthID] = l
+	}
+	l.waiters++
+	i.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		if l.waiters--; l.waiters == 0 {
+			delete(i.installing, oauthID)
+		}
+	}
+}
+
+// goTracked runs f in a goroutine that Wait waits for.
+func (i *Integration) goTracked(f func()) {
+	i.pending.Add(1)
+	go func() {
+		defer i.pending.Done()
+		f()
+	}()
+}
+
+// runCallbacks runs the callbacks in the background or, in synchronous mode,
+// one after the other, waiting up to the callback timeout for each of them.
+func (i *Integration) runCallbacks(callbacks []func()) {
+	for _, callback := range callbacks {
+		if !i.synchronous {
+			i.goTracked(callback)
+			continue
+		}
+		if i.callbackTimeout <= 0 {
+			callback()
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), i.callbackTimeout)
+		done := make(chan struct{})
+		i.goTracked(func() {
+			defer close(done)
+			callback()
+		})
+		select {
+		case <-done:
+		case <-ctx.Done():
+			i.logger.Printf("Callback did not complete within %v", i.callbackTimeout)
+		}
+		cancel()
 	}
 }
 
 // getToken requests a token from HipChat and then caches the result
 func (i *Integration) getToken(credentials *InstallRecord) (string, error) {
-	client := NewClient("")
-	// TODO: Hard-coded, but should be stored away when descriptor is generated.
-	token, _, err := client.GenerateToken(ClientCredentials{credentials.OAuthID, credentials.OAuthSecret}, []string{})
+	token, _, err := i.requestToken(credentials)
 	if err != nil {
 		return "", err
 	}
-	log.Printf("Token obtained: %v", token)
-	
-	key := fmt.Sprintf("%v:%v", credentials.GroupID, credentials.RoomID)
-	i.tokens[key] = token.AccessToken
-
+	i.cacheToken(credentials, token)
 	return token.AccessToken, nil
 }
 
+// requestToken requests a token from HipChat with the credentials of an
+// installation, without caching it. The response is not nil when HipChat
+// answered, e.g. to reject the credentials.
+func (i *Integration) requestToken(credentials *InstallRecord) (*OAuthAccessToken, *http.Response, error) {
+	client, err := i.newClient("")
+	if err != nil {
+		return nil, nil, err
+	}
+	token, resp, err := client.GenerateToken(ClientCredentials{credentials.OAuthID, credentials.OAuthSecret}, i.scopes)
+	if err != nil {
+		return nil, resp, err
+	}
+	i.logger.Printf("Token obtained: %v", token)
+	return token, resp, nil
+}
+
+// tokenExpiryMargin is how long before their expiry cached tokens are
+// renewed, so that requests started with a token do not outlive it.
+const tokenExpiryMargin = time.Minute
+
+// cacheToken caches the token of an installation until shortly before it
+// expires.
+func (i *Integration) cacheToken(credentials *InstallRecord, token *OAuthAccessToken) {
+	var expiresAt time.Time
+	if token.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenExpiryMargin)
+	}
+	i.tokens.Set(tokenKey(credentials.GroupID, credentials.RoomID), token.AccessToken, expiresAt)
+}
+
+// newClient returns a client of the API the integration talks to, using its
+// HTTP client.
+func (i *Integration) newClient(token string) (*Client, error) {
+	client := NewClient(token)
+	client.SetHTTPClient(i.httpClient)
+	client.SetRateLimiter(i.rateLimiter)
+	if err := client.SetBaseURL(i.apiBaseURL); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// HandleUpdated handles the POST HipChat sends when an installation is updated.
+func (c *Integration) HandleUpdated(w http.ResponseWriter, r *http.Request) {
+	c.observe(AuditEventUpdated, c.handleUpdated, w, r)
+}
+
 func (c *Integration) handleUpdated(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintf(w, "TODO - handle %s callback", r.URL.Path)
-	for _, callback := range c.updatedCallbacks {
-		go callback()
+	if !c.begin() {
+		writeError(w, http.StatusServiceUnavailable, ErrorCodeShuttingDown, "The integration is shutting down.")
+		return
+	}
+	defer c.pending.Done()
+
+	update := c.readInstallRecord(w, r)
+	if update == nil {
+		return
+	}
+	noteOAuthID(w, update.OAuthID)
+
+	existing, err := c.Store.GetCredentialsByOAuthID(update.OAuthID)
+	if err != nil {
+		c.logger.Printf("Error loading credentials for %v: %v", update.OAuthID, err)
+		writeError(w, http.StatusInternalServerError, ErrorCodeStoreError, "There was an error loading these credentials.")
+		return
+	}
+	if existing == nil {
+		c.logger.Printf("Update of unknown installation %v", update.OAuthID)
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, "There is no installation with this oauthId.")
+		return
 	}
+	noteRecord(w, existing)
+
+	capabilitiesURL := update.CapabilitiesURL
+	if capabilitiesURL == "" {
+		capabilitiesURL = existing.CapabilitiesURL
+	}
+	if err := c.checkSource(r, capabilitiesURL); err != nil {
+		c.logger.Printf("Update of %v refused: %v", update.OAuthID, err)
+		writeError(w, http.StatusForbidden, ErrorCodeForbiddenSource, err.Error())
+		return
+	}
+
+	// A changed secret invalidates the tokens obtained with the old one. The
+	// request is not signed, so the new secret is only saved once HipChat
+	// issued a token for it: a forged update must not replace a working one.
+	if update.OAuthSecret != "" && update.OAuthSecret != existing.OAuthSecret {
+		c.logger.Printf("OAuth secret of %v changed", existing.OAuthID)
+		rotated := *existing
+		rotated.OAuthSecret = update.OAuthSecret
+		token, resp, err := c.requestToken(&rotated)
+		if err != nil {
+			c.logger.Printf("Error requesting token with the new secret of %v: %v", rotated.OAuthID, err)
+			if resp != nil {
+				writeError(w, http.StatusForbidden, ErrorCodeRejected, "HipChat did not accept the new OAuth secret.")
+			} else {
+				writeError(w, http.StatusServiceUnavailable, ErrorCodeAPIUnavailable, "The new OAuth secret could not be checked.")
+			}
+			return
+		}
+		c.tokens.Delete(tokenKey(existing.GroupID, existing.RoomID))
+		if !c.saveCredentials(w, &rotated) {
+			return
+		}
+		c.cacheToken(&rotated, token)
+	}
+
+	c.runCallbacks(c.updatedCallbacks)
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "OK")
 }
 
 type Capabilities struct {
@@ -160,7 +763,7 @@ type Provider struct {
 }
 
 func (c *Integration) getCapabilities(url string) (*Capabilities, error) {
-	resp, err := http.Get(url)
+	resp, err := c.httpClient.Get(url)
 	if err != nil {
 		return nil, err
 	}
@@ -179,140 +782,273 @@ func (c *Integration) getCapabilities(url string) (*Capabilities, error) {
 	return capabilities, nil
 }
 
+// HandleRemoved handles the DELETE HipChat sends to /installed/{oAuthId} when
+// the integration is uninstalled.
+func (c *Integration) HandleRemoved(w http.ResponseWriter, r *http.Request) {
+	c.observe(AuditEventRemoved, c.handleRemoved, w, r)
+}
+
 func (c *Integration) handleRemoved(w http.ResponseWriter, r *http.Request) {
+	if !c.begin() {
+		writeError(w, http.StatusServiceUnavailable, ErrorCodeShuttingDown, "The integration is shutting down.")
+		return
+	}
+	defer c.pending.Done()
+
 	if r.Method == "DELETE" {
-		// TODO - validate request.
 		oAuthID := gorillaMux.Vars(r)["oAuthId"]
+		if oAuthID == "" {
+			// Not routed by gorilla/mux, the oauthId is the last path segment.
+			oAuthID = path.Base(r.URL.Path)
+		}
+		noteOAuthID(w, oAuthID)
 
-		err := c.Store.DeleteCredentials(oAuthID)
+		record, err := c.Store.GetCredentialsByOAuthID(oAuthID)
 		if err != nil {
-			log.Printf("Error deleting credentials credentials for %v: %v", oAuthID, err)
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprintln(w, "There was an error deleting these credentials")
+			c.logger.Printf("Error loading credentials for %v: %v", oAuthID, err)
+			writeError(w, http.StatusInternalServerError, ErrorCodeStoreError, "There was an error loading these credentials.")
+			return
+		}
+		if record == nil {
+			c.logger.Printf("Removal of unknown installation %v", oAuthID)
+			writeError(w, http.StatusNotFound, ErrorCodeNotFound, "There is no installation with this oauthId.")
+			return
+		}
+		noteRecord(w, record)
+		// The request carries no capabilitiesUrl, the one of the installation
+		// is checked against the allowed hosts.
+		if err := c.checkSource(r, record.CapabilitiesURL); err != nil {
+			c.logger.Printf("Removal of %v refused: %v", oAuthID, err)
+			writeError(w, http.StatusForbidden, ErrorCodeForbiddenSource, err.Error())
 			return
 		}
 
+		for _, hook := range c.preDeleteHooks {
+			if err := hook(oAuthID, record); err != nil {
+				c.logger.Printf("Removal of %v rejected: %v", oAuthID, err)
+				writeError(w, http.StatusForbidden, ErrorCodeRejected, err.Error())
+				return
+			}
+		}
+
+		err = c.Store.DeleteCredentials(oAuthID)
+		if err != nil {
+			c.logger.Printf("Error deleting credentials credentials for %v: %v", oAuthID, err)
+			writeError(w, http.StatusInternalServerError, ErrorCodeStoreError, "There was an error deleting these credentials.")
+			return
+		}
+
+		c.tokens.Delete(tokenKey(record.GroupID, record.RoomID))
+		if err := c.settings.DeleteSettings(oAuthID); err != nil {
+			c.logger.Printf("Error deleting settings for %v: %v", oAuthID, err)
+		}
+
+		for _, hook := range c.postDeleteHooks {
+			hook(oAuthID, record)
+		}
+
+		callbacks := make([]func(), len(c.removedCallbacks))
+		for n, callback := range c.removedCallbacks {
+			callback := callback
+			callbacks[n] = func() { callback(oAuthID, record) }
+		}
+		c.runCallbacks(callbacks)
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprintln(w, "OK")
-		for _, callback := range c.removedCallbacks {
-			go callback()
-		}
 	} else {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		fmt.Fprintf(w, "Method %s not supported at %s", r.Method, r.URL.Path)
+		writeError(w, http.StatusMethodNotAllowed, ErrorCodeMethodNotAllowed,
+			fmt.Sprintf("Method %s not supported at %s", r.Method, r.URL.Path))
 	}
 }
 
+// GetTokenForRoom returns a token for the installation in the given room.
 func (i *Integration) GetTokenForRoom(roomID uint32) (string, error) {
-	// TODO: Handle token expiry
 	groupID, err := i.Store.GetGroupID(roomID)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
-	
-	key := fmt.Sprintf("%v:%v", groupID, roomID)
-	
-	token, exists := i.tokens[key]
+
+	room := uint64(roomID)
+	token, exists := i.tokens.Get(tokenKey(uint64(groupID), &room))
 	if !exists {
 		credentials, err := i.Store.GetCredentials(groupID, roomID)
 		if err != nil {
 			return "", err
 		}
+		if credentials == nil {
+			return "", fmt.Errorf("No installation for room %v", roomID)
+		}
 		return i.getToken(credentials)
 	}
 	return token, nil
 }
 
+// GetTokenForGroup returns a token for the global installation in the given group.
+func (i *Integration) GetTokenForGroup(groupID uint32) (string, error) {
+	token, exists := i.tokens.Get(tokenKey(uint64(groupID), nil))
+	if !exists {
+		credentials, err := i.Store.GetGlobalCredentials(groupID)
+		if err != nil {
+			return "", err
+		}
+		if credentials == nil {
+			return "", fmt.Errorf("No global installation for group %v", groupID)
+		}
+		return i.getToken(credentials)
+	}
+	return token, nil
+}
+
+// SignedParams holds the claims of a JWT signed by HipChat for the add-on.
+// Claims HipChat does not always send are left to their zero value when missing.
 type SignedParams struct {
-	RoomID uint32
+	// Issuer is the oauthId of the installation the token was signed for.
+	Issuer string
+	// UserID is the id of the user, from the sub claim or the older prn claim.
+	UserID       string
+	UserName     string
+	GroupID      uint32
+	RoomID       uint32
+	RoomName     string
 	UserTimezone string
+	IssuedAt     time.Time
+	ExpiresAt    time.Time
+	// TokenID is the unique identifier of the token, from the jti claim.
+	TokenID string
 }
 
 func (sp SignedParams) String() string {
-	return fmt.Sprintf("SignedParams<RoomID: %v, Timezone: \"%v\">", sp.RoomID, sp.UserTimezone)
-} 
+	return fmt.Sprintf("SignedParams<Issuer: %v, UserID: %v, GroupID: %v, RoomID: %v, Timezone: \"%v\">",
+		sp.Issuer, sp.UserID, sp.GroupID, sp.RoomID, sp.UserTimezone)
+}
 
-func NewSignedParams(token *jwt.Token) (*SignedParams, error) {
-	result := &SignedParams{}
-	
-	switch context := token.Claims["context"].(type) {
-	case map[string]interface{}:
-		if err := extractType(context, "room_id", &result.RoomID); err != nil {
-			return nil, fmt.Errorf("Error extracting room_id: %v", err)
-		}
-		if err := extractType(context, "user_tz", &result.UserTimezone); err != nil {
-			return nil, fmt.Errorf("Error extracting user_tz: %v", err)
-		}
-	default:
-		return nil, fmt.Errorf("context of wrong type: %t", context)
+// NewSignedParams extracts the SignedParams from the claims of a validated
+// token, as decoded by DecodeClaims.
+func NewSignedParams(claims *Claims) (*SignedParams, error) {
+	if claims.Issuer == "" {
+		return nil, fmt.Errorf("%w: missing signed parameter \"iss\"", ErrInvalidClaims)
+	}
+	if claims.Context == nil {
+		return nil, fmt.Errorf("%w: missing signed parameter \"context\"", ErrInvalidClaims)
 	}
-	
-	return result, nil
-}
 
-func extractType(dict map[string]interface{}, key string, dest interface{}) error {
-	if dict[key] == nil {
-		return fmt.Errorf("Missing signed parameter \"%v\"", key)
+	result := &SignedParams{
+		Issuer:       claims.Issuer,
+		UserID:       claims.Subject,
+		UserName:     claims.Context.UserName,
+		GroupID:      claims.Context.GroupID,
+		RoomID:       claims.Context.RoomID,
+		RoomName:     claims.Context.RoomName,
+		UserTimezone: claims.Context.UserTimezone,
+		TokenID:      claims.ID,
 	}
-	switch d := dest.(type) {
-	case *string:
-		switch v := dict[key].(type) {
-		case string:
-			*d = v
-			return nil
-		}
-	case *uint32:
-		switch v := dict[key].(type) {
-		case float32, float64:
-			*d = uint32(v.(float64))
-			return nil
-		}
+	if result.UserID == "" {
+		result.UserID = claims.Principal
+	}
+	if claims.IssuedAt != 0 {
+		result.IssuedAt = time.Unix(claims.IssuedAt, 0)
 	}
-	return fmt.Errorf("Type mismatch for signed param %v dest: %t, source: %t", key, dest, dict[key])
+	if claims.ExpiresAt != 0 {
+		result.ExpiresAt = time.Unix(claims.ExpiresAt, 0)
+	}
+	return result, nil
 }
 
-// ParseTokenFromRequest extracts and validates a JWT token from the request.
+// ParseSignedParams verifies the JWT signed by HipChat that comes with req,
+// in the Authorization header, the signed_request parameter of the query
+// string or form, or the cookie set with WithTokenCookie, and returns the
+// parameters it carries. ErrTokenMissing is returned if there is no token, see
+// TokenErrorStatus for the other errors.
 func (i *Integration) ParseSignedParams(req *http.Request) (*SignedParams, error) {
-	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-		
-		// Look up oauth secret with the iss string
-		switch oauthID := token.Claims["iss"].(type) {
-		case string:
-			secret, err := i.Store.GetOAuthSecret(oauthID)
-			if err != nil {
-				return nil, err
-			}
-			
-        return []byte(secret), nil
-		default:
-			return nil, fmt.Errorf("iss header of wrong type: %t", oauthID)
-		}
+	return i.ParseSignedParamsContext(req.Context(), req)
+}
+
+// ParseSignedParamsContext is like ParseSignedParams, but gives up looking up
+// the keys of the token when ctx is done.
+//
+// The token is not parsed again when ctx already holds the SignedParams of the
+// request, as it does in handlers wrapped by RequireSignedParams.
+func (i *Integration) ParseSignedParamsContext(ctx context.Context, req *http.Request) (*SignedParams, error) {
+	if params, ok := SignedParamsFromContext(ctx); ok {
+		return params, nil
 	}
-	
+
 	// Look for an Authorization header
 	if ah := req.Header.Get("Authorization"); ah != "" {
 		prefix := "JWT "
 		if strings.HasPrefix(strings.ToUpper(ah), prefix) {
-			return parse(ah[len(prefix):], keyFunc)
+			return i.parse(ctx, req, ah[len(prefix):])
 		}
 	}
 
+	// Look for "signed_request" in the query string, used when HipChat loads
+	// glances and sidebars with a GET
+	if tokStr := req.URL.Query().Get("signed_request"); tokStr != "" {
+		return i.parse(ctx, req, tokStr)
+	}
+
 	// Look for "signed_request" parameter
-	req.ParseMultipartForm(10e6)
+	// ParseMultipartForm ignores the errors of ParseForm on urlencoded
+	// bodies, which is then called first.
+	i.limitBody(nil, req)
+	err := req.ParseForm()
+	if err == nil {
+		err = req.ParseMultipartForm(i.maxBodyBytes())
+	}
+	if isBodyTooLarge(err) {
+		return nil, err
+	}
 	if tokStr := req.Form.Get("signed_request"); tokStr != "" {
-		return parse(tokStr, keyFunc)
+		return i.parse(ctx, req, tokStr)
+	}
+
+	// Look for the token cookie
+	if i.tokenCookie != "" {
+		if cookie, err := req.Cookie(i.tokenCookie); err == nil && cookie.Value != "" {
+			return i.parse(ctx, req, cookie.Value)
+		}
 	}
 
-	return nil, jwt.ErrNoTokenInRequest
+	return nil, ErrTokenMissing
+}
+
+// oauthSecrets returns the keys of a token signed by HipChat: the oauth
+// secrets of the installation named by its iss claim.
+func (i *Integration) oauthSecrets(ctx context.Context, claims map[string]interface{}) ([][]byte, error) {
+	switch oauthID := claims["iss"].(type) {
+	case string:
+		return i.resolveKeys(ctx, oauthID)
+	default:
+		return nil, fmt.Errorf("%w: iss header of wrong type: %T", ErrInvalidClaims, oauthID)
+	}
 }
 
-func parse(tokenStr string, keyFunc func(token *jwt.Token) (interface{}, error)) (*SignedParams, error) {
-	token, err := jwt.Parse(tokenStr, keyFunc)
+func (i *Integration) parse(ctx context.Context, req *http.Request, tokenStr string) (*SignedParams, error) {
+	rawClaims, err := codec.Parse(tokenStr, func(claims map[string]interface{}) ([][]byte, error) {
+		return i.oauthSecrets(ctx, claims)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return NewSignedParams(token)
-}
\ No newline at end of file
+	claims, err := decodeClaims(rawClaims)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.checkTimes(claims); err != nil {
+		return nil, err
+	}
+	if err := i.checkQSH(req, claims); err != nil {
+		return nil, err
+	}
+	params, err := NewSignedParams(claims)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.checkTenant(params); err != nil {
+		return nil, err
+	}
+	if err := i.checkReplay(params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
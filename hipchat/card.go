@@ -0,0 +1,131 @@
+package hipchat
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Length limits enforced by HipChat on the fields of a Card.
+const (
+	MaxCardTitleLength       = 500
+	MaxCardDescriptionLength = 1000
+	MaxCardIDLength          = 100
+	MaxCardAttributes        = 10
+	MaxCardAttributeLength   = 50
+)
+
+// CardBuilder builds a Card, e.g.
+//
+//	card, err := NewCard(CardStyleApplication, "Build #42 passed").
+//		Description("All tests passed").
+//		Attribute("Branch", "master").
+//		Icon("https://example.com/icon.png").
+//		Build()
+type CardBuilder struct {
+	c Card
+}
+
+// NewCard starts building a Card with the given style, one of the CardStyle
+// constants, and title.
+func NewCard(style, title string) *CardBuilder {
+	return &CardBuilder{c: Card{Style: style, Title: title}}
+}
+
+// ID sets the id of the card, used to update the card later.
+func (b *CardBuilder) ID(id string) *CardBuilder {
+	b.c.ID = id
+	return b
+}
+
+// Description sets the plain text description of the card.
+func (b *CardBuilder) Description(text string) *CardBuilder {
+	b.c.Description = CardDescription{Value: text}
+	return b
+}
+
+// HTMLDescription sets the description of the card to HTML content.
+func (b *CardBuilder) HTMLDescription(html string) *CardBuilder {
+	b.c.Description = CardDescription{Format: "html", Value: html}
+	return b
+}
+
+// URL sets the URL the title of the card links to.
+func (b *CardBuilder) URL(url string) *CardBuilder {
+	b.c.URL = url
+	return b
+}
+
+// Format sets the display format of the card, "compact" or "medium".
+func (b *CardBuilder) Format(format string) *CardBuilder {
+	b.c.Format = format
+	return b
+}
+
+// Icon sets the icon of the card.
+func (b *CardBuilder) Icon(url string) *CardBuilder {
+	b.c.Icon = &Icon{URL: url}
+	return b
+}
+
+// Thumbnail sets the thumbnail image of the card, with its size in pixels.
+func (b *CardBuilder) Thumbnail(url string, width, height uint) *CardBuilder {
+	b.c.Thumbnail = &Thumbnail{URL: url, Width: width, Height: height}
+	return b
+}
+
+// Activity sets the HTML activity line of the card, with an optional icon.
+func (b *CardBuilder) Activity(html, iconURL string) *CardBuilder {
+	b.c.Activity = &Activity{HTML: html}
+	if iconURL != "" {
+		b.c.Activity.Icon = &Icon{URL: iconURL}
+	}
+	return b
+}
+
+// Attribute adds an attribute with a plain value to the card.
+func (b *CardBuilder) Attribute(label, value string) *CardBuilder {
+	b.c.Attributes = append(b.c.Attributes, Attribute{Label: label, Value: AttributeValue{Label: value}})
+	return b
+}
+
+// StyledAttribute adds an attribute whose value is rendered with the given
+// style, one of the AttributeStyle constants.
+func (b *CardBuilder) StyledAttribute(label, value, style string) *CardBuilder {
+	b.c.Attributes = append(b.c.Attributes, Attribute{Label: label, Value: AttributeValue{Label: value, Style: style}})
+	return b
+}
+
+// LinkAttribute adds an attribute whose value links to url.
+func (b *CardBuilder) LinkAttribute(label, value, url string) *CardBuilder {
+	b.c.Attributes = append(b.c.Attributes, Attribute{Label: label, Value: AttributeValue{Label: value, URL: url}})
+	return b
+}
+
+// Build returns the Card, or an error if it is missing required fields or
+// exceeds the length limits of HipChat.
+func (b *CardBuilder) Build() (*Card, error) {
+	c := b.c
+	switch {
+	case c.Style == "":
+		return nil, errors.New("Missing card style")
+	case c.Title == "":
+		return nil, errors.New("Missing card title")
+	case utf8.RuneCountInString(c.Title) > MaxCardTitleLength:
+		return nil, fmt.Errorf("Card title is longer than %d characters", MaxCardTitleLength)
+	case utf8.RuneCountInString(c.Description.Value) > MaxCardDescriptionLength:
+		return nil, fmt.Errorf("Card description is longer than %d characters", MaxCardDescriptionLength)
+	case len(c.ID) > MaxCardIDLength:
+		return nil, fmt.Errorf("Card id is longer than %d characters", MaxCardIDLength)
+	case len(c.Attributes) > MaxCardAttributes:
+		return nil, fmt.Errorf("Card has more than %d attributes", MaxCardAttributes)
+	}
+	for _, attr := range c.Attributes {
+		if utf8.RuneCountInString(attr.Label) > MaxCardAttributeLength ||
+			utf8.RuneCountInString(attr.Value.Label) > MaxCardAttributeLength {
+			return nil, fmt.Errorf("Card attribute %q is longer than %d characters", attr.Label, MaxCardAttributeLength)
+		}
+	}
+	c.Attributes = append([]Attribute(nil), c.Attributes...)
+	return &c, nil
+}
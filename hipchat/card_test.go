@@ -0,0 +1,34 @@
+package hipchat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCardBuilder(t *testing.T) {
+	card, err := NewCard(CardStyleApplication, "t").
+		ID("1").
+		Description("d").
+		Icon("i").
+		StyledAttribute("l", "v", AttributeStyleLozengeSuccess).
+		Build()
+	if err != nil {
+		t.Fatalf("Build returned an error %v", err)
+	}
+
+	testJSONEqual(t, card, `{
+		"style": "application",
+		"description": "d",
+		"title": "t",
+		"attributes": [{"label": "l", "value": {"style": "lozenge-success", "label": "v"}}],
+		"id": "1",
+		"icon": {"url": "i"}
+	}`)
+}
+
+func TestCardBuilder_TooLong(t *testing.T) {
+	_, err := NewCard(CardStyleLink, strings.Repeat("t", MaxCardTitleLength+1)).Build()
+	if err == nil {
+		t.Errorf("Build accepted a title longer than %d characters", MaxCardTitleLength)
+	}
+}
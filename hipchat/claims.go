@@ -0,0 +1,70 @@
+package hipchat
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Claims are the claims of the JWTs signed by HipChat for the add-on, and of
+// the session tokens issued by the add-on. Times are in seconds since the epoch.
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub,omitempty"`
+	Principal string `json:"prn,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	ID        string `json:"jti,omitempty"`
+	// QSH is the query string hash binding the token to a request.
+	QSH     string         `json:"qsh,omitempty"`
+	Context *ClaimsContext `json:"context,omitempty"`
+}
+
+// ClaimsContext is the context claim of the JWTs signed by HipChat.
+type ClaimsContext struct {
+	RoomID       uint32 `json:"room_id"`
+	RoomName     string `json:"room_name,omitempty"`
+	GroupID      uint32 `json:"group_id,omitempty"`
+	UserName     string `json:"user_name,omitempty"`
+	UserTimezone string `json:"user_tz"`
+}
+
+// decodeClaims converts the claims returned by the token codec to Claims.
+func decodeClaims(m map[string]interface{}) (*Claims, error) {
+	claims := &Claims{}
+	if err := DecodeClaims(m, claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClaims, err)
+	}
+	return claims, nil
+}
+
+// ErrTokenExpired is returned by ParseSignedParams when the exp claim of a
+// token is in the past, beyond the allowed clock skew. Frontends can react to
+// it by requesting a fresh token instead of failing.
+var ErrTokenExpired = errors.New("token is expired")
+
+// WithClockSkew sets how far the clocks of HipChat and the add-on may drift
+// apart when checking the exp, nbf and iat claims of incoming JWTs.
+// By default no skew is tolerated.
+func WithClockSkew(skew time.Duration) IntegrationOption {
+	return func(i *Integration) {
+		i.clockSkew = skew
+	}
+}
+
+// checkTimes validates the time claims of a token, with the clock skew applied.
+func (i *Integration) checkTimes(claims *Claims) error {
+	now := time.Now()
+	if exp := claims.ExpiresAt; exp != 0 && now.After(time.Unix(exp, 0).Add(i.clockSkew)) {
+		return ErrTokenExpired
+	}
+	if nbf := claims.NotBefore; nbf != 0 && now.Before(time.Unix(nbf, 0).Add(-i.clockSkew)) {
+		return fmt.Errorf("%w: token is not valid yet", ErrInvalidClaims)
+	}
+	if iat := claims.IssuedAt; iat != 0 && now.Before(time.Unix(iat, 0).Add(-i.clockSkew)) {
+		return fmt.Errorf("%w: token was issued in the future", ErrInvalidClaims)
+	}
+	return nil
+}
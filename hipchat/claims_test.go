@@ -0,0 +1,105 @@
+package hipchat
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeClaims(t *testing.T) {
+	claims, err := decodeClaims(map[string]interface{}{
+		"iss": "oauth",
+		"prn": float64(42),
+		"exp": float64(1400000000),
+		"context": map[string]interface{}{
+			"room_id":  "123",
+			"group_id": float64(7),
+			"user_tz":  "UTC",
+		},
+	})
+	if err != nil {
+		t.Fatalf("decodeClaims returned an error %v", err)
+	}
+
+	want := &Claims{
+		Issuer:    "oauth",
+		Principal: "42",
+		ExpiresAt: 1400000000,
+		Context:   &ClaimsContext{RoomID: 123, GroupID: 7, UserTimezone: "UTC"},
+	}
+	if !reflect.DeepEqual(claims, want) {
+		t.Errorf("decodeClaims returned %+v, want %+v", claims, want)
+	}
+}
+
+func TestDecodeClaims_BadRoomID(t *testing.T) {
+	_, err := decodeClaims(map[string]interface{}{
+		"iss":     "oauth",
+		"context": map[string]interface{}{"room_id": "lobby"},
+	})
+	if err == nil {
+		t.Errorf("decodeClaims accepted a non numeric room_id")
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		claim      string
+		at         time.Time
+		skew       time.Duration
+		wantStatus int
+	}{
+		{"expired", "exp", now.Add(-30 * time.Second), 0, http.StatusUnauthorized},
+		{"expired within skew", "exp", now.Add(-30 * time.Second), time.Minute, http.StatusOK},
+		{"expired beyond skew", "exp", now.Add(-2 * time.Minute), time.Minute, http.StatusUnauthorized},
+		{"not valid yet", "nbf", now.Add(30 * time.Second), 0, http.StatusBadRequest},
+		{"not valid yet within skew", "nbf", now.Add(30 * time.Second), time.Minute, http.StatusOK},
+		{"issued in the future", "iat", now.Add(30 * time.Second), 0, http.StatusBadRequest},
+		{"issued in the future within skew", "iat", now.Add(30 * time.Second), time.Minute, http.StatusOK},
+		{"issued beyond skew", "iat", now.Add(2 * time.Minute), time.Minute, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params []*SignedParams
+			_, handler := newSignedIntegration(&params, WithClockSkew(tt.skew))
+			r := httptest.NewRequest("GET", "/panel", nil)
+			claims := testClaims("oauth")
+			claims[tt.claim] = tt.at.Unix()
+			signTestRequest(t, r, claims, "secret")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Request with %s %v answered %d %s, want %d", tt.claim, tt.at.Sub(now), w.Code, w.Body, tt.wantStatus)
+			}
+			if reached := len(params) == 1; reached != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("Request reached the module is %v", reached)
+			}
+		})
+	}
+}
+
+func TestNewSignedParams(t *testing.T) {
+	params, err := NewSignedParams(&Claims{Issuer: "oauth", Principal: "1", IssuedAt: 10, Context: &ClaimsContext{RoomID: 3, UserTimezone: "UTC"}})
+	if err != nil {
+		t.Fatalf("NewSignedParams returned an error %v", err)
+	}
+	want := &SignedParams{Issuer: "oauth", UserID: "1", RoomID: 3, UserTimezone: "UTC", IssuedAt: time.Unix(10, 0)}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("NewSignedParams returned %+v, want %+v", params, want)
+	}
+
+	for _, claims := range []*Claims{
+		{Context: &ClaimsContext{}},
+		{Issuer: "oauth"},
+	} {
+		if _, err := NewSignedParams(claims); !errors.Is(err, ErrInvalidClaims) {
+			t.Errorf("NewSignedParams(%+v) returned %v, want %v", claims, err, ErrInvalidClaims)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package hipchat
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// CSRFFieldName is the form field holding the CSRF token.
+	CSRFFieldName = "csrf_token"
+	// CSRFHeaderName is the header holding the CSRF token of AJAX requests.
+	CSRFHeaderName = "X-CSRF-Token"
+	// CSRFTokenTTL is how long a CSRF token is accepted after it was issued.
+	CSRFTokenTTL = time.Hour
+)
+
+// ErrBadCSRFToken is returned by VerifyCSRFToken when the request carries no
+// valid CSRF token.
+var ErrBadCSRFToken = errors.New("missing or invalid CSRF token")
+
+// CSRFToken returns a CSRF token bound to the installation, user and room of
+// params, to be embedded in the forms served inside HipChat dialogs and
+// configuration pages. The token is signed with the secret set by
+// WithSessionSecret.
+func (i *Integration) CSRFToken(params *SignedParams) (string, error) {
+	if len(i.sessionSecret) == 0 {
+		return "", ErrNoSessionSecret
+	}
+	issued := strconv.FormatInt(time.Now().Unix(), 10)
+	return issued + "." + i.csrfMAC(params, issued), nil
+}
+
+// CSRFField returns a hidden form input holding a CSRF token for params,
+// ready to be used in an html/template.
+func (i *Integration) CSRFField(params *SignedParams) (template.HTML, error) {
+	token, err := i.CSRFToken(params)
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+		CSRFFieldName, template.HTMLEscapeString(token))), nil
+}
+
+// VerifyCSRFToken checks that r carries a CSRF token issued for params, in the
+// csrf_token form field or the X-CSRF-Token header.
+func (i *Integration) VerifyCSRFToken(r *http.Request, params *SignedParams) error {
+	if len(i.sessionSecret) == 0 {
+		return ErrNoSessionSecret
+	}
+	token := r.Header.Get(CSRFHeaderName)
+	if token == "" {
+		i.limitBody(nil, r)
+		token = r.FormValue(CSRFFieldName)
+	}
+
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return ErrBadCSRFToken
+	}
+	issued, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || time.Since(time.Unix(issued, 0)) > CSRFTokenTTL {
+		return ErrBadCSRFToken
+	}
+	if !hmac.Equal([]byte(parts[1]), []byte(i.csrfMAC(params, parts[0]))) {
+		return ErrBadCSRFToken
+	}
+	return nil
+}
+
+// RequireCSRF wraps next so that state-changing requests, anything but GET,
+// HEAD and OPTIONS, must carry a valid CSRF token. It must be used inside
+// RequireSignedParams, whose SignedParams the token is checked against.
+func (i *Integration) RequireCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		params, ok := SignedParamsFromContext(r.Context())
+		if !ok || i.VerifyCSRFToken(r, params) != nil {
+			writeError(w, http.StatusForbidden, ErrorCodeBadCSRFToken, "The CSRF token is missing or invalid.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// csrfMAC returns the signature of a CSRF token issued at the given time.
+func (i *Integration) csrfMAC(params *SignedParams, issued string) string {
+	mac := hmac.New(sha256.New, i.sessionSecret)
+	fmt.Fprintf(mac, "csrf|%s|%s|%d|%s", params.Issuer, params.UserID, params.RoomID, issued)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,116 @@
+package hipchat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSRFToken(t *testing.T) {
+	i := NewIntegration(nil, WithSessionSecret([]byte("secret")))
+	params := &SignedParams{Issuer: "oauth", UserID: "1", RoomID: 2}
+
+	token, err := i.CSRFToken(params)
+	if err != nil {
+		t.Fatalf("CSRFToken returned an error %v", err)
+	}
+
+	r := httptest.NewRequest("POST", "/config", nil)
+	r.Header.Set(CSRFHeaderName, token)
+	if err := i.VerifyCSRFToken(r, params); err != nil {
+		t.Errorf("VerifyCSRFToken returned an error %v", err)
+	}
+
+	other := &SignedParams{Issuer: "oauth", UserID: "3", RoomID: 2}
+	if err := i.VerifyCSRFToken(r, other); err != ErrBadCSRFToken {
+		t.Errorf("VerifyCSRFToken for another user returned %v, want %v", err, ErrBadCSRFToken)
+	}
+}
+
+func TestVerifyCSRFToken_Errors(t *testing.T) {
+	i := NewIntegration(nil, WithSessionSecret([]byte("secret")))
+	params := &SignedParams{Issuer: "oauth", UserID: "1", RoomID: 2}
+	issued := strconv.FormatInt(time.Now().Add(-2*CSRFTokenTTL).Unix(), 10)
+	expired := issued + "." + i.csrfMAC(params, issued)
+	valid, _ := i.CSRFToken(params)
+	otherRoom, _ := i.CSRFToken(&SignedParams{Issuer: "oauth", UserID: "1", RoomID: 5})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"missing", ""},
+		{"without signature", strconv.FormatInt(time.Now().Unix(), 10)},
+		{"bad issue time", "now." + i.csrfMAC(params, "now")},
+		{"expired", expired},
+		{"tampered", strings.Replace(valid, ".", ".0", 1)},
+		{"for another room", otherRoom},
+		{"signed with another secret", func() string {
+			tok, _ := NewIntegration(nil, WithSessionSecret([]byte("other"))).CSRFToken(params)
+			return tok
+		}()},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/config", strings.NewReader(url.Values{CSRFFieldName: {tt.token}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if err := i.VerifyCSRFToken(r, params); err != ErrBadCSRFToken {
+			t.Errorf("VerifyCSRFToken of %s token returned %v, want %v", tt.name, err, ErrBadCSRFToken)
+		}
+	}
+
+	r := httptest.NewRequest("POST", "/config", strings.NewReader(url.Values{CSRFFieldName: {valid}}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := i.VerifyCSRFToken(r, params); err != nil {
+		t.Errorf("VerifyCSRFToken of form token returned %v", err)
+	}
+	if _, err := NewIntegration(nil).CSRFToken(params); err != ErrNoSessionSecret {
+		t.Errorf("CSRFToken without secret returned %v, want %v", err, ErrNoSessionSecret)
+	}
+}
+
+func TestRequireCSRF(t *testing.T) {
+	i := NewIntegration(nil, WithSessionSecret([]byte("secret")))
+	params := &SignedParams{Issuer: "oauth", UserID: "1", RoomID: 2}
+	token, _ := i.CSRFToken(params)
+	tests := []struct {
+		method     string
+		signed     bool
+		token      string
+		wantStatus int
+	}{
+		{"GET", true, "", http.StatusOK},
+		{"HEAD", false, "", http.StatusOK},
+		{"POST", true, token, http.StatusOK},
+		{"DELETE", true, token, http.StatusOK},
+		{"POST", true, "", http.StatusForbidden},
+		{"PUT", true, "", http.StatusForbidden},
+		{"POST", false, token, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		reached := false
+		handler := i.RequireCSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reached = true
+		}))
+		r := httptest.NewRequest(tt.method, "/config", nil)
+		if tt.signed {
+			r = r.WithContext(context.WithValue(r.Context(), signedParamsKey{}, params))
+		}
+		if tt.token != "" {
+			r.Header.Set(CSRFHeaderName, tt.token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tt.wantStatus || reached != (tt.wantStatus == http.StatusOK) {
+			t.Errorf("%s signed=%v with token %q answered %d, reached %v, want %d", tt.method, tt.signed, tt.token, w.Code, reached, tt.wantStatus)
+		}
+		if w.Code == http.StatusForbidden && !strings.Contains(w.Body.String(), ErrorCodeBadCSRFToken) {
+			t.Errorf("%s answered %s, want %s", tt.method, w.Body, ErrorCodeBadCSRFToken)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package hipchat
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// DecodeClaims decodes the claims of a JWT, as found in its JSON payload, into
+// the struct pointed to by dest. Fields are matched by the name given in their
+// "claim" tag, or else their "json" tag, or else their name. A ",required" tag
+// option makes a missing claim an error; other missing claims leave the field
+// untouched.
+//
+// Values are converted strictly:
+//   - string fields accept strings and integral numbers,
+//   - integer fields accept integral numbers and numeric strings in range,
+//   - float and bool fields only accept numbers and booleans,
+//   - time.Time fields accept numbers of seconds since the epoch,
+//   - slice fields accept arrays, or a single value,
+//   - struct fields and pointers to structs accept objects, decoded recursively,
+//   - interface{} fields accept anything.
+//
+// Errors name the offending claim, e.g. `claim "context.room_id": ...`.
+func DecodeClaims(claims map[string]interface{}, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("DecodeClaims needs a pointer to a struct, got %T", dest)
+	}
+	return decodeStruct("", claims, v.Elem())
+}
+
+func decodeStruct(prefix string, claims map[string]interface{}, v reflect.Value) error {
+	t := v.Type()
+	for n := 0; n < t.NumField(); n++ {
+		field := t.Field(n)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, required := claimName(field)
+		if name == "-" {
+			continue
+		}
+		path := prefix + name
+
+		value, ok := claims[name]
+		if !ok || value == nil {
+			if required {
+				return fmt.Errorf("claim %q: missing", path)
+			}
+			continue
+		}
+		if err := decodeValue(path, value, v.Field(n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimName returns the claim a struct field is decoded from.
+func claimName(field reflect.StructField) (name string, required bool) {
+	tag, ok := field.Tag.Lookup("claim")
+	if !ok {
+		tag = field.Tag.Get("json")
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "required" {
+			required = true
+		}
+	}
+	return name, required
+}
+
+func decodeValue(path string, value interface{}, v reflect.Value) error {
+	mismatch := func() error {
+		return fmt.Errorf("claim %q: cannot decode %T into %s", path, value, v.Type())
+	}
+
+	if v.Type() == timeType {
+		f, ok := value.(float64)
+		if !ok {
+			return mismatch()
+		}
+		v.Set(reflect.ValueOf(time.Unix(int64(f), 0)))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		switch value := value.(type) {
+		case string:
+			v.SetString(value)
+		case float64:
+			if value != math.Trunc(value) {
+				return mismatch()
+			}
+			v.SetString(strconv.FormatFloat(value, 'f', -1, 64))
+		default:
+			return mismatch()
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s, ok := numericString(value)
+		if !ok {
+			return mismatch()
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("claim %q: %v is not a valid %s", path, value, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s, ok := numericString(value)
+		if !ok {
+			return mismatch()
+		}
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("claim %q: %v is not a valid %s", path, value, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, ok := value.(float64)
+		if !ok {
+			return mismatch()
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return mismatch()
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for n, value := range values {
+			if err := decodeValue(fmt.Sprintf("%s[%d]", path, n), value, slice.Index(n)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeValue(path, value, v.Elem())
+	case reflect.Struct:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		return decodeStruct(path+".", m, v)
+	case reflect.Interface, reflect.Map:
+		rv := reflect.ValueOf(value)
+		if !rv.Type().AssignableTo(v.Type()) {
+			return mismatch()
+		}
+		v.Set(rv)
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+// numericString returns the decimal form of an integral number or of a
+// string holding one.
+func numericString(value interface{}) (string, bool) {
+	switch value := value.(type) {
+	case float64:
+		if value != math.Trunc(value) {
+			return "", false
+		}
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case string:
+		return value, true
+	}
+	return "", false
+}
@@ -0,0 +1,57 @@
+package hipchat
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeClaims_Coercion(t *testing.T) {
+	type payload struct {
+		ID      int64     `claim:"id,required"`
+		Name    string    `json:"name"`
+		Tags    []string  `claim:"tags"`
+		Owners  []string  `claim:"owners"`
+		Created time.Time `claim:"created"`
+		Extra   interface{}
+	}
+	var got payload
+	err := DecodeClaims(map[string]interface{}{
+		"id":      "12",
+		"name":    float64(3),
+		"tags":    []interface{}{"a", "b"},
+		"owners":  "me",
+		"created": float64(10),
+		"Extra":   true,
+	}, &got)
+	if err != nil {
+		t.Fatalf("DecodeClaims returned an error %v", err)
+	}
+
+	want := payload{ID: 12, Name: "3", Tags: []string{"a", "b"}, Owners: []string{"me"}, Created: time.Unix(10, 0), Extra: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeClaims returned %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeClaims_Errors(t *testing.T) {
+	type payload struct {
+		ID   uint8 `claim:"id,required"`
+		Flag bool  `claim:"flag"`
+	}
+	tests := []struct {
+		claims map[string]interface{}
+		want   string
+	}{
+		{map[string]interface{}{}, `claim "id": missing`},
+		{map[string]interface{}{"id": float64(300)}, `claim "id": 300 is not a valid uint8`},
+		{map[string]interface{}{"id": float64(1.5)}, `claim "id": cannot decode float64 into uint8`},
+		{map[string]interface{}{"id": float64(1), "flag": "yes"}, `claim "flag": cannot decode string into bool`},
+	}
+	for _, tt := range tests {
+		err := DecodeClaims(tt.claims, &payload{})
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("DecodeClaims(%v) returned error %v, want %s", tt.claims, err, tt.want)
+		}
+	}
+}
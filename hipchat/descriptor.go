@@ -0,0 +1,535 @@
+package hipchat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// Descriptor represents the capabilities descriptor of a HipChat add-on.
+//
+// HipChat docs: https://developer.atlassian.com/hipchat/guide/capabilities-descriptor
+type Descriptor struct {
+	Key          string                 `json:"key"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	Vendor       *Vendor                `json:"vendor,omitempty"`
+	Links        DescriptorLinks        `json:"links"`
+	Capabilities DescriptorCapabilities `json:"capabilities"`
+}
+
+// Vendor represents the vendor of an add-on.
+type Vendor struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// DescriptorLinks represents the links of a Descriptor.
+type DescriptorLinks struct {
+	Self     string `json:"self"`
+	Homepage string `json:"homepage,omitempty"`
+}
+
+// DescriptorCapabilities represents the capabilities declared by a Descriptor.
+type DescriptorCapabilities struct {
+	HipchatAPIConsumer *APIConsumer        `json:"hipchatApiConsumer,omitempty"`
+	Installable        *Installable        `json:"installable,omitempty"`
+	OAuth2Consumer     *OAuth2Consumer     `json:"oauth2Consumer,omitempty"`
+	Webhooks           []WebhookModule     `json:"webhook,omitempty"`
+	Glances            []GlanceModule      `json:"glance,omitempty"`
+	Dialogs            []DialogModule      `json:"dialog,omitempty"`
+	WebPanels          []WebPanelModule    `json:"webPanel,omitempty"`
+	Configurable       *ConfigurableModule `json:"configurable,omitempty"`
+	Actions            []ActionModule      `json:"action,omitempty"`
+}
+
+// APIConsumer declares the scopes an add-on uses the HipChat API with.
+type APIConsumer struct {
+	Scopes []string `json:"scopes"`
+}
+
+// Installable declares where HipChat notifies an add-on of its installations.
+type Installable struct {
+	CallbackURL       string `json:"callbackUrl"`
+	UpdateCallbackURL string `json:"updateCallbackUrl,omitempty"`
+	AllowGlobal       bool   `json:"allowGlobal"`
+	AllowRoom         bool   `json:"allowRoom"`
+}
+
+// OAuth2Consumer declares the URLs HipChat may redirect to during OAuth2 flows.
+type OAuth2Consumer struct {
+	RedirectionURLs []string `json:"redirectionUrls"`
+}
+
+// WebhookModule declares a webhook HipChat calls when an event occurs in a room.
+type WebhookModule struct {
+	Event   string `json:"event"`
+	URL     string `json:"url"`
+	Name    string `json:"name,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	// Key identifies the webhook among those of the add-on.
+	Key string `json:"key,omitempty"`
+	// Authentication is how the webhook requests are signed, one of the
+	// WebhookAuthentication constants.
+	Authentication string `json:"authentication,omitempty"`
+}
+
+// GlanceModule declares a glance shown in the HipChat sidebar.
+type GlanceModule struct {
+	Key      string `json:"key"`
+	Name     Name   `json:"name"`
+	QueryURL string `json:"queryUrl,omitempty"`
+	Icon     Icon   `json:"icon"`
+	// Target is the key of the module opened when the glance is clicked, e.g.
+	// a web panel or a dialog.
+	Target string `json:"target,omitempty"`
+	// Conditions restrict where the glance is shown, e.g. to room admins.
+	Conditions []ModuleCondition `json:"conditions,omitempty"`
+	// Weight orders the glances, lighter ones first.
+	Weight int `json:"weight,omitempty"`
+}
+
+// Conditions HipChat evaluates to show a module.
+const (
+	ConditionRoomIsPublic    = "room_is_public"
+	ConditionUserIsAdmin     = "user_is_admin"
+	ConditionUserIsGuest     = "user_is_guest"
+	ConditionUserIsRoomOwner = "user_is_room_owner"
+	ConditionGlanceMatches   = "glance_matches"
+)
+
+// ModuleCondition is a condition under which a module is shown: either a
+// single condition with its parameters, or the combination of Conditions
+// according to Type, "and" or "or".
+type ModuleCondition struct {
+	Condition  string            `json:"condition,omitempty"`
+	Params     map[string]string `json:"params,omitempty"`
+	Invert     bool              `json:"invert,omitempty"`
+	Type       string            `json:"type,omitempty"`
+	Conditions []ModuleCondition `json:"conditions,omitempty"`
+}
+
+// Condition returns the condition with the given name, e.g.
+// ConditionUserIsAdmin.
+func Condition(name string) ModuleCondition {
+	return ModuleCondition{Condition: name}
+}
+
+// Not returns the inverse of c.
+func (c ModuleCondition) Not() ModuleCondition {
+	c.Invert = !c.Invert
+	return c
+}
+
+// AllOf returns the condition met when all of conditions are.
+func AllOf(conditions ...ModuleCondition) ModuleCondition {
+	return ModuleCondition{Type: "and", Conditions: conditions}
+}
+
+// AnyOf returns the condition met when any of conditions is.
+func AnyOf(conditions ...ModuleCondition) ModuleCondition {
+	return ModuleCondition{Type: "or", Conditions: conditions}
+}
+
+// Locations of actions.
+const (
+	// ActionLocationMessage adds the action to the menu of messages.
+	ActionLocationMessage = "hipchat.message.action"
+	// ActionLocationInput adds the action to the menu of the chat input.
+	ActionLocationInput = "hipchat.input.action"
+)
+
+// ActionModule declares an action, an entry of the message or input menus
+// opening its target, a dialog or a web panel of the add-on.
+type ActionModule struct {
+	Key        string            `json:"key"`
+	Name       Name              `json:"name"`
+	Target     string            `json:"target"`
+	Location   string            `json:"location"`
+	Conditions []ModuleCondition `json:"conditions,omitempty"`
+	Weight     int               `json:"weight,omitempty"`
+}
+
+// ConfigurableModule declares the configuration page of an add-on, shown to
+// the admins of the rooms or group it is installed in.
+type ConfigurableModule struct {
+	URL string `json:"url"`
+}
+
+// WebPanelLocationSidebar is the location of the web panels shown in the right
+// sidebar of HipChat.
+const WebPanelLocationSidebar = "hipchat.sidebar.right"
+
+// WebPanelModule declares a web panel, a page of the add-on shown in HipChat,
+// e.g. opened by clicking a glance targeting it.
+type WebPanelModule struct {
+	Key        string            `json:"key"`
+	Name       Name              `json:"name"`
+	URL        string            `json:"url"`
+	Location   string            `json:"location"`
+	Icon       *Icon             `json:"icon,omitempty"`
+	Conditions []ModuleCondition `json:"conditions,omitempty"`
+	Weight     int               `json:"weight,omitempty"`
+}
+
+// DialogModule declares a dialog the add-on can open.
+type DialogModule struct {
+	Key     string         `json:"key"`
+	Title   Name           `json:"title"`
+	URL     string         `json:"url"`
+	Options *DialogOptions `json:"options,omitempty"`
+}
+
+// Styles of dialogs.
+const (
+	DialogStyleNormal  = "normal"
+	DialogStyleWarning = "warning"
+)
+
+// DialogOptions represents the options of a dialog, declared in its module or
+// passed to HipChat.dialog.open by the Javascript API.
+type DialogOptions struct {
+	Style            string         `json:"style,omitempty"`
+	PrimaryAction    *DialogAction  `json:"primaryAction,omitempty"`
+	SecondaryActions []DialogAction `json:"secondaryActions,omitempty"`
+	Size             *DialogSize    `json:"size,omitempty"`
+	Hint             *Name          `json:"hint,omitempty"`
+	Filter           *DialogFilter  `json:"filter,omitempty"`
+}
+
+// DialogAction represents a button of a dialog.
+type DialogAction struct {
+	Key     string `json:"key"`
+	Name    Name   `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// NewDialogAction returns an enabled button with the given key and name.
+func NewDialogAction(key, name string) DialogAction {
+	return DialogAction{Key: key, Name: Name{Value: name}, Enabled: true}
+}
+
+// DialogSize represents the size of a dialog, as CSS lengths, e.g. "600px".
+type DialogSize struct {
+	Width  string `json:"width,omitempty"`
+	Height string `json:"height,omitempty"`
+}
+
+// DialogFilter adds a filter input to the header of a dialog.
+type DialogFilter struct {
+	Placeholder Name `json:"placeholder"`
+}
+
+// DialogOpenRequest represents the parameter of HipChat.dialog.open, with
+// which the Javascript API of an add-on opens a dialog.
+type DialogOpenRequest struct {
+	Key           string            `json:"key"`
+	Title         string            `json:"title,omitempty"`
+	Options       *DialogOptions    `json:"options,omitempty"`
+	URLParameters map[string]string `json:"urlParameters,omitempty"`
+}
+
+// JS returns r as a Javascript object literal, safe to use in the scripts of
+// html/template templates, e.g.
+//
+//	<script>HipChat.dialog.open({{.Dialog.JS}});</script>
+func (r *DialogOpenRequest) JS() (template.JS, error) {
+	return marshalJS(r)
+}
+
+// JS returns o as a Javascript object literal, e.g. for
+// HipChat.dialog.update.
+func (o *DialogOptions) JS() (template.JS, error) {
+	return marshalJS(o)
+}
+
+// marshalJS returns the JSON encoding of v, whose HTML characters are escaped
+// so that it can be inlined in a script.
+func marshalJS(v interface{}) (template.JS, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(data), nil
+}
+
+// DescriptorBuilder builds a Descriptor.
+type DescriptorBuilder struct {
+	d Descriptor
+}
+
+// NewDescriptor starts building the descriptor of the add-on with the given key and name.
+func NewDescriptor(key, name string) *DescriptorBuilder {
+	return &DescriptorBuilder{d: Descriptor{Key: key, Name: name}}
+}
+
+// Description sets the description of the add-on.
+func (b *DescriptorBuilder) Description(description string) *DescriptorBuilder {
+	b.d.Description = description
+	return b
+}
+
+// Vendor sets the vendor of the add-on.
+func (b *DescriptorBuilder) Vendor(name, url string) *DescriptorBuilder {
+	b.d.Vendor = &Vendor{Name: name, URL: url}
+	return b
+}
+
+// Links sets the URL the descriptor is served from and the homepage of the add-on.
+func (b *DescriptorBuilder) Links(self, homepage string) *DescriptorBuilder {
+	b.d.Links = DescriptorLinks{Self: self, Homepage: homepage}
+	return b
+}
+
+// Installable declares the URLs HipChat notifies of installations and updates,
+// and whether the add-on can be installed globally and in rooms.
+func (b *DescriptorBuilder) Installable(callbackURL, updateCallbackURL string, allowGlobal, allowRoom bool) *DescriptorBuilder {
+	b.d.Capabilities.Installable = &Installable{
+		CallbackURL:       callbackURL,
+		UpdateCallbackURL: updateCallbackURL,
+		AllowGlobal:       allowGlobal,
+		AllowRoom:         allowRoom,
+	}
+	return b
+}
+
+// Scopes declares the scopes the add-on uses the HipChat API with.
+func (b *DescriptorBuilder) Scopes(scopes ...string) *DescriptorBuilder {
+	b.d.Capabilities.HipchatAPIConsumer = &APIConsumer{Scopes: scopes}
+	return b
+}
+
+// OAuth2Consumer declares the URLs HipChat may redirect to during OAuth2 flows.
+func (b *DescriptorBuilder) OAuth2Consumer(redirectionURLs ...string) *DescriptorBuilder {
+	b.d.Capabilities.OAuth2Consumer = &OAuth2Consumer{RedirectionURLs: redirectionURLs}
+	return b
+}
+
+// Webhook declares a webhook HipChat calls at url when event occurs.
+func (b *DescriptorBuilder) Webhook(event, url string) *DescriptorBuilder {
+	b.d.Capabilities.Webhooks = append(b.d.Capabilities.Webhooks, WebhookModule{Event: event, URL: url})
+	return b
+}
+
+// AddWebhook declares a webhook with all its settings, e.g. its pattern and
+// authentication.
+func (b *DescriptorBuilder) AddWebhook(webhook WebhookModule) *DescriptorBuilder {
+	b.d.Capabilities.Webhooks = append(b.d.Capabilities.Webhooks, webhook)
+	return b
+}
+
+// Glance declares a glance, whose content is fetched from queryURL.
+func (b *DescriptorBuilder) Glance(key, name, queryURL, iconURL string) *DescriptorBuilder {
+	b.d.Capabilities.Glances = append(b.d.Capabilities.Glances, GlanceModule{
+		Key:      key,
+		Name:     Name{Value: name},
+		QueryURL: queryURL,
+		Icon:     Icon{URL: iconURL},
+	})
+	return b
+}
+
+// AddGlance declares a glance with all its settings, e.g. its target and
+// conditions.
+func (b *DescriptorBuilder) AddGlance(glance GlanceModule) *DescriptorBuilder {
+	b.d.Capabilities.Glances = append(b.d.Capabilities.Glances, glance)
+	return b
+}
+
+// Dialog declares a dialog showing url.
+func (b *DescriptorBuilder) Dialog(key, title, url string) *DescriptorBuilder {
+	b.d.Capabilities.Dialogs = append(b.d.Capabilities.Dialogs, DialogModule{
+		Key:   key,
+		Title: Name{Value: title},
+		URL:   url,
+	})
+	return b
+}
+
+// AddWebPanel declares a web panel. Its location defaults to
+// WebPanelLocationSidebar.
+func (b *DescriptorBuilder) AddWebPanel(panel WebPanelModule) *DescriptorBuilder {
+	if panel.Location == "" {
+		panel.Location = WebPanelLocationSidebar
+	}
+	b.d.Capabilities.WebPanels = append(b.d.Capabilities.WebPanels, panel)
+	return b
+}
+
+// AddAction declares an action.
+func (b *DescriptorBuilder) AddAction(action ActionModule) *DescriptorBuilder {
+	b.d.Capabilities.Actions = append(b.d.Capabilities.Actions, action)
+	return b
+}
+
+// Configurable declares the configuration page of the add-on, served at url.
+func (b *DescriptorBuilder) Configurable(url string) *DescriptorBuilder {
+	b.d.Capabilities.Configurable = &ConfigurableModule{URL: url}
+	return b
+}
+
+// AddDialog declares a dialog with all its settings, e.g. its options.
+func (b *DescriptorBuilder) AddDialog(dialog DialogModule) *DescriptorBuilder {
+	b.d.Capabilities.Dialogs = append(b.d.Capabilities.Dialogs, dialog)
+	return b
+}
+
+// Build returns the descriptor, or an error if it is missing required fields.
+func (b *DescriptorBuilder) Build() (*Descriptor, error) {
+	switch {
+	case b.d.Key == "":
+		return nil, errors.New("Missing descriptor key")
+	case b.d.Name == "":
+		return nil, errors.New("Missing descriptor name")
+	case b.d.Links.Self == "":
+		return nil, errors.New("Missing descriptor self link")
+	}
+	for _, webhook := range b.d.Capabilities.Webhooks {
+		switch {
+		case webhook.Event == "" || webhook.URL == "":
+			return nil, fmt.Errorf("Webhook %q is missing its event or URL", webhook.Key)
+		case webhook.Pattern != "" && webhook.Event != WebhookEventRoomMessage:
+			return nil, fmt.Errorf("Webhook %q has a pattern but is not a %s webhook", webhook.Key, WebhookEventRoomMessage)
+		case webhook.Authentication != "" && webhook.Authentication != WebhookAuthenticationJWT && webhook.Authentication != WebhookAuthenticationNone:
+			return nil, fmt.Errorf("Webhook %q has an unknown authentication %q", webhook.Key, webhook.Authentication)
+		}
+	}
+	for _, glance := range b.d.Capabilities.Glances {
+		if glance.Key == "" || glance.Name.Value == "" || glance.Icon.URL == "" {
+			return nil, fmt.Errorf("Glance %q is missing its key, name or icon", glance.Key)
+		}
+	}
+	for _, dialog := range b.d.Capabilities.Dialogs {
+		if dialog.Key == "" || dialog.Title.Value == "" || dialog.URL == "" {
+			return nil, fmt.Errorf("Dialog %q is missing its key, title or URL", dialog.Key)
+		}
+	}
+	for _, action := range b.d.Capabilities.Actions {
+		switch {
+		case action.Key == "" || action.Name.Value == "" || action.Target == "":
+			return nil, fmt.Errorf("Action %q is missing its key, name or target", action.Key)
+		case action.Location != ActionLocationMessage && action.Location != ActionLocationInput:
+			return nil, fmt.Errorf("Action %q has an unknown location %q", action.Key, action.Location)
+		}
+	}
+	for _, panel := range b.d.Capabilities.WebPanels {
+		if panel.Key == "" || panel.Name.Value == "" || panel.URL == "" {
+			return nil, fmt.Errorf("Web panel %q is missing its key, name or URL", panel.Key)
+		}
+	}
+	d := b.d
+	return &d, nil
+}
+
+// WithDescriptorModules lets modules be added to the descriptor served by the
+// integration. The function is called with the URL the routes of the integration
+// are served under, including the route prefix.
+func WithDescriptorModules(modules func(b *DescriptorBuilder, baseURL string)) IntegrationOption {
+	return func(i *Integration) {
+		i.descriptorModules = modules
+	}
+}
+
+// WithDescriptorFunc makes the integration serve the descriptor returned by fn
+// for each request, e.g. to use different add-on keys per environment or host.
+// When fn returns nil, the generated descriptor is served.
+func WithDescriptorFunc(fn func(r *http.Request) *Descriptor) IntegrationOption {
+	return func(i *Integration) {
+		i.descriptorFunc = fn
+	}
+}
+
+// WithAddOn sets the key, name and description of the add-on in the descriptor
+// served by the integration.
+func WithAddOn(key, name, description string) IntegrationOption {
+	return func(i *Integration) {
+		i.addOnKey = key
+		i.addOnName = name
+		i.addOnDescription = description
+	}
+}
+
+// WithInstallable sets whether the add-on can be installed globally and in rooms.
+// Both are allowed by default.
+func WithInstallable(allowGlobal, allowRoom bool) IntegrationOption {
+	return func(i *Integration) {
+		i.allowGlobal = allowGlobal
+		i.allowRoom = allowRoom
+	}
+}
+
+// Descriptor returns the descriptor of the add-on, with URLs derived from the
+// base URL set with WithBaseURL.
+func (i *Integration) Descriptor() (*Descriptor, error) {
+	return i.descriptor(i.baseURL)
+}
+
+// descriptor generates the descriptor of the add-on served from baseURL.
+func (i *Integration) descriptor(baseURL string) (*Descriptor, error) {
+	root := baseURL + i.routePrefix
+	scopes := i.scopes
+	if len(scopes) == 0 {
+		scopes = []string{ScopeSendNotification}
+	}
+
+	b := NewDescriptor(i.addOnKey, i.addOnName).
+		Description(i.addOnDescription).
+		Links(root+"/capabilities", baseURL).
+		Scopes(scopes...).
+		Installable(root+"/installed", root+"/updated", i.allowGlobal, i.allowRoom)
+	for _, m := range i.moduleRoutes {
+		if m.declare != nil {
+			m.declare(b, root+m.path)
+		}
+	}
+	if i.descriptorModules != nil {
+		i.descriptorModules(b, root)
+	}
+	return b.Build()
+}
+
+// requestBaseURL returns the configured base URL or, when none is configured,
+// the one the request was made to. Its scheme is taken from the
+// X-Forwarded-Proto header only with WithForwardedProto, and only when it is
+// http or https.
+func (i *Integration) requestBaseURL(r *http.Request) string {
+	if i.baseURL != "" {
+		return i.baseURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if i.forwardedProto {
+		switch proto := strings.ToLower(r.Header.Get("X-Forwarded-Proto")); proto {
+		case "http", "https":
+			scheme = proto
+		}
+	}
+	return scheme + "://" + r.Host
+}
+
+// HandleDescriptor serves the descriptor of the add-on. Without WithBaseURL,
+// its URLs are derived from the Host the request was made to, which clients
+// choose: set WithBaseURL unless a proxy in front of the integration checks
+// it.
+func (i *Integration) HandleDescriptor(w http.ResponseWriter, r *http.Request) {
+	var d *Descriptor
+	var err error
+	if i.descriptorFunc != nil {
+		d = i.descriptorFunc(r)
+	}
+	if d == nil {
+		d, err = i.descriptor(i.requestBaseURL(r))
+	}
+	if err != nil {
+		i.logger.Printf("Error generating descriptor: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrorCodeBadDescriptor, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
@@ -0,0 +1,305 @@
+package hipchat
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDescriptorBuilder(t *testing.T) {
+	d, err := NewDescriptor("com.example.addon", "Example").
+		Description("An example").
+		Vendor("Example Inc", "https://example.com").
+		Links("https://example.com/capabilities", "https://example.com").
+		Scopes(ScopeSendNotification).
+		Installable("https://example.com/installed", "https://example.com/updated", true, false).
+		Webhook("room_message", "https://example.com/message").
+		Glance("g", "Glance", "https://example.com/glance", "https://example.com/icon.png").
+		Dialog("d", "Dialog", "https://example.com/dialog").
+		Build()
+	if err != nil {
+		t.Fatalf("DescriptorBuilder.Build returns an error %v", err)
+	}
+
+	want := `{
+		"key": "com.example.addon",
+		"name": "Example",
+		"description": "An example",
+		"vendor": {"name": "Example Inc", "url": "https://example.com"},
+		"links": {"self": "https://example.com/capabilities", "homepage": "https://example.com"},
+		"capabilities": {
+			"hipchatApiConsumer": {"scopes": ["send_notification"]},
+			"installable": {
+				"callbackUrl": "https://example.com/installed",
+				"updateCallbackUrl": "https://example.com/updated",
+				"allowGlobal": true,
+				"allowRoom": false
+			},
+			"webhook": [{"event": "room_message", "url": "https://example.com/message"}],
+			"glance": [{
+				"key": "g",
+				"name": {"value": "Glance"},
+				"queryUrl": "https://example.com/glance",
+				"icon": {"url": "https://example.com/icon.png"}
+			}],
+			"dialog": [{"key": "d", "title": {"value": "Dialog"}, "url": "https://example.com/dialog"}]
+		}
+	}`
+
+	testJSONEqual(t, d, want)
+}
+
+func TestDescriptorBuilder_MissingKey(t *testing.T) {
+	_, err := NewDescriptor("", "Example").Links("https://example.com/capabilities", "").Build()
+	if err == nil {
+		t.Errorf("DescriptorBuilder.Build without key returns no error")
+	}
+}
+
+// testJSONEqual checks that v marshals to JSON equivalent to want.
+func testJSONEqual(t *testing.T, v interface{}, want string) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal returns an error %v", err)
+	}
+	var got, expected interface{}
+	json.Unmarshal(data, &got)
+	if err := json.Unmarshal([]byte(want), &expected); err != nil {
+		t.Fatalf("Invalid expected JSON: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("JSON %s, want %s", data, want)
+	}
+}
+
+func TestDescriptorBuilder_AddGlance(t *testing.T) {
+	d, err := NewDescriptor("com.example.addon", "Example").
+		Links("https://example.com/capabilities", "").
+		AddGlance(GlanceModule{
+			Key:      "g",
+			Name:     Name{Value: "Glance"},
+			QueryURL: "https://example.com/glance",
+			Icon:     Icon{URL: "https://example.com/icon.png", URL2x: "https://example.com/icon@2x.png"},
+			Target:   "panel",
+			Conditions: []ModuleCondition{
+				AnyOf(Condition(ConditionUserIsAdmin), Condition(ConditionUserIsGuest).Not()),
+			},
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("DescriptorBuilder.Build returns an error %v", err)
+	}
+
+	want := `[{
+		"key": "g",
+		"name": {"value": "Glance"},
+		"queryUrl": "https://example.com/glance",
+		"icon": {"url": "https://example.com/icon.png", "url@2x": "https://example.com/icon@2x.png"},
+		"target": "panel",
+		"conditions": [{"type": "or", "conditions": [
+			{"condition": "user_is_admin"},
+			{"condition": "user_is_guest", "invert": true}
+		]}]
+	}]`
+	testJSONEqual(t, d.Capabilities.Glances, want)
+}
+
+func TestDescriptorBuilder_InvalidGlance(t *testing.T) {
+	_, err := NewDescriptor("com.example.addon", "Example").
+		Links("https://example.com/capabilities", "").
+		AddGlance(GlanceModule{Key: "g", Name: Name{Value: "Glance"}}).
+		Build()
+	if err == nil {
+		t.Errorf("DescriptorBuilder.Build with a glance without icon returns no error")
+	}
+}
+
+func TestDescriptorBuilder_AddDialog(t *testing.T) {
+	d, err := NewDescriptor("com.example.addon", "Example").
+		Links("https://example.com/capabilities", "").
+		AddDialog(DialogModule{
+			Key:   "d",
+			Title: Name{Value: "Dialog"},
+			URL:   "https://example.com/dialog",
+			Options: &DialogOptions{
+				Style:            DialogStyleWarning,
+				PrimaryAction:    &DialogAction{Key: "ok", Name: Name{Value: "OK"}},
+				SecondaryActions: []DialogAction{NewDialogAction("cancel", "Cancel")},
+				Size:             &DialogSize{Width: "600px", Height: "400px"},
+				Filter:           &DialogFilter{Placeholder: Name{Value: "Search"}},
+			},
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("DescriptorBuilder.Build returns an error %v", err)
+	}
+
+	want := `[{
+		"key": "d",
+		"title": {"value": "Dialog"},
+		"url": "https://example.com/dialog",
+		"options": {
+			"style": "warning",
+			"primaryAction": {"key": "ok", "name": {"value": "OK"}, "enabled": false},
+			"secondaryActions": [{"key": "cancel", "name": {"value": "Cancel"}, "enabled": true}],
+			"size": {"width": "600px", "height": "400px"},
+			"filter": {"placeholder": {"value": "Search"}}
+		}
+	}]`
+	testJSONEqual(t, d.Capabilities.Dialogs, want)
+}
+
+func TestDialogOpenRequest_JS(t *testing.T) {
+	r := &DialogOpenRequest{Key: "d", Title: "</script>", URLParameters: map[string]string{"id": "1"}}
+
+	js, err := r.JS()
+	if err != nil {
+		t.Fatalf("DialogOpenRequest.JS returns an error %v", err)
+	}
+	if want := `{"key":"d","title":"\u003c/script\u003e","urlParameters":{"id":"1"}}`; string(js) != want {
+		t.Errorf("DialogOpenRequest.JS returned %s, want %s", js, want)
+	}
+}
+
+func TestDescriptorBuilder_InvalidWebhook(t *testing.T) {
+	_, err := NewDescriptor("com.example.addon", "Example").
+		Links("https://example.com/capabilities", "").
+		AddWebhook(WebhookModule{Event: WebhookEventRoomEnter, URL: "https://example.com/enter", Pattern: "^/hello"}).
+		Build()
+	if err == nil {
+		t.Errorf("DescriptorBuilder.Build with a room_enter webhook with a pattern returns no error")
+	}
+}
+
+func TestHandleDescriptor_BaseURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []IntegrationOption
+		tls   bool
+		proto string
+		want  string
+	}{
+		{"request", nil, false, "", "http://addon.example.com"},
+		{"TLS request", nil, true, "", "https://addon.example.com"},
+		{"untrusted forwarded proto", nil, false, "https", "http://addon.example.com"},
+		{"forwarded proto", []IntegrationOption{WithForwardedProto()}, false, "HTTPS", "https://addon.example.com"},
+		{"invalid forwarded proto", []IntegrationOption{WithForwardedProto()}, true, "javascript", "https://addon.example.com"},
+		{"base URL", []IntegrationOption{WithBaseURL("https://public.example.com/"), WithForwardedProto()}, false, "http", "https://public.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []IntegrationOption{WithAddOn("com.example.addon", "Example", "An example"), WithLogger(log.New(ioutil.Discard, "", 0))}
+			i := NewIntegration(newMemoryStore(), append(opts, tt.opts...)...)
+			r := httptest.NewRequest("GET", "http://addon.example.com/capabilities", nil)
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			w := httptest.NewRecorder()
+			routesHandler(i).ServeHTTP(w, r)
+
+			var d Descriptor
+			if err := json.NewDecoder(w.Body).Decode(&d); err != nil || w.Code != http.StatusOK {
+				t.Fatalf("GET /capabilities answered %d, %v", w.Code, err)
+			}
+			if d.Links.Homepage != tt.want || d.Links.Self != tt.want+"/capabilities" {
+				t.Errorf("Descriptor links are %+v, want the base URL %s", d.Links, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleDescriptor(t *testing.T) {
+	i := NewIntegration(newMemoryStore(),
+		WithAddOn("com.example.addon", "Example", "An example"),
+		WithBaseURL("https://addon.example.com"),
+		WithRoutePrefix("/hipchat"),
+		WithScopes(ScopeSendNotification, ScopeViewGroup),
+		WithInstallable(true, false),
+		WithDescriptorModules(func(b *DescriptorBuilder, baseURL string) {
+			b.AddWebhook(WebhookModule{Event: WebhookEventRoomEnter, URL: baseURL + "/enter"})
+		}),
+		WithLogger(log.New(ioutil.Discard, "", 0)))
+	r := httptest.NewRequest("GET", "https://addon.example.com/hipchat/capabilities", nil)
+	w := httptest.NewRecorder()
+	i.GetHandler().ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("GET /hipchat/capabilities answered %d %s %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+	want := `{
+		"key": "com.example.addon",
+		"name": "Example",
+		"description": "An example",
+		"links": {"self": "https://addon.example.com/hipchat/capabilities", "homepage": "https://addon.example.com"},
+		"capabilities": {
+			"hipchatApiConsumer": {"scopes": ["send_notification", "view_group"]},
+			"installable": {
+				"callbackUrl": "https://addon.example.com/hipchat/installed",
+				"updateCallbackUrl": "https://addon.example.com/hipchat/updated",
+				"allowGlobal": true,
+				"allowRoom": false
+			},
+			"webhook": [{"event": "room_enter", "url": "https://addon.example.com/hipchat/enter"}]
+		}
+	}`
+	testJSONEqual(t, json.RawMessage(w.Body.Bytes()), want)
+}
+
+func TestHandleDescriptor_Invalid(t *testing.T) {
+	// Without WithAddOn, the generated descriptor has no key.
+	i := NewIntegration(newMemoryStore(), WithBaseURL("https://addon.example.com"), WithLogger(log.New(ioutil.Discard, "", 0)))
+	w := httptest.NewRecorder()
+	routesHandler(i).ServeHTTP(w, httptest.NewRequest("GET", "/capabilities", nil))
+
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), ErrorCodeBadDescriptor) {
+		t.Errorf("GET /capabilities answered %d %s, want %d %s", w.Code, w.Body, http.StatusInternalServerError, ErrorCodeBadDescriptor)
+	}
+}
+
+func TestWithDescriptorFunc(t *testing.T) {
+	staging := &Descriptor{Key: "com.example.addon.staging", Name: "Example (staging)"}
+	var requests []*http.Request
+	i := NewIntegration(newMemoryStore(),
+		WithAddOn("com.example.addon", "Example", "An example"),
+		WithBaseURL("https://addon.example.com"),
+		WithDescriptorFunc(func(r *http.Request) *Descriptor {
+			requests = append(requests, r)
+			if r.Host == "staging.example.com" {
+				return staging
+			}
+			return nil
+		}),
+		WithLogger(log.New(ioutil.Discard, "", 0)))
+
+	tests := []struct {
+		host    string
+		wantKey string
+	}{
+		{"staging.example.com", "com.example.addon.staging"},
+		{"addon.example.com", "com.example.addon"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		routesHandler(i).ServeHTTP(w, httptest.NewRequest("GET", "https://"+tt.host+"/capabilities", nil))
+
+		var d Descriptor
+		if err := json.NewDecoder(w.Body).Decode(&d); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("GET /capabilities on %s answered %d, %v", tt.host, w.Code, err)
+		}
+		if d.Key != tt.wantKey {
+			t.Errorf("GET /capabilities on %s served the descriptor of %s, want %s", tt.host, d.Key, tt.wantKey)
+		}
+	}
+	if len(requests) != len(tests) {
+		t.Errorf("Descriptor func was called %d times, want %d", len(requests), len(tests))
+	}
+}
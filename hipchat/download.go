@@ -0,0 +1,35 @@
+package hipchat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// DownloadFile writes the content of a file shared in a room or a private
+// chat, e.g. the File of a Message of the history or of a room_file_upload
+// webhook, to w. The URL of the file is signed, so the request is only
+// authenticated like those of the API methods when the URL has the scheme and
+// host of BaseURL, as with HipChat Server.
+func (c *Client) DownloadFile(file *MessageFile, w io.Writer) (*http.Response, error) {
+	return c.DownloadFileContext(context.Background(), file, w)
+}
+
+// DownloadFileContext is like DownloadFile, with ctx controlling the request.
+func (c *Client) DownloadFileContext(ctx context.Context, file *MessageFile, w io.Writer) (*http.Response, error) {
+	if file == nil || file.URL == "" {
+		return nil, errors.New("hipchat: no file URL to download")
+	}
+	req, err := http.NewRequest("GET", file.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL.Scheme == c.BaseURL.Scheme && req.URL.Host == c.BaseURL.Host {
+		if err := c.authorize(req); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.DoContext(ctx, req, w)
+}
@@ -0,0 +1,86 @@
+package hipchat
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDownloadFile(t *testing.T) {
+	setup()
+	defer teardown()
+
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Authorization = %q sent to the file host", got)
+		}
+		if got := r.URL.Query().Get("sig"); got != "s" {
+			t.Errorf("sig = %q, want s", got)
+		}
+		fmt.Fprint(w, "content")
+	}))
+	defer files.Close()
+
+	var buf bytes.Buffer
+	file := &MessageFile{Name: "f.txt", URL: files.URL + "/files/1/f.txt?sig=s"}
+	if _, err := client.DownloadFile(file, &buf); err != nil {
+		t.Fatalf("DownloadFile returns an error %v", err)
+	}
+	if buf.String() != "content" {
+		t.Errorf("DownloadFile wrote %q, want content", buf.String())
+	}
+}
+
+func TestDownloadFile_APIHost(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/files/1/f.txt", func(w http.ResponseWriter, r *http.Request) {
+		testHeader(t, r, "Authorization", "Bearer AuthToken")
+		fmt.Fprint(w, "content")
+	})
+
+	var buf bytes.Buffer
+	if _, err := client.DownloadFile(&MessageFile{URL: server.URL + "/files/1/f.txt"}, &buf); err != nil {
+		t.Fatalf("DownloadFile returns an error %v", err)
+	}
+}
+
+func TestDownloadFile_APIHostOtherScheme(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/files/1/f.txt", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Authorization = %q sent over another scheme", got)
+		}
+	})
+	// The API is served over https, the file over http.
+	client.BaseURL.Scheme = "https"
+
+	var buf bytes.Buffer
+	if _, err := client.DownloadFile(&MessageFile{URL: server.URL + "/files/1/f.txt"}, &buf); err != nil {
+		t.Fatalf("DownloadFile returns an error %v", err)
+	}
+}
+
+func TestDownloadFile_JWTSigner(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/files/1/f.txt", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); !strings.HasPrefix(got, "JWT ") {
+			t.Errorf("Authorization = %q, want a JWT", got)
+		}
+	})
+	client.SetJWTSigner(&JWTSigner{Issuer: "oauth", Secret: "secret"})
+
+	var buf bytes.Buffer
+	if _, err := client.DownloadFile(&MessageFile{URL: server.URL + "/files/1/f.txt"}, &buf); err != nil {
+		t.Fatalf("DownloadFile returns an error %v", err)
+	}
+}
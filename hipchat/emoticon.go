@@ -1,6 +1,9 @@
 package hipchat
 
 import (
+	"context"
+	"encoding/base64"
+	"fmt"
 	"net/http"
 )
 
@@ -17,12 +20,18 @@ type Emoticons struct {
 	Links      PageLinks  `json:"links"`
 }
 
-// Emoticon represents a hipchat emoticon.
+// Emoticon represents a hipchat emoticon. The size, audio and creator are only
+// returned by EmoticonService.Get.
 type Emoticon struct {
-	ID       int    `json:"id"`
-	URL      string `json:"url"`
-	Links    Links  `json:"links"`
-	Shortcut string `json:"shortcut"`
+	ID        int    `json:"id"`
+	URL       string `json:"url"`
+	Links     Links  `json:"links"`
+	Shortcut  string `json:"shortcut"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	AudioPath string `json:"audio_path,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Creator   *User  `json:"creator,omitempty"`
 }
 
 // EmoticonsListOptions specifies the optionnal parameters of the EmoticonService.List
@@ -38,15 +47,94 @@ type EmoticonsListOptions struct {
 //
 // HipChat api docs : https://www.hipchat.com/docs/apiv2/method/get_all_emoticons
 func (e *EmoticonService) List(opt *EmoticonsListOptions) (*Emoticons, *http.Response, error) {
+	return e.ListContext(context.Background(), opt)
+}
+
+// ListContext is like List, with ctx controlling the request.
+func (e *EmoticonService) ListContext(ctx context.Context, opt *EmoticonsListOptions) (*Emoticons, *http.Response, error) {
 	req, err := e.client.NewRequest("GET", "emoticon", opt, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	emoticons := new(Emoticons)
-	resp, err := e.client.Do(req, emoticons)
+	resp, err := e.client.DoContext(ctx, req, emoticons)
 	if err != nil {
 		return nil, resp, err
 	}
 	return emoticons, resp, nil
 }
+
+// Get returns the emoticon specified by the id or shortcut.
+//
+// HipChat api docs : https://www.hipchat.com/docs/apiv2/method/get_emoticon
+func (e *EmoticonService) Get(idOrShortcut string) (*Emoticon, *http.Response, error) {
+	return e.GetContext(context.Background(), idOrShortcut)
+}
+
+// GetContext is like Get, with ctx controlling the request.
+func (e *EmoticonService) GetContext(ctx context.Context, idOrShortcut string) (*Emoticon, *http.Response, error) {
+	req, err := e.client.NewRequest("GET", fmt.Sprintf("emoticon/%s", idOrShortcut), nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	emoticon := new(Emoticon)
+	resp, err := e.client.DoContext(ctx, req, emoticon)
+	if err != nil {
+		return nil, resp, err
+	}
+	return emoticon, resp, nil
+}
+
+// CreateEmoticonRequest represents a HipChat emoticon creation request. The
+// image is given either by URL, or inline as a base64-encoded PNG, JPEG or
+// GIF.
+type CreateEmoticonRequest struct {
+	Shortcut string `json:"shortcut"`
+	URL      string `json:"url,omitempty"`
+	Image    string `json:"image,omitempty"`
+}
+
+// NewCreateEmoticonRequest returns the request creating an emoticon with the
+// given shortcut, without parentheses, from image data.
+func NewCreateEmoticonRequest(shortcut string, image []byte) *CreateEmoticonRequest {
+	return &CreateEmoticonRequest{Shortcut: shortcut, Image: base64.StdEncoding.EncodeToString(image)}
+}
+
+// Create adds a custom emoticon to the group. It requires an admin token with
+// the admin_group scope.
+func (e *EmoticonService) Create(emoReq *CreateEmoticonRequest) (*Emoticon, *http.Response, error) {
+	return e.CreateContext(context.Background(), emoReq)
+}
+
+// CreateContext is like Create, with ctx controlling the request.
+func (e *EmoticonService) CreateContext(ctx context.Context, emoReq *CreateEmoticonRequest) (*Emoticon, *http.Response, error) {
+	req, err := e.client.NewRequest("POST", "emoticon", nil, emoReq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	emoticon := new(Emoticon)
+	resp, err := e.client.DoContext(ctx, req, emoticon)
+	if err != nil {
+		return nil, resp, err
+	}
+	return emoticon, resp, nil
+}
+
+// Delete removes a custom emoticon of the group, given by id or shortcut. It
+// requires an admin token with the admin_group scope.
+func (e *EmoticonService) Delete(idOrShortcut string) (*http.Response, error) {
+	return e.DeleteContext(context.Background(), idOrShortcut)
+}
+
+// DeleteContext is like Delete, with ctx controlling the request.
+func (e *EmoticonService) DeleteContext(ctx context.Context, idOrShortcut string) (*http.Response, error) {
+	req, err := e.client.NewRequest("DELETE", fmt.Sprintf("emoticon/%s", idOrShortcut), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.client.DoContext(ctx, req, nil)
+}
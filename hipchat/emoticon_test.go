@@ -1,6 +1,7 @@
 package hipchat
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -41,3 +42,64 @@ func TestEmoticonList(t *testing.T) {
 		t.Errorf("Emoticon.List returned %+v, want %+v", emos, want)
 	}
 }
+
+func TestEmoticonGet(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/emoticon/s", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprintf(w, `{"id":1, "url":"u", "shortcut":"s", "width":30, "height":25, "type":"group", "links":{"self":"s"}}`)
+	})
+	want := &Emoticon{ID: 1, URL: "u", Shortcut: "s", Width: 30, Height: 25, Type: "group", Links: Links{Self: "s"}}
+
+	emo, _, err := client.Emoticon.Get("s")
+	if err != nil {
+		t.Fatalf("Emoticon.Get returned an error %v", err)
+	}
+	if !reflect.DeepEqual(want, emo) {
+		t.Errorf("Emoticon.Get returned %+v, want %+v", emo, want)
+	}
+}
+
+func TestEmoticonCreate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	args := NewCreateEmoticonRequest("party", []byte("GIF"))
+
+	mux.HandleFunc("/emoticon", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		v := new(CreateEmoticonRequest)
+		json.NewDecoder(r.Body).Decode(v)
+
+		want := &CreateEmoticonRequest{Shortcut: "party", Image: "R0lG"}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("Request body %+v, want %+v", v, want)
+		}
+		fmt.Fprintf(w, `{"id":1,"links":{"self":"s"}}`)
+	})
+	want := &Emoticon{ID: 1, Links: Links{Self: "s"}}
+
+	emo, _, err := client.Emoticon.Create(args)
+	if err != nil {
+		t.Fatalf("Emoticon.Create returns an error %v", err)
+	}
+	if !reflect.DeepEqual(emo, want) {
+		t.Errorf("Emoticon.Create returned %+v, want %+v", emo, want)
+	}
+}
+
+func TestEmoticonDelete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/emoticon/party", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if _, err := client.Emoticon.Delete("party"); err != nil {
+		t.Fatalf("Emoticon.Delete returns an error %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package hipchat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// Errors matched by the ErrorResponses of the corresponding HTTP status, e.g.
+// errors.Is(err, ErrNotFound) when a room does not exist.
+var (
+	ErrBadRequest      = errors.New("bad request")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrForbidden       = errors.New("forbidden")
+	ErrNotFound        = errors.New("not found")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrServerError     = errors.New("server error")
+)
+
+// maxErrorBodyBytes limits how much of an error response is read.
+const maxErrorBodyBytes = 1 << 20
+
+// ErrorResponse is the error returned by API methods when HipChat answers with
+// an error status. It holds the error HipChat describes in the body of the
+// response, e.g. {"error": {"code": 404, "message": "Room not found", "type":
+// "Not Found"}}.
+type ErrorResponse struct {
+	// Response is the HTTP response, whose body can still be read.
+	Response *http.Response `json:"-"`
+	// StatusCode is the HTTP status of the response.
+	StatusCode int `json:"-"`
+	// Code, Message and Type describe the error, when HipChat did.
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+func (e *ErrorResponse) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Server returns status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("Server returns status %d: %s", e.StatusCode, e.Message)
+}
+
+// Is reports whether target is the sentinel error of the status of e, e.g.
+// ErrForbidden for 403 responses, which HipChat returns when the token lacks
+// a scope.
+func (e *ErrorResponse) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrTooManyRequests:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServerError:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// checkResponse returns an ErrorResponse if resp has an error status.
+func checkResponse(resp *http.Response) error {
+	if c := resp.StatusCode; c >= 200 && c <= 299 {
+		return nil
+	}
+	errResp := &ErrorResponse{Response: resp, StatusCode: resp.StatusCode}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	resp.Body.Close()
+	if err == nil {
+		body := struct {
+			Error *ErrorResponse `json:"error"`
+		}{errResp}
+		json.Unmarshal(data, &body)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return errResp
+}
@@ -0,0 +1,55 @@
+package hipchat
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestErrorResponse(t *testing.T) {
+	setup()
+	defer teardown()
+
+	body := `{"error":{"code":404,"message":"Room not found","type":"Not Found"}}`
+	mux.HandleFunc("/room/1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, body)
+	})
+
+	_, resp, err := client.Room.Get("1")
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) {
+		t.Fatalf("Room.Get returned %v, want an *ErrorResponse", err)
+	}
+	if errResp.StatusCode != 404 || errResp.Code != 404 || errResp.Message != "Room not found" || errResp.Type != "Not Found" {
+		t.Errorf("ErrorResponse is %+v", errResp)
+	}
+	if want := "Server returns status 404: Room not found"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrForbidden) {
+		t.Errorf("errors.Is does not match the status of %v", err)
+	}
+	if data, _ := ioutil.ReadAll(resp.Body); string(data) != body {
+		t.Errorf("Response body is %q, want %q", data, body)
+	}
+}
+
+func TestErrorResponse_NoBody(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	_, _, err := client.Room.Get("1")
+	if want := "Server returns status 502"; err == nil || err.Error() != want {
+		t.Errorf("Room.Get returned %v, want %q", err, want)
+	}
+	if !errors.Is(err, ErrServerError) {
+		t.Errorf("%v is not an ErrServerError", err)
+	}
+}
@@ -0,0 +1,38 @@
+package hipchat
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type expandKey struct{}
+
+// WithExpand returns a context that makes the GET requests of the ...Context
+// API methods expand the given fields, e.g. "items" to get full objects in
+// lists, or "participants" and "owner" for rooms. The expanded sub-resources
+// are decoded into the fields of the response structs, e.g.
+//
+//	ctx := hipchat.WithExpand(context.Background(), "items")
+//	users, _, err := client.User.ListContext(ctx, nil)
+func WithExpand(ctx context.Context, fields ...string) context.Context {
+	if previous, ok := ctx.Value(expandKey{}).([]string); ok {
+		fields = append(append([]string(nil), previous...), fields...)
+	}
+	return context.WithValue(ctx, expandKey{}, fields)
+}
+
+// addExpand adds the fields to expand set by WithExpand on ctx to the query of
+// a GET request, after those it already has.
+func addExpand(ctx context.Context, req *http.Request) {
+	fields, ok := ctx.Value(expandKey{}).([]string)
+	if !ok || len(fields) == 0 || req.Method != http.MethodGet {
+		return
+	}
+	query := req.URL.Query()
+	if existing := query.Get("expand"); existing != "" {
+		fields = append([]string{existing}, fields...)
+	}
+	query.Set("expand", strings.Join(fields, ","))
+	req.URL.RawQuery = query.Encode()
+}
@@ -0,0 +1,44 @@
+package hipchat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestWithExpand(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1", func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"expand": "statistics,owner,participants"})
+		fmt.Fprintf(w, `{"id":1, "owner":{"id":2, "name":"o"}, "participants":[{"id":3}]}`)
+	})
+	want := &Room{ID: 1, Owner: User{ID: 2, Name: "o"}, Participants: []User{{ID: 3}}}
+
+	ctx := WithExpand(WithExpand(context.Background(), "owner"), "participants")
+	room, _, err := client.Room.GetWithOptionsContext(ctx, "1", &RoomGetOptions{Expand: "statistics"})
+	if err != nil {
+		t.Fatalf("Room.GetWithOptionsContext returns an error %v", err)
+	}
+	if !reflect.DeepEqual(room, want) {
+		t.Errorf("Room.GetWithOptionsContext returned %+v, want %+v", room, want)
+	}
+}
+
+func TestWithExpand_NotGET(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "" {
+			t.Errorf("DELETE request has query %q", r.URL.RawQuery)
+		}
+	})
+
+	if _, err := client.Room.DeleteContext(WithExpand(context.Background(), "items"), "1"); err != nil {
+		t.Fatalf("Room.DeleteContext returns an error %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package hipchat
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// exportPageSize is the number of messages fetched per page when exporting,
+// the maximum the API accepts.
+const exportPageSize = 1000
+
+// ExportHistory writes the messages posted in a room between from and to to w,
+// newest first, as JSON lines: one JSON object per message and per line.
+// Pages are fetched and written one at a time, so that the history of busy
+// rooms is never held in memory, in the only order the API pages through
+// consistently. It returns the number of messages written.
+func (r *RoomService) ExportHistory(id string, from, to time.Time, w io.Writer) (int, error) {
+	return r.ExportHistoryContext(context.Background(), id, from, to, w)
+}
+
+// ExportHistoryContext is like ExportHistory, with ctx controlling the
+// requests.
+func (r *RoomService) ExportHistoryContext(ctx context.Context, id string, from, to time.Time, w io.Writer) (int, error) {
+	enc := json.NewEncoder(w)
+	return r.exportHistory(ctx, id, from, to, func(m Message) error {
+		return enc.Encode(m)
+	})
+}
+
+// ExportHistoryCSV is like ExportHistory, with the messages written as CSV
+// records, after a header naming the columns.
+func (r *RoomService) ExportHistoryCSV(id string, from, to time.Time, w io.Writer) (int, error) {
+	return r.ExportHistoryCSVContext(context.Background(), id, from, to, w)
+}
+
+// ExportHistoryCSVContext is like ExportHistoryCSV, with ctx controlling the
+// requests.
+func (r *RoomService) ExportHistoryCSVContext(ctx context.Context, id string, from, to time.Time, w io.Writer) (int, error) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "date", "type", "from_id", "from_name", "from_mention_name", "message_format", "color", "message", "file_url"})
+	n, err := r.exportHistory(ctx, id, from, to, func(m Message) error {
+		sender := m.Sender()
+		fileURL := ""
+		if m.File != nil {
+			fileURL = m.File.URL
+		}
+		fromID := ""
+		if sender.ID != 0 {
+			fromID = strconv.Itoa(sender.ID)
+		}
+		cw.Write([]string{m.ID, m.Date, m.Type, fromID, sender.Name, sender.MentionName, m.MessageFormat, m.Color, m.Message, fileURL})
+		return cw.Error()
+	})
+	cw.Flush()
+	if err == nil {
+		err = cw.Error()
+	}
+	return n, err
+}
+
+// exportHistory calls write with each message posted in the room between from
+// and to, and returns the number of messages written.
+func (r *RoomService) exportHistory(ctx context.Context, id string, from, to time.Time, write func(Message) error) (int, error) {
+	opt := HistoryBetween(from, to)
+	opt.MaxResults = exportPageSize
+	opt.Reverse = false
+
+	n := 0
+	it := r.IterateHistoryContext(ctx, id, opt)
+	for it.Next() {
+		if err := write(it.Message()); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, it.Err()
+}
@@ -0,0 +1,126 @@
+package hipchat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func handleExportHistory(t *testing.T) {
+	mux.HandleFunc("/room/1/history", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if r.URL.Query().Get("start-index") == "1" {
+			fmt.Fprintf(w, `{"items": [{"id": "1", "date": "2015-01-02T10:00:00Z", "from": "CI", "message": "ok", "type": "notification"}]}`)
+			return
+		}
+		testFormValues(t, r, values{
+			"date":        "2015-01-03T00:00:00Z",
+			"end-date":    "2015-01-02T00:00:00Z",
+			"timezone":    "UTC",
+			"reverse":     "false",
+			"max-results": "1000",
+		})
+		fmt.Fprintf(w, `{"items": [{"id": "2", "date": "2015-01-02T11:00:00Z", "from": {"id": 5, "name": "Bob", "mention_name": "bob"}, "message": "hi, \"all\"", "type": "message"}], "links": {"next": "%s/room/1/history?start-index=1"}}`, server.URL)
+	})
+}
+
+func TestRoomService_ExportHistory(t *testing.T) {
+	setup()
+	defer teardown()
+	handleExportHistory(t)
+
+	from := time.Date(2015, 1, 2, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	n, err := client.Room.ExportHistory("1", from, from.AddDate(0, 0, 1), &buf)
+	if err != nil {
+		t.Fatalf("Room.ExportHistory returns an error %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Room.ExportHistory exported %d messages, want 2", n)
+	}
+	want := `{"date":"2015-01-02T11:00:00Z","from":{"id":5,"mention_name":"bob","name":"Bob"},"id":"2","mentions":null,"message":"hi, \"all\"","message_format":"","type":"message"}
+{"date":"2015-01-02T10:00:00Z","from":"CI","id":"1","mentions":null,"message":"ok","message_format":"","type":"notification"}
+`
+	if buf.String() != want {
+		t.Errorf("Room.ExportHistory wrote %s, want %s", buf.String(), want)
+	}
+}
+
+func TestRoomService_ExportHistoryCSV(t *testing.T) {
+	setup()
+	defer teardown()
+	handleExportHistory(t)
+
+	from := time.Date(2015, 1, 2, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	if _, err := client.Room.ExportHistoryCSV("1", from, from.AddDate(0, 0, 1), &buf); err != nil {
+		t.Fatalf("Room.ExportHistoryCSV returns an error %v", err)
+	}
+	want := `id,date,type,from_id,from_name,from_mention_name,message_format,color,message,file_url
+2,2015-01-02T11:00:00Z,message,5,Bob,bob,,,"hi, ""all""",
+1,2015-01-02T10:00:00Z,notification,,CI,,,,ok,
+`
+	if buf.String() != want {
+		t.Errorf("Room.ExportHistoryCSV wrote %s, want %s", buf.String(), want)
+	}
+}
+
+func TestRoomService_ExportHistory_Pages(t *testing.T) {
+	setup()
+	defer teardown()
+
+	// 2500 messages, one per second, paged newest first by start-index like
+	// the API does with reverse=false.
+	const total = 2500
+	start := time.Date(2015, 1, 2, 0, 0, 0, 0, time.UTC)
+	mux.HandleFunc("/room/1/history", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("reverse") != "false" {
+			t.Errorf("Request reverse=%q, want false", q.Get("reverse"))
+		}
+		index, _ := strconv.Atoi(q.Get("start-index"))
+		size, _ := strconv.Atoi(q.Get("max-results"))
+		var items []string
+		for i := index; i < index+size && i < total; i++ {
+			id := total - 1 - i
+			items = append(items, fmt.Sprintf(`{"id": "%d", "date": "%s", "message": "m", "type": "message"}`, id, start.Add(time.Duration(id)*time.Second).Format(time.RFC3339)))
+		}
+		next := ""
+		if index+size < total {
+			next = fmt.Sprintf("%s/room/1/history?%s", server.URL, url.Values{
+				"start-index": {strconv.Itoa(index + size)},
+				"max-results": {strconv.Itoa(size)},
+				"reverse":     {"false"},
+			}.Encode())
+		}
+		fmt.Fprintf(w, `{"items": [%s], "links": {"next": "%s"}}`, strings.Join(items, ","), next)
+	})
+
+	var buf bytes.Buffer
+	n, err := client.Room.ExportHistory("1", start, start.Add(time.Hour), &buf)
+	if err != nil {
+		t.Fatalf("Room.ExportHistory returns an error %v", err)
+	}
+	if n != total {
+		t.Errorf("Room.ExportHistory exported %d messages, want %d", n, total)
+	}
+	dec := json.NewDecoder(&buf)
+	for want := total - 1; want >= 0; want-- {
+		var m Message
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("Message %d: %v", want, err)
+		}
+		if m.ID != strconv.Itoa(want) {
+			t.Fatalf("Room.ExportHistory wrote message %s, want %d", m.ID, want)
+		}
+	}
+	if dec.More() {
+		t.Error("Room.ExportHistory wrote more messages than exported")
+	}
+}
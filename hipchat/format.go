@@ -0,0 +1,166 @@
+package hipchat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Message formats of notifications.
+const (
+	MessageFormatText = "text"
+	MessageFormatHTML = "html"
+)
+
+// MaxMessageLength is the maximum length, in characters, of a message.
+const MaxMessageLength = 10000
+
+// ErrMessageTooLong is returned by Validate for messages longer than
+// MaxMessageLength.
+var ErrMessageTooLong = errors.New("message is longer than 10000 characters")
+
+// htmlMarkup matches the HTML tags and entities HipChat renders in messages.
+var htmlMarkup = regexp.MustCompile(`(?i)</?(a|b|i|u|s|strong|em|br|p|ul|ol|li|code|pre|img|table|tr|td|th|span|div|blockquote)\b[^>]*>|&([a-z]+|#[0-9]+|#x[0-9a-f]+);`)
+
+// DetectMessageFormat returns MessageFormatHTML if message contains HTML
+// markup HipChat renders, MessageFormatText otherwise.
+func DetectMessageFormat(message string) string {
+	if htmlMarkup.MatchString(message) {
+		return MessageFormatHTML
+	}
+	return MessageFormatText
+}
+
+// Validate sets the message format of the notification, if empty, to the one
+// detected from its message, and checks that the message is not longer than
+// MaxMessageLength.
+func (n *NotificationRequest) Validate() error {
+	if n.MessageFormat == "" {
+		n.MessageFormat = DetectMessageFormat(n.Message)
+	}
+	if utf8.RuneCountInString(n.Message) > MaxMessageLength {
+		return ErrMessageTooLong
+	}
+	return nil
+}
+
+// Split returns the notification split into notifications whose messages are
+// at most max characters long, or MaxMessageLength if max is zero. Messages are
+// cut at line breaks, else at spaces, and never inside an HTML tag. Only the
+// first part notifies the users of the room. Notifications with a card are not
+// split.
+func (n *NotificationRequest) Split(max int) []*NotificationRequest {
+	if max <= 0 {
+		max = MaxMessageLength
+	}
+	format := n.MessageFormat
+	if format == "" {
+		format = DetectMessageFormat(n.Message)
+	}
+	if n.Card != nil {
+		return []*NotificationRequest{n}
+	}
+
+	var parts []*NotificationRequest
+	for _, message := range SplitMessage(n.Message, format, max) {
+		part := *n
+		part.Message = message
+		part.MessageFormat = format
+		part.Notify = n.Notify && len(parts) == 0
+		parts = append(parts, &part)
+	}
+	return parts
+}
+
+// SplitMessage splits message into parts of at most max characters, or
+// MaxMessageLength if max is zero or negative, cut at line breaks, else at
+// spaces, and for the HTML format never inside a tag.
+func SplitMessage(message, format string, max int) []string {
+	if max <= 0 {
+		max = MaxMessageLength
+	}
+	var parts []string
+	for utf8.RuneCountInString(message) > max {
+		cut := splitPoint(message[:runeOffset(message, max)], format)
+		part := strings.TrimRight(message[:cut], " \n")
+		if part != "" {
+			parts = append(parts, part)
+		}
+		message = strings.TrimLeft(message[cut:], " \n")
+	}
+	if message != "" || len(parts) == 0 {
+		parts = append(parts, message)
+	}
+	return parts
+}
+
+// runeOffset returns the byte offset of the n-th character of s.
+func runeOffset(s string, n int) int {
+	for offset := range s {
+		if n == 0 {
+			return offset
+		}
+		n--
+	}
+	return len(s)
+}
+
+// splitPoint returns where to cut chunk, the beginning of a longer message:
+// after its last line break, else its last space, else at its end. For the
+// HTML format, <br> tags are line breaks and tags are never cut.
+func splitPoint(chunk, format string) int {
+	html := format == MessageFormatHTML
+	lineBreak, space, tagStart := -1, -1, -1
+	for n := 0; n < len(chunk); n++ {
+		switch c := chunk[n]; {
+		case html && c == '<':
+			tagStart = n
+		case html && c == '>' && tagStart >= 0:
+			if strings.HasPrefix(strings.ToLower(chunk[tagStart:]), "<br") {
+				lineBreak = n + 1
+			}
+			tagStart = -1
+		case tagStart >= 0:
+			// Inside a tag.
+		case c == '\n':
+			lineBreak = n + 1
+		case c == ' ':
+			space = n + 1
+		}
+	}
+	switch {
+	case lineBreak > 0:
+		return lineBreak
+	case space > 0:
+		return space
+	case tagStart > 0:
+		return tagStart
+	}
+	return len(chunk)
+}
+
+// NotificationSplit sends a notification to a room like Notification, split
+// into several notifications if its message is longer than MaxMessageLength.
+// It stops at the first part that fails, and returns the response to the last
+// part sent.
+func (r *RoomService) NotificationSplit(id string, notifReq *NotificationRequest) (*http.Response, error) {
+	return r.NotificationSplitContext(context.Background(), id, notifReq)
+}
+
+// NotificationSplitContext is like NotificationSplit, with ctx controlling the
+// requests.
+func (r *RoomService) NotificationSplitContext(ctx context.Context, id string, notifReq *NotificationRequest) (*http.Response, error) {
+	var (
+		resp *http.Response
+		err  error
+	)
+	for _, part := range notifReq.Split(MaxMessageLength) {
+		if resp, err = r.NotificationContext(ctx, id, part); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
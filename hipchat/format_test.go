@@ -0,0 +1,86 @@
+package hipchat
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectMessageFormat(t *testing.T) {
+	tests := map[string]string{
+		"Build passed":        MessageFormatText,
+		"1 < 2 and 3 > 2":     MessageFormatText,
+		"Build <b>passed</b>": MessageFormatHTML,
+		`See <a href="https://example.com">it</a>`: MessageFormatHTML,
+		"Fish &amp; chips":                         MessageFormatHTML,
+	}
+	for message, want := range tests {
+		if got := DetectMessageFormat(message); got != want {
+			t.Errorf("DetectMessageFormat(%q) = %q, want %q", message, got, want)
+		}
+	}
+}
+
+func TestNotificationRequestValidate(t *testing.T) {
+	n := &NotificationRequest{Message: "<b>hi</b>"}
+	if err := n.Validate(); err != nil || n.MessageFormat != MessageFormatHTML {
+		t.Errorf("Validate returned %v with format %q", err, n.MessageFormat)
+	}
+
+	n = &NotificationRequest{Message: strings.Repeat("é", MaxMessageLength+1)}
+	if err := n.Validate(); err != ErrMessageTooLong {
+		t.Errorf("Validate returned %v, want %v", err, ErrMessageTooLong)
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		message, format string
+		max             int
+		want            []string
+	}{
+		{"short", MessageFormatText, 10, []string{"short"}},
+		{"line one\nline two", MessageFormatText, 12, []string{"line one", "line two"}},
+		{"aaa bbb ccc", MessageFormatText, 8, []string{"aaa bbb", "ccc"}},
+		{"abcdefgh", MessageFormatText, 3, []string{"abc", "def", "gh"}},
+		{"one<br>two three", MessageFormatHTML, 12, []string{"one<br>", "two three"}},
+		{`ab<a href="x y">link</a>`, MessageFormatHTML, 12, []string{"ab", `<a href="x y">link</a>`[:12], `<a href="x y">link</a>`[12:]}},
+		{"ééé ééé", MessageFormatText, 4, []string{"ééé", "ééé"}},
+		{"hello world", MessageFormatText, 0, []string{"hello world"}},
+		{"hello world", MessageFormatText, -1, []string{"hello world"}},
+		{strings.Repeat("x", MaxMessageLength+1), MessageFormatText, 0, []string{strings.Repeat("x", MaxMessageLength), "x"}},
+	}
+	for _, tt := range tests {
+		if got := SplitMessage(tt.message, tt.format, tt.max); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitMessage(%q, %d) = %q, want %q", tt.message, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestRoomNotificationSplit(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var parts []*NotificationRequest
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		v := new(NotificationRequest)
+		json.NewDecoder(r.Body).Decode(v)
+		parts = append(parts, v)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	line := strings.Repeat("x", 6000)
+	_, err := client.Room.NotificationSplit("1", &NotificationRequest{Message: line + "\n" + line, Notify: true})
+	if err != nil {
+		t.Fatalf("Room.NotificationSplit returns an error %v", err)
+	}
+	want := []*NotificationRequest{
+		{Message: line, MessageFormat: MessageFormatText, Notify: true},
+		{Message: line, MessageFormat: MessageFormatText},
+	}
+	if !reflect.DeepEqual(parts, want) {
+		t.Errorf("Room.NotificationSplit sent %d parts, want 2 of 6000 characters", len(parts))
+	}
+}
@@ -0,0 +1,98 @@
+package hipchat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GroupService gives access to the group related methods of the API.
+type GroupService struct {
+	client *Client
+}
+
+// Group represents a HipChat group.
+type Group struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Links     Links      `json:"links"`
+	Subdomain string     `json:"subdomain,omitempty"`
+	Domain    string     `json:"domain,omitempty"`
+	AvatarURL string     `json:"avatar_url,omitempty"`
+	Created   string     `json:"created,omitempty"`
+	Owner     *User      `json:"owner,omitempty"`
+	Plan      *GroupPlan `json:"plan,omitempty"`
+}
+
+// GroupPlan represents the HipChat plan a group is subscribed to.
+type GroupPlan struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Get returns the profile of the group.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/view_group
+func (g *GroupService) Get(id string) (*Group, *http.Response, error) {
+	return g.GetContext(context.Background(), id)
+}
+
+// GetContext is like Get, with ctx controlling the request.
+func (g *GroupService) GetContext(ctx context.Context, id string) (*Group, *http.Response, error) {
+	req, err := g.client.NewRequest("GET", fmt.Sprintf("group/%s", id), nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	group := new(Group)
+	resp, err := g.client.DoContext(ctx, req, group)
+	if err != nil {
+		return nil, resp, err
+	}
+	return group, resp, nil
+}
+
+// groupAdminsOptions expands the users of the user list so that their
+// is_group_admin flag is returned.
+type groupAdminsOptions struct {
+	ListOptions
+	Expand string `url:"expand,omitempty"`
+}
+
+// Admins returns the administrators of the group the token belongs to. The API
+// has no dedicated endpoint, so all the pages of the user list are fetched.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/get_all_users
+func (g *GroupService) Admins() ([]User, *http.Response, error) {
+	return g.AdminsContext(context.Background())
+}
+
+// AdminsContext is like Admins, with ctx controlling the request.
+func (g *GroupService) AdminsContext(ctx context.Context) ([]User, *http.Response, error) {
+	opt := &groupAdminsOptions{ListOptions: ListOptions{MaxResults: 1000}, Expand: "items"}
+	var (
+		admins []User
+		resp   *http.Response
+	)
+	for {
+		req, err := g.client.NewRequest("GET", "user", opt, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		users := new(Users)
+		resp, err = g.client.DoContext(ctx, req, users)
+		if err != nil {
+			return nil, resp, err
+		}
+		for _, user := range users.Items {
+			if user.IsGroupAdmin {
+				admins = append(admins, user)
+			}
+		}
+		if users.Links.Next == "" || len(users.Items) == 0 {
+			return admins, resp, nil
+		}
+		opt.StartIndex += len(users.Items)
+	}
+}
@@ -0,0 +1,67 @@
+package hipchat
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestGroupGet(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/group/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprintf(w, `{
+			"id":1, "name":"n", "subdomain":"s", "created":"c",
+			"owner":{"id":2, "name":"o"},
+			"plan":{"id":"p", "name":"Premium"},
+			"links":{"self":"s"}
+		}`)
+	})
+	want := &Group{
+		ID:        1,
+		Name:      "n",
+		Subdomain: "s",
+		Created:   "c",
+		Owner:     &User{ID: 2, Name: "o"},
+		Plan:      &GroupPlan{ID: "p", Name: "Premium"},
+		Links:     Links{Self: "s"},
+	}
+
+	group, _, err := client.Group.Get("1")
+	if err != nil {
+		t.Fatalf("Group.Get returns an error %v", err)
+	}
+	if !reflect.DeepEqual(want, group) {
+		t.Errorf("Group.Get returned %+v, want %+v", group, want)
+	}
+}
+
+func TestGroupAdmins(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		switch r.FormValue("start-index") {
+		case "":
+			testFormValues(t, r, values{"expand": "items", "max-results": "1000"})
+			fmt.Fprintf(w, `{"items":[{"id":1, "is_group_admin":true}, {"id":2}], "links":{"next":"n"}}`)
+		case "2":
+			fmt.Fprintf(w, `{"items":[{"id":3, "is_group_admin":true}], "links":{}}`)
+		default:
+			t.Errorf("Unexpected start-index %q", r.FormValue("start-index"))
+		}
+	})
+	want := []User{{ID: 1, IsGroupAdmin: true}, {ID: 3, IsGroupAdmin: true}}
+
+	admins, _, err := client.Group.Admins()
+	if err != nil {
+		t.Fatalf("Group.Admins returns an error %v", err)
+	}
+	if !reflect.DeepEqual(want, admins) {
+		t.Errorf("Group.Admins returned %+v, want %+v", admins, want)
+	}
+}
@@ -0,0 +1,124 @@
+package hipchat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ReadinessTimeout bounds how long the readiness endpoint waits for the Store
+// and the HipChat API to answer, unless changed with WithReadinessTimeout.
+const ReadinessTimeout = 2 * time.Second
+
+// Pinger is implemented by Stores that can check their connectivity. The
+// readiness endpoint of an Integration reports not ready while Ping fails, or
+// once the readiness timeout passes; Ping is then left to return in the
+// background.
+type Pinger interface {
+	Ping() error
+}
+
+// ContextPinger is a Pinger whose checks can be cancelled. It is used by the
+// readiness endpoint when the Store implements it, so that the checks of an
+// unreachable Store do not pile up beyond the readiness timeout.
+type ContextPinger interface {
+	Pinger
+	PingContext(ctx context.Context) error
+}
+
+// WithAPIReadinessCheck makes the readiness endpoint also check that the HipChat
+// API can be reached.
+func WithAPIReadinessCheck() IntegrationOption {
+	return func(i *Integration) {
+		i.checkAPI = true
+	}
+}
+
+// WithReadinessTimeout sets how long the readiness endpoint waits for the
+// Store and the HipChat API to answer, instead of ReadinessTimeout.
+func WithReadinessTimeout(timeout time.Duration) IntegrationOption {
+	return func(i *Integration) {
+		i.readinessTimeout = timeout
+	}
+}
+
+// HandleHealthz reports that the integration is alive.
+func (i *Integration) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "OK")
+}
+
+// HandleReadyz reports whether the integration is ready to serve requests: it is
+// not shutting down, its Store is reachable and, with WithAPIReadinessCheck, so
+// is the HipChat API. The checks give up after the readiness timeout.
+func (i *Integration) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	i.mu.Lock()
+	closing := i.closing
+	i.mu.Unlock()
+	if closing {
+		writeError(w, http.StatusServiceUnavailable, ErrorCodeShuttingDown, "The integration is shutting down.")
+		return
+	}
+
+	timeout := i.readinessTimeout
+	if timeout <= 0 {
+		timeout = ReadinessTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	if pinger, ok := i.Store.(Pinger); ok {
+		if err := ping(ctx, pinger); err != nil {
+			i.logger.Printf("Store is not reachable: %v", err)
+			writeError(w, http.StatusServiceUnavailable, ErrorCodeStoreUnavailable, "The store is not reachable.")
+			return
+		}
+	}
+
+	if i.checkAPI {
+		resp, err := i.probeAPI(ctx)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 500 {
+				err = fmt.Errorf("Server returns status %d", resp.StatusCode)
+			}
+		}
+		if err != nil {
+			i.logger.Printf("HipChat API is not reachable: %v", err)
+			writeError(w, http.StatusServiceUnavailable, ErrorCodeAPIUnavailable, "The HipChat API is not reachable.")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "OK")
+}
+
+// ping checks the connectivity of a Store, with ctx if it supports it. Plain
+// Pingers are run in a goroutine, abandoned once ctx is done.
+func ping(ctx context.Context, pinger Pinger) error {
+	if pinger, ok := pinger.(ContextPinger); ok {
+		return pinger.PingContext(ctx)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- pinger.Ping()
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// probeAPI requests the capabilities of the HipChat API.
+func (i *Integration) probeAPI(ctx context.Context) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(i.apiBaseURL, "/")+"/capabilities", nil)
+	if err != nil {
+		return nil, err
+	}
+	return i.httpClient.Do(req)
+}
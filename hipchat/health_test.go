@@ -0,0 +1,112 @@
+package hipchat
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pingStore is a memoryStore implementing Pinger.
+type pingStore struct {
+	*memoryStore
+	err error
+}
+
+func (s pingStore) Ping() error {
+	return s.err
+}
+
+// contextPingStore is a memoryStore implementing ContextPinger, whose checks
+// last until ctx is done.
+type contextPingStore struct {
+	*memoryStore
+}
+
+func (s contextPingStore) Ping() error {
+	return errors.New("Ping called instead of PingContext")
+}
+
+func (s contextPingStore) PingContext(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// blockingPingStore is a memoryStore implementing Pinger, whose checks last
+// until unblock is closed.
+type blockingPingStore struct {
+	*memoryStore
+	unblock chan struct{}
+}
+
+func (s blockingPingStore) Ping() error {
+	<-s.unblock
+	return nil
+}
+
+func TestHandleReadyz(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow/capabilities" {
+			<-r.Context().Done()
+		}
+	}))
+	defer api.Close()
+
+	tests := []struct {
+		name       string
+		store      Store
+		opts       []IntegrationOption
+		shutdown   bool
+		wantStatus int
+		wantCode   string
+	}{
+		{"ready", newMemoryStore(), nil, false, http.StatusOK, ""},
+		{"store reachable", pingStore{newMemoryStore(), nil}, nil, false, http.StatusOK, ""},
+		{"store unreachable", pingStore{newMemoryStore(), errors.New("down")}, nil, false, http.StatusServiceUnavailable, ErrorCodeStoreUnavailable},
+		{"store not answering", contextPingStore{newMemoryStore()}, nil, false, http.StatusServiceUnavailable, ErrorCodeStoreUnavailable},
+		{"store blocking", blockingPingStore{newMemoryStore(), unblock}, nil, false, http.StatusServiceUnavailable, ErrorCodeStoreUnavailable},
+		{"API reachable", newMemoryStore(), []IntegrationOption{WithAPIReadinessCheck(), WithAPIBaseURL(api.URL + "/v2/")}, false, http.StatusOK, ""},
+		{"API not answering", newMemoryStore(), []IntegrationOption{WithAPIReadinessCheck(), WithAPIBaseURL(api.URL + "/slow/")}, false, http.StatusServiceUnavailable, ErrorCodeAPIUnavailable},
+		{"shutting down", newMemoryStore(), nil, true, http.StatusServiceUnavailable, ErrorCodeShuttingDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := NewIntegration(tt.store, append([]IntegrationOption{WithLogger(log.New(ioutil.Discard, "", 0))}, tt.opts...)...)
+			if tt.shutdown {
+				i.Shutdown(context.Background())
+			}
+			// The checks give up with the request, before ReadinessTimeout.
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			r := httptest.NewRequest("GET", "/readyz", nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			routesHandler(i).ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantCode) {
+				t.Errorf("GET /readyz answered %d %s, want %d %s", w.Code, w.Body, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestHandleReadyz_Timeout(t *testing.T) {
+	i := NewIntegration(contextPingStore{newMemoryStore()}, WithLogger(log.New(ioutil.Discard, "", 0)), WithReadinessTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	routesHandler(i).ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz answered %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if elapsed := time.Since(start); elapsed >= ReadinessTimeout {
+		t.Errorf("GET /readyz answered after %v, want the readiness timeout", elapsed)
+	}
+}
@@ -3,6 +3,7 @@ package hipchat
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -18,6 +19,8 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/go-querystring/query"
 )
@@ -31,12 +34,32 @@ type Client struct {
 	authToken string
 	BaseURL   *url.URL
 	client    *http.Client
+	signer    *JWTSigner
+	retry     *RetryPolicy
+	limiter   *RateLimiter
+	cache     ResponseCache
+	rateMu    sync.Mutex
+	rate      Rate
+	// session caches the session of authToken for RequireScopes.
+	sessionMu sync.Mutex
+	session   *OAuthSession
+	sessionAt time.Time
+	// requestHooks and responseHooks are called around each request.
+	requestHooks  []RequestHook
+	responseHooks []ResponseHook
+	instrument    InstrumentFunc
+	timeout       time.Duration
+	idempotency   *IdempotencyCache
 	// Room gives access to the /room part of the API.
 	Room *RoomService
 	// User gives access to the /user part of the API.
 	User *UserService
 	// Emoticon gives access to the /emoticon part of the API.
 	Emoticon *EmoticonService
+	// Group gives access to the /group part of the API.
+	Group *GroupService
+	// AddOn gives access to the /addon part of the API.
+	AddOn *AddOnService
 }
 
 // Links represents the HipChat default links.
@@ -91,6 +114,17 @@ func NewClient(authToken string) *Client {
 	c.Room = &RoomService{client: c}
 	c.User = &UserService{client: c}
 	c.Emoticon = &EmoticonService{client: c}
+	c.Group = &GroupService{client: c}
+	c.AddOn = &AddOnService{client: c}
+	return c
+}
+
+// NewClientWithHTTPClient is like NewClient, with the API requests performed by
+// httpClient, e.g. one with a timeout, a proxy or an instrumented Transport. If
+// a nil httpClient is provided, http.DefaultClient will be used.
+func NewClientWithHTTPClient(authToken string, httpClient *http.Client) *Client {
+	c := NewClient(authToken)
+	c.SetHTTPClient(httpClient)
 	return c
 }
 
@@ -104,10 +138,63 @@ func (c *Client) SetHTTPClient(httpClient *http.Client) {
 	}
 }
 
-// NewRequest creates an API request. This method can be used to performs
-// API request not implemented in this library. Otherwise it should not be
-// be used directly.
-// Relative URLs should always be specified without a preceding slash.
+// SetBaseURL points the client at the API of a HipChat Server, e.g.
+// "https://hipchat.example.com:8443/v2/". A path prefix, if any, must be
+// included up to the API version.
+func (c *Client) SetBaseURL(baseURL string) error {
+	u, err := parseBaseURL(baseURL)
+	if err != nil {
+		return err
+	}
+	c.BaseURL = u
+	return nil
+}
+
+// parseBaseURL parses the base URL of an API, adding the trailing slash
+// relative URLs are resolved against.
+func parseBaseURL(baseURL string) (*url.URL, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("Base URL %q is not absolute", baseURL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u, nil
+}
+
+// SetJWTSigner makes the client authenticate its requests with JWTs created
+// by signer instead of the auth token. A nil signer restores the auth token.
+func (c *Client) SetJWTSigner(signer *JWTSigner) {
+	c.signer = signer
+}
+
+// authorize sets the Authorization header of req.
+func (c *Client) authorize(req *http.Request) error {
+	if c.signer != nil {
+		return c.signer.SignRequest(req)
+	}
+	req.Header.Add("Authorization", "Bearer "+c.authToken)
+	return nil
+}
+
+// NewRequest creates an API request, authenticated like the requests of the
+// API methods. Along with Do, it can be used to call the endpoints that are
+// not wrapped by this library, e.g.
+//
+//	req, err := client.NewRequest("GET", "room/42/statistics", nil, nil)
+//	if err != nil {
+//		return err
+//	}
+//	var stats hipchat.RoomStatistics
+//	_, err = client.Do(req, &stats)
+//
+// urlStr is resolved against BaseURL; relative URLs should always be specified
+// without a preceding slash. opt, if not nil, is a struct whose fields with
+// "url" tags are added to the query, and body, if not nil, is sent as JSON.
 func (c *Client) NewRequest(method, urlStr string, opt interface{}, body interface{}) (*http.Request, error) {
 	rel, err := addOptions(urlStr, opt)
 	if err != nil {
@@ -136,8 +223,11 @@ func (c *Client) NewRequest(method, urlStr string, opt interface{}, body interfa
 		return nil, err
 	}
 
-	req.Header.Add("Authorization", "Bearer "+c.authToken)
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
 	req.Header.Add("Content-Type", "application/json")
+	req.Header.Set("Accept-Encoding", "gzip")
 	return req, nil
 }
 
@@ -178,6 +268,9 @@ func (c *Client) NewFileUploadRequest(method, urlStr string, v interface{}) (*ht
 	}
 	b64 := base64.StdEncoding.EncodeToString(file)
 	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
 
 	// Set proper filename
 	filename := shareFileReq.Filename
@@ -187,17 +280,24 @@ func (c *Client) NewFileUploadRequest(method, urlStr string, v interface{}) (*ht
 		filename = filepath.Base(filename) + filepath.Ext(path)
 	}
 
+	metadata, err := json.Marshal(struct {
+		Message string `json:"message,omitempty"`
+	}{message})
+	if err != nil {
+		return nil, err
+	}
+
 	// Build request body
 	body := "--hipfileboundary\n" +
 		"Content-Type: application/json; charset=UTF-8\n" +
 		"Content-Disposition: attachment; name=\"metadata\"\n\n" +
-		"{\"message\": \"" + message + "\"}\n" +
+		string(metadata) + "\n" +
 		"--hipfileboundary\n" +
-		"Content-Type: " + contentType + " charset=UTF-8\n" +
+		"Content-Type: " + contentType + "\n" +
 		"Content-Transfer-Encoding: base64\n" +
-		"Content-Disposition: attachment; name=file; filename=" + filename + "\n\n" +
+		"Content-Disposition: attachment; name=\"file\"; filename=" + strconv.Quote(filename) + "\n\n" +
 		b64 + "\n" +
-		"--hipfileboundary\n"
+		"--hipfileboundary--\n"
 
 	b := &bytes.Buffer{}
 	b.Write([]byte(body))
@@ -207,29 +307,59 @@ func (c *Client) NewFileUploadRequest(method, urlStr string, v interface{}) (*ht
 		return nil, err
 	}
 
-	req.Header.Add("Authorization", "Bearer "+c.authToken)
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
 	req.Header.Add("Content-Type", "multipart/related; boundary=hipfileboundary")
 
 	return req, err
 }
 
 // Do performs the request, the json received in the response is decoded
-// and stored in the value pointed by v.
-// Do can be used to perform the request created with NewRequest, as the latter
-// it should be used only for API requests not implemented in this library.
+// and stored in the value pointed by v, or copied to v if it is an io.Writer.
+// Error statuses are returned as an *ErrorResponse, and the rate limit
+// reported by the response is available from ParseRate and Client.Rate.
+//
+// Do can be used to perform the requests created with NewRequest for the
+// endpoints not wrapped by this library. The retry policy, rate limiter,
+// response cache, hooks and timeout of the client apply as for the API
+// methods.
 func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
-	resp, err := c.client.Do(req)
+	req, cancel := c.applyTimeout(req)
+	resp, err := c.doOnce(req, v)
+	if err == nil && v == nil && resp != nil {
+		// The body is left for the caller to read.
+		resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	} else {
+		cancel()
+	}
+	return resp, err
+}
+
+// do performs req for Do, once its timeout is applied.
+func (c *Client) do(req *http.Request, v interface{}) (*http.Response, error) {
+	c.addETag(req)
+	start := time.Now()
+	resp, err := c.send(req)
+	c.instrumentCall(req, resp, start)
 	if err != nil {
 		return nil, err
 	}
+	c.updateRate(resp)
+	if err := decompress(resp); err != nil {
+		return resp, err
+	}
+	if err := c.useCache(req, resp); err != nil {
+		return resp, err
+	}
 
 	if AuthTest {
 		// If AuthTest is enabled, the reponse won't be the
 		// one defined in the API endpoint.
 		err = json.NewDecoder(resp.Body).Decode(&AuthTestResponse)
 	} else {
-		if c := resp.StatusCode; c < 200 || c > 299 {
-			return resp, fmt.Errorf("Server returns status %d", c)
+		if err := checkResponse(resp); err != nil {
+			return resp, err
 		}
 
 		if v != nil {
@@ -244,6 +374,14 @@ func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
 	return resp, err
 }
 
+// DoContext is like Do, with ctx controlling the request: the request is
+// canceled when ctx is done. Every API method has a ...Context variant built on
+// it, e.g. RoomService.NotificationContext.
+func (c *Client) DoContext(ctx context.Context, req *http.Request, v interface{}) (*http.Response, error) {
+	addExpand(ctx, req)
+	return c.Do(req.WithContext(ctx), v)
+}
+
 // addOptions adds the parameters in opt as URL query parameters to s.  opt
 // must be a struct whose fields may contain "url" tags.
 func addOptions(s string, opt interface{}) (*url.URL, error) {
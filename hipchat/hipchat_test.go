@@ -1,13 +1,18 @@
 package hipchat
 
 import (
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -89,6 +94,31 @@ func TestSetHTTPClient(t *testing.T) {
 	}
 }
 
+func TestSetBaseURL(t *testing.T) {
+	c := NewClient("AuthToken")
+
+	if err := c.SetBaseURL("https://hipchat.example.com:8443/chat/v2"); err != nil {
+		t.Fatalf("SetBaseURL returned an error %v", err)
+	}
+	req, _ := c.NewRequest("GET", "room", nil, nil)
+	if want := "https://hipchat.example.com:8443/chat/v2/room"; req.URL.String() != want {
+		t.Errorf("NewRequest URL is %v, want %v", req.URL, want)
+	}
+
+	if err := c.SetBaseURL("/v2/"); err == nil {
+		t.Error("SetBaseURL accepted a relative URL")
+	}
+}
+
+func TestNewClientWithHTTPClient(t *testing.T) {
+	httpClient := new(http.Client)
+	c := NewClientWithHTTPClient("AuthToken", httpClient)
+
+	if c.client != httpClient {
+		t.Errorf("NewClientWithHTTPClient client %p, want %p", c.client, httpClient)
+	}
+}
+
 func TestSetHTTPClient_NilHTTPClient(t *testing.T) {
 	c := NewClient("AuthToken")
 
@@ -137,6 +167,30 @@ func TestNewRequest_AuthTestEnabled(t *testing.T) {
 	}
 }
 
+func TestNewRequest_JWTSigner(t *testing.T) {
+	c := NewClient("AuthToken")
+	c.SetJWTSigner(&JWTSigner{Issuer: "oauth", Secret: "secret"})
+
+	r, _ := c.NewRequest("GET", "foo", nil, nil)
+
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "JWT ") {
+		t.Fatalf("NewRequest authorization header %s, want a JWT", authorization)
+	}
+	claims, err := codec.Parse(authorization[len("JWT "):], func(map[string]interface{}) ([][]byte, error) {
+		return [][]byte{[]byte("secret")}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewRequest signed an invalid JWT: %v", err)
+	}
+	if want := QueryStringHash("GET", r.URL, ""); claims["qsh"] != want {
+		t.Errorf("NewRequest JWT qsh %v, want %v", claims["qsh"], want)
+	}
+	if claims["iss"] != "oauth" {
+		t.Errorf("NewRequest JWT iss %v, want oauth", claims["iss"])
+	}
+}
+
 func TestDo(t *testing.T) {
 	setup()
 	defer teardown()
@@ -164,6 +218,93 @@ func TestDo(t *testing.T) {
 	}
 }
 
+func TestDoContext_Canceled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Canceled request reached the server")
+	})
+	req, _ := client.NewRequest("GET", "/", nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.DoContext(ctx, req, nil)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("DoContext returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestRoomNotificationContext(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if r.Context().Err() != nil {
+			t.Errorf("Request context is done: %v", r.Context().Err())
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	_, err := client.Room.NotificationContext(ctx, "1", &NotificationRequest{Message: "m"})
+	if err != nil {
+		t.Fatalf("Room.NotificationContext returns an error %v", err)
+	}
+}
+
+func TestDo_Gzip(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept-Encoding"); got != "gzip" {
+			t.Errorf("Accept-Encoding = %q, want gzip", got)
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		fmt.Fprintf(zw, `{"Bar":1}`)
+		zw.Close()
+	})
+	req, _ := client.NewRequest("GET", "/", nil, nil)
+	body := new(struct{ Bar int })
+
+	_, err := client.Do(req, body)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body.Bar != 1 {
+		t.Errorf("Response body = %+v, want Bar 1", body)
+	}
+}
+
+func TestDo_UnwrappedEndpoint(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/statistics", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer AuthToken" {
+			t.Errorf("Authorization = %q, want Bearer AuthToken", got)
+		}
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, `{"error":{"code":403,"message":"Missing scope","type":"Forbidden"}}`)
+	})
+	req, err := client.NewRequest("GET", "room/1/statistics", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Do(req, new(RoomStatistics))
+
+	if !errors.Is(err, ErrForbidden) || !strings.Contains(err.Error(), "Missing scope") {
+		t.Errorf("Do returned %v, want the decoded 403 error", err)
+	}
+}
+
 func TestDo_AuthTestEnabled(t *testing.T) {
 	AuthTest = true
 	defer func() { AuthTest = false }()
@@ -0,0 +1,74 @@
+package hipchattest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tbruyelle/hipchat-go/hipchat"
+)
+
+func TestSignRequest(t *testing.T) {
+	tok := NewToken("oauth", "secret")
+	tok.RoomID = 12
+	i := hipchat.NewIntegration(nil, hipchat.WithKeyResolver(KeyResolver(tok)))
+
+	r := httptest.NewRequest("GET", "/glance?lang=en", nil)
+	if err := tok.SignRequest(r); err != nil {
+		t.Fatalf("SignRequest returned an error %v", err)
+	}
+	params, err := i.ParseSignedParams(r)
+	if err != nil {
+		t.Fatalf("ParseSignedParams returned an error %v", err)
+	}
+	if params.Issuer != "oauth" || params.RoomID != 12 || params.UserTimezone != "UTC" {
+		t.Errorf("ParseSignedParams returned %v", params)
+	}
+}
+
+func TestSignRequest_Expired(t *testing.T) {
+	tok := NewToken("oauth", "secret")
+	tok.ExpiresAt = time.Now().Add(-time.Minute)
+	i := hipchat.NewIntegration(nil, hipchat.WithKeyResolver(KeyResolver(tok)))
+
+	r := httptest.NewRequest("GET", "/glance", nil)
+	tok.SignRequest(r)
+	if _, err := i.ParseSignedParams(r); !errors.Is(err, hipchat.ErrTokenExpired) {
+		t.Errorf("ParseSignedParams returned %v, want %v", err, hipchat.ErrTokenExpired)
+	}
+}
+
+func TestSignRequest_Action(t *testing.T) {
+	tok := NewToken("oauth", "secret")
+	i := hipchat.NewIntegration(nil, hipchat.WithKeyResolver(KeyResolver(tok)))
+	var got *hipchat.ActionPayload
+	i.AddAction("/ticket", hipchat.ActionModule{
+		Key:      "ticket",
+		Name:     hipchat.Name{Value: "Create ticket"},
+		Target:   "ticket-dialog",
+		Location: hipchat.ActionLocationMessage,
+	}, func(w http.ResponseWriter, r *http.Request, params *hipchat.SignedParams, payload *hipchat.ActionPayload) {
+		if params.Issuer != "oauth" {
+			t.Errorf("Action called for %v, want oauth", params.Issuer)
+		}
+		got = payload
+	})
+
+	r := httptest.NewRequest("POST", "/ticket", strings.NewReader(`{"message": {"id": "m1", "body": "the build is broken"}}`))
+	r.Header.Set("Content-Type", "application/json")
+	if err := tok.SignRequest(r); err != nil {
+		t.Fatalf("SignRequest returned an error %v", err)
+	}
+	w := httptest.NewRecorder()
+	i.GetHandler().ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Action request answered %d: %s", w.Code, w.Body)
+	}
+	if got == nil || got.Message == nil || got.Message.ID != "m1" || got.Message.Body != "the build is broken" {
+		t.Errorf("Action called with %+v", got)
+	}
+}
@@ -0,0 +1,74 @@
+package hipchat
+
+import "time"
+
+// HistoryDateRecent is the HistoryOptions.Date fetching the latest 75
+// messages of a room.
+const HistoryDateRecent = "recent"
+
+// historyDateLayout is the ISO-8601 layout of the dates of the history API.
+const historyDateLayout = "2006-01-02T15:04:05.999999Z07:00"
+
+// FormatHistoryDate formats t for HistoryOptions.Date and EndDate.
+func FormatHistoryDate(t time.Time) string {
+	return t.Format(historyDateLayout)
+}
+
+// historyTimezone returns the timezone HipChat is given for dates in loc, UTC
+// when loc has no IANA name, e.g. time.Local.
+func historyTimezone(loc *time.Location) string {
+	if loc == nil || loc == time.Local || loc.String() == "" {
+		return "UTC"
+	}
+	return loc.String()
+}
+
+// historyTime returns t in a location HipChat knows the name of.
+func historyTime(t time.Time) time.Time {
+	if historyTimezone(t.Location()) == "UTC" {
+		return t.UTC()
+	}
+	return t
+}
+
+// SetDate sets the latest date to fetch history for, along with the Timezone
+// of t, which the dates of the returned messages are expressed in.
+func (o *HistoryOptions) SetDate(t time.Time) {
+	t = historyTime(t)
+	o.Date = FormatHistoryDate(t)
+	o.Timezone = historyTimezone(t.Location())
+}
+
+// SetEndDate sets the earliest date to fetch history for.
+func (o *HistoryOptions) SetEndDate(t time.Time) {
+	o.EndDate = FormatHistoryDate(historyTime(t))
+}
+
+// SetTimezone sets the timezone the dates of the returned messages are
+// expressed in.
+func (o *HistoryOptions) SetTimezone(loc *time.Location) {
+	o.Timezone = historyTimezone(loc)
+}
+
+// HistorySince returns the options fetching the messages posted since t, e.g.
+// for RoomService.History or IterateHistory. Messages are returned oldest
+// first.
+func HistorySince(t time.Time) *HistoryOptions {
+	return HistoryBetween(t, time.Now().In(t.Location()))
+}
+
+// HistoryBetween returns the options fetching the messages posted between
+// from and to. Messages are returned oldest first, in the timezone of to; to
+// page through them consistently, set Reverse to false, which returns them
+// newest first.
+func HistoryBetween(from, to time.Time) *HistoryOptions {
+	opt := &HistoryOptions{Reverse: true}
+	opt.SetDate(to)
+	opt.SetEndDate(from)
+	return opt
+}
+
+// Time returns the date the message was posted at.
+func (m Message) Time() (time.Time, error) {
+	return time.Parse(historyDateLayout, m.Date)
+}
@@ -0,0 +1,59 @@
+package hipchat
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHistoryBetween(t *testing.T) {
+	setup()
+	defer teardown()
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	from := time.Date(2015, 1, 2, 10, 0, 0, 0, time.UTC)
+	to := time.Date(2015, 1, 3, 10, 30, 0, 0, paris)
+
+	mux.HandleFunc("/room/1/history", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{
+			"date":     "2015-01-03T10:30:00+01:00",
+			"end-date": "2015-01-02T10:00:00Z",
+			"timezone": "Europe/Paris",
+			"reverse":  "true",
+		})
+		fmt.Fprintf(w, `{"items": []}`)
+	})
+
+	if _, _, err := client.Room.History("1", HistoryBetween(from, to)); err != nil {
+		t.Fatalf("Room.History returns an error %v", err)
+	}
+}
+
+func TestHistoryOptionsSetDate_Local(t *testing.T) {
+	opt := new(HistoryOptions)
+	opt.SetDate(time.Date(2015, 1, 2, 10, 0, 0, 0, time.UTC).In(time.Local))
+
+	if want := "2015-01-02T10:00:00Z"; opt.Date != want {
+		t.Errorf("SetDate set Date %v, want %v", opt.Date, want)
+	}
+	if opt.Timezone != "UTC" {
+		t.Errorf("SetDate set Timezone %v, want UTC", opt.Timezone)
+	}
+}
+
+func TestMessageTime(t *testing.T) {
+	m := Message{Date: "2014-11-23T21:23:49.807578+00:00"}
+
+	got, err := m.Time()
+	if err != nil {
+		t.Fatalf("Message.Time returns an error %v", err)
+	}
+	if want := time.Date(2014, 11, 23, 21, 23, 49, 807578000, time.UTC); !got.Equal(want) {
+		t.Errorf("Message.Time returned %v, want %v", got, want)
+	}
+}
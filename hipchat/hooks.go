@@ -0,0 +1,71 @@
+package hipchat
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RequestHook is called with every HTTP request sent by a Client, retries
+// included, right before it is sent. It can mutate the request, e.g. to add
+// headers or sign it. An error aborts the request and is returned by the API
+// method.
+type RequestHook func(req *http.Request) error
+
+// ResponseHook is called with the outcome of every HTTP request sent by a
+// Client, e.g. to log it or record metrics. resp is nil when err is not.
+type ResponseHook func(req *http.Request, resp *http.Response, err error)
+
+// InstrumentFunc is called once per API call made by a Client, retries
+// included, e.g. to record metrics. endpoint is the path of the request
+// relative to the base URL, e.g. "room/1/notification", method its HTTP
+// method, status the HTTP status of the final response, or 0 if there was
+// none, and duration the time spent sending the request and waiting for the
+// response.
+type InstrumentFunc func(endpoint, method string, status int, duration time.Duration)
+
+// SetInstrumentFunc sets the function called after each API call, or removes
+// it if f is nil.
+func (c *Client) SetInstrumentFunc(f InstrumentFunc) {
+	c.instrument = f
+}
+
+// AddRequestHook adds a hook called before each request, after the hooks
+// added before it.
+func (c *Client) AddRequestHook(hook RequestHook) {
+	c.requestHooks = append(c.requestHooks, hook)
+}
+
+// AddResponseHook adds a hook called after each request, after the hooks
+// added before it.
+func (c *Client) AddResponseHook(hook ResponseHook) {
+	c.responseHooks = append(c.responseHooks, hook)
+}
+
+// roundTrip sends req through the hooks of the client.
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	for _, hook := range c.requestHooks {
+		if err := hook(req); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := c.client.Do(req)
+	for _, hook := range c.responseHooks {
+		hook(req, resp, err)
+	}
+	return resp, err
+}
+
+// instrumentCall calls the InstrumentFunc of the client, if any, for the API call of
+// req that started at start.
+func (c *Client) instrumentCall(req *http.Request, resp *http.Response, start time.Time) {
+	if c.instrument == nil {
+		return
+	}
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	endpoint := strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, c.BaseURL.Path), "/")
+	c.instrument(endpoint, req.Method, status, time.Since(start))
+}
@@ -0,0 +1,82 @@
+package hipchat
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Trace"); got != "t" {
+			t.Errorf("X-Trace = %q, want t", got)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	var calls []string
+	client.AddRequestHook(func(req *http.Request) error {
+		calls = append(calls, "request")
+		req.Header.Set("X-Trace", "t")
+		return nil
+	})
+	client.AddResponseHook(func(req *http.Request, resp *http.Response, err error) {
+		calls = append(calls, "response")
+		if err != nil || resp.StatusCode != http.StatusNoContent {
+			t.Errorf("Response hook called with %v, %v", resp, err)
+		}
+	})
+
+	if _, err := client.Room.Notification("1", &NotificationRequest{Message: "m"}); err != nil {
+		t.Fatalf("Room.Notification returns an error %v", err)
+	}
+	if len(calls) != 2 || calls[0] != "request" || calls[1] != "response" {
+		t.Errorf("Hooks called %v, want request then response", calls)
+	}
+}
+
+func TestRequestHook_Error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Aborted request reached the server")
+	})
+	client.AddRequestHook(func(req *http.Request) error {
+		return errTest
+	})
+
+	_, err := client.Room.Notification("1", &NotificationRequest{Message: "m"})
+	if !errors.Is(err, errTest) {
+		t.Errorf("Room.Notification returned %v, want %v", err, errTest)
+	}
+}
+
+func TestInstrumentFunc(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	calls := 0
+	client.SetInstrumentFunc(func(endpoint, method string, status int, duration time.Duration) {
+		calls++
+		if endpoint != "room/1/notification" || method != "POST" || status != http.StatusNoContent {
+			t.Errorf("InstrumentFunc called with %v, %v, %v, want room/1/notification, POST, 204", endpoint, method, status)
+		}
+		if duration <= 0 {
+			t.Errorf("InstrumentFunc called with duration %v", duration)
+		}
+	})
+
+	if _, err := client.Room.Notification("1", &NotificationRequest{Message: "m"}); err != nil {
+		t.Fatalf("Room.Notification returns an error %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("InstrumentFunc called %d times, want 1", calls)
+	}
+}
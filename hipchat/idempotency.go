@@ -0,0 +1,115 @@
+package hipchat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultIdempotencyTTL is how long an IdempotencyCache remembers keys by
+// default.
+const DefaultIdempotencyTTL = 10 * time.Minute
+
+// ErrAlreadySent is returned by the API methods called with an idempotency key
+// the client already sent a request with, instead of sending it again.
+var ErrAlreadySent = errors.New("hipchat: request already sent with this idempotency key")
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a context that makes the ...Context API methods
+// send their request at most once for key, among the calls made with the same
+// key by a client having an IdempotencyCache. Retrying a notification whose
+// send timed out with the same key does not post it twice:
+//
+//	ctx := hipchat.WithIdempotencyKey(context.Background(), key)
+//	_, err := client.Room.NotificationContext(ctx, "1", notifReq)
+//	if errors.Is(err, hipchat.ErrAlreadySent) {
+//		// A previous attempt went through, or may have.
+//	}
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// NewIdempotencyKey returns a random idempotency key.
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// IdempotencyCache remembers the idempotency keys of the requests sent by a
+// client. HipChat has no notion of idempotency, so a request whose outcome is
+// unknown, e.g. one that timed out, is considered sent: sends are at most
+// once. Only the keys of requests HipChat answered with an error status are
+// forgotten, so that they can be retried. It is safe for concurrent use.
+type IdempotencyCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]time.Time
+}
+
+// NewIdempotencyCache returns a cache remembering keys for ttl, or
+// DefaultIdempotencyTTL if ttl is not positive.
+func NewIdempotencyCache(ttl time.Duration) *IdempotencyCache {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &IdempotencyCache{ttl: ttl, now: time.Now, keys: make(map[string]time.Time)}
+}
+
+// SetIdempotencyCache makes the client honor the keys set by
+// WithIdempotencyKey, remembering them in cache. A nil cache disables them,
+// which is the default.
+func (c *Client) SetIdempotencyCache(cache *IdempotencyCache) {
+	c.idempotency = cache
+}
+
+// claim records key, and reports whether it was not already.
+func (ic *IdempotencyCache) claim(key string) bool {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	now := ic.now()
+	for k, expires := range ic.keys {
+		if !now.Before(expires) {
+			delete(ic.keys, k)
+		}
+	}
+	if _, ok := ic.keys[key]; ok {
+		return false
+	}
+	ic.keys[key] = now.Add(ic.ttl)
+	return true
+}
+
+// release forgets key.
+func (ic *IdempotencyCache) release(key string) {
+	ic.mu.Lock()
+	delete(ic.keys, key)
+	ic.mu.Unlock()
+}
+
+// doOnce performs req, unless its idempotency key was already used.
+func (c *Client) doOnce(req *http.Request, v interface{}) (*http.Response, error) {
+	key, ok := req.Context().Value(idempotencyKey{}).(string)
+	if !ok || c.idempotency == nil {
+		return c.do(req, v)
+	}
+	if !c.idempotency.claim(key) {
+		return nil, ErrAlreadySent
+	}
+	resp, err := c.do(req, v)
+	var errResp *ErrorResponse
+	if errors.As(err, &errResp) {
+		c.idempotency.release(key)
+	}
+	return resp, err
+}
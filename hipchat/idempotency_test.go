@@ -0,0 +1,56 @@
+package hipchat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var calls int32
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	client.SetIdempotencyCache(NewIdempotencyCache(time.Minute))
+	ctx := WithIdempotencyKey(context.Background(), NewIdempotencyKey())
+	notifReq := &NotificationRequest{Message: "m"}
+
+	if _, err := client.Room.NotificationContext(ctx, "1", notifReq); !errors.Is(err, ErrServerError) {
+		t.Fatalf("Room.NotificationContext returned %v, want %v", err, ErrServerError)
+	}
+	timeoutCtx := WithTimeout(ctx, time.Millisecond)
+	if _, err := client.Room.NotificationContext(timeoutCtx, "1", notifReq); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Room.NotificationContext returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, err := client.Room.NotificationContext(ctx, "1", notifReq); !errors.Is(err, ErrAlreadySent) {
+		t.Errorf("Room.NotificationContext returned %v, want %v", err, ErrAlreadySent)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("Notification sent %d times, want 2", n)
+	}
+}
+
+func TestIdempotencyCache_TTL(t *testing.T) {
+	cache := NewIdempotencyCache(time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	if !cache.claim("k") || cache.claim("k") {
+		t.Fatal("IdempotencyCache claimed a key twice")
+	}
+	now = now.Add(time.Minute)
+	if !cache.claim("k") {
+		t.Error("IdempotencyCache did not forget an expired key")
+	}
+}
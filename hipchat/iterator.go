@@ -0,0 +1,217 @@
+package hipchat
+
+import (
+	"context"
+	"fmt"
+)
+
+// pager fetches the successive pages of a paginated endpoint, following their
+// links.next.
+type pager struct {
+	client  *Client
+	ctx     context.Context
+	urlStr  string
+	opt     interface{}
+	started bool
+	next    string
+	err     error
+}
+
+func newPager(ctx context.Context, client *Client, urlStr string, opt interface{}) pager {
+	return pager{client: client, ctx: ctx, urlStr: urlStr, opt: opt}
+}
+
+// fetch decodes the next page into page, whose next link is links, and
+// reports whether there was one.
+func (p *pager) fetch(page interface{}, links *PageLinks) bool {
+	if p.err != nil || (p.started && p.next == "") {
+		return false
+	}
+	urlStr, opt := p.next, interface{}(nil)
+	if !p.started {
+		urlStr, opt = p.urlStr, p.opt
+	}
+	p.started = true
+
+	req, err := p.client.NewRequest("GET", urlStr, opt, nil)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	if _, err := p.client.DoContext(p.ctx, req, page); err != nil {
+		p.err = err
+		return false
+	}
+	p.next = links.Next
+	return true
+}
+
+// RoomIterator iterates over all the rooms, fetching pages as needed:
+//
+//	it := client.Room.Iterate(nil)
+//	for it.Next() {
+//		room := it.Room()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type RoomIterator struct {
+	p     pager
+	items []Room
+	cur   Room
+}
+
+// RoomsListOptions specifies the optional parameters of the room list.
+type RoomsListOptions struct {
+	ListOptions
+	// Include private rooms in the result.
+	IncludePrivate bool `url:"include-private,omitempty"`
+	// Include archived rooms in the result.
+	IncludeArchived bool `url:"include-archived,omitempty"`
+}
+
+// Iterate returns an iterator over the rooms, starting at the page given by
+// opt.
+func (r *RoomService) Iterate(opt *RoomsListOptions) *RoomIterator {
+	return r.IterateContext(context.Background(), opt)
+}
+
+// IterateContext is like Iterate, with ctx controlling the requests.
+func (r *RoomService) IterateContext(ctx context.Context, opt *RoomsListOptions) *RoomIterator {
+	return &RoomIterator{p: newPager(ctx, r.client, "room", opt)}
+}
+
+// Next advances to the next room, and reports whether there is one.
+func (it *RoomIterator) Next() bool {
+	for len(it.items) == 0 {
+		page := new(Rooms)
+		if !it.p.fetch(page, &page.Links) || len(page.Items) == 0 {
+			return false
+		}
+		it.items = page.Items
+	}
+	it.cur, it.items = it.items[0], it.items[1:]
+	return true
+}
+
+// Room returns the current room.
+func (it *RoomIterator) Room() Room { return it.cur }
+
+// Err returns the error that stopped the iteration, if any.
+func (it *RoomIterator) Err() error { return it.p.err }
+
+// UserIterator iterates over all the users, fetching pages as needed. It is
+// used like RoomIterator.
+type UserIterator struct {
+	p     pager
+	items []User
+	cur   User
+}
+
+// Iterate returns an iterator over the users, starting at the page given by
+// opt.
+func (u *UserService) Iterate(opt *UserListOptions) *UserIterator {
+	return u.IterateContext(context.Background(), opt)
+}
+
+// IterateContext is like Iterate, with ctx controlling the requests.
+func (u *UserService) IterateContext(ctx context.Context, opt *UserListOptions) *UserIterator {
+	return &UserIterator{p: newPager(ctx, u.client, "user", opt)}
+}
+
+// Next advances to the next user, and reports whether there is one.
+func (it *UserIterator) Next() bool {
+	for len(it.items) == 0 {
+		page := new(Users)
+		if !it.p.fetch(page, &page.Links) || len(page.Items) == 0 {
+			return false
+		}
+		it.items = page.Items
+	}
+	it.cur, it.items = it.items[0], it.items[1:]
+	return true
+}
+
+// User returns the current user.
+func (it *UserIterator) User() User { return it.cur }
+
+// Err returns the error that stopped the iteration, if any.
+func (it *UserIterator) Err() error { return it.p.err }
+
+// EmoticonIterator iterates over all the emoticons, fetching pages as needed.
+// It is used like RoomIterator.
+type EmoticonIterator struct {
+	p     pager
+	items []Emoticon
+	cur   Emoticon
+}
+
+// Iterate returns an iterator over the emoticons, starting at the page given
+// by opt.
+func (e *EmoticonService) Iterate(opt *EmoticonsListOptions) *EmoticonIterator {
+	return e.IterateContext(context.Background(), opt)
+}
+
+// IterateContext is like Iterate, with ctx controlling the requests.
+func (e *EmoticonService) IterateContext(ctx context.Context, opt *EmoticonsListOptions) *EmoticonIterator {
+	return &EmoticonIterator{p: newPager(ctx, e.client, "emoticon", opt)}
+}
+
+// Next advances to the next emoticon, and reports whether there is one.
+func (it *EmoticonIterator) Next() bool {
+	for len(it.items) == 0 {
+		page := new(Emoticons)
+		if !it.p.fetch(page, &page.Links) || len(page.Items) == 0 {
+			return false
+		}
+		it.items = page.Items
+	}
+	it.cur, it.items = it.items[0], it.items[1:]
+	return true
+}
+
+// Emoticon returns the current emoticon.
+func (it *EmoticonIterator) Emoticon() Emoticon { return it.cur }
+
+// Err returns the error that stopped the iteration, if any.
+func (it *EmoticonIterator) Err() error { return it.p.err }
+
+// MessageIterator iterates over the chat history of a room, fetching pages as
+// needed. It is used like RoomIterator.
+type MessageIterator struct {
+	p     pager
+	items []Message
+	cur   Message
+}
+
+// IterateHistory returns an iterator over the chat history of a room,
+// starting at the page given by opt.
+func (r *RoomService) IterateHistory(id string, opt *HistoryOptions) *MessageIterator {
+	return r.IterateHistoryContext(context.Background(), id, opt)
+}
+
+// IterateHistoryContext is like IterateHistory, with ctx controlling the
+// requests.
+func (r *RoomService) IterateHistoryContext(ctx context.Context, id string, opt *HistoryOptions) *MessageIterator {
+	return &MessageIterator{p: newPager(ctx, r.client, fmt.Sprintf("room/%s/history", id), opt)}
+}
+
+// Next advances to the next message, and reports whether there is one.
+func (it *MessageIterator) Next() bool {
+	for len(it.items) == 0 {
+		page := new(History)
+		if !it.p.fetch(page, &page.Links) || len(page.Items) == 0 {
+			return false
+		}
+		it.items = page.Items
+	}
+	it.cur, it.items = it.items[0], it.items[1:]
+	return true
+}
+
+// Message returns the current message.
+func (it *MessageIterator) Message() Message { return it.cur }
+
+// Err returns the error that stopped the iteration, if any.
+func (it *MessageIterator) Err() error { return it.p.err }
@@ -0,0 +1,102 @@
+package hipchat
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRoomIterate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		switch r.FormValue("start-index") {
+		case "":
+			testFormValues(t, r, values{"max-results": "2", "include-archived": "true"})
+			fmt.Fprintf(w, `{"items":[{"id":1}, {"id":2}], "links":{"next":"%s/room?start-index=2&max-results=2"}}`, server.URL)
+		case "2":
+			fmt.Fprintf(w, `{"items":[{"id":3}], "links":{}}`)
+		default:
+			t.Errorf("Unexpected start-index %q", r.FormValue("start-index"))
+		}
+	})
+
+	var ids []int
+	it := client.Room.Iterate(&RoomsListOptions{ListOptions: ListOptions{MaxResults: 2}, IncludeArchived: true})
+	for it.Next() {
+		ids = append(ids, it.Room().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("RoomIterator returned an error %v", err)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("RoomIterator returned %v, want %v", ids, want)
+	}
+}
+
+func TestUserIterate_Error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("start-index") == "" {
+			fmt.Fprintf(w, `{"items":[{"id":1}], "links":{"next":"%s/user?start-index=1"}}`, server.URL)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	it := client.User.Iterate(nil)
+	if !it.Next() || it.User().ID != 1 {
+		t.Fatalf("UserIterator did not return the first user")
+	}
+	if it.Next() {
+		t.Fatalf("UserIterator returned %+v, want an error", it.User())
+	}
+	if it.Err() == nil {
+		t.Error("UserIterator did not return an error")
+	}
+}
+
+func TestEmoticonIterate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/emoticon", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"items":[{"id":1, "shortcut":"s"}], "links":{}}`)
+	})
+
+	it := client.Emoticon.Iterate(nil)
+	if !it.Next() || it.Emoticon().Shortcut != "s" || it.Next() || it.Err() != nil {
+		t.Errorf("EmoticonIterator did not return the only emoticon")
+	}
+}
+
+func TestRoomIterateHistory(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/history", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("start-index") == "" {
+			testFormValues(t, r, values{"date": "2016-01-01", "reverse": "false"})
+			fmt.Fprintf(w, `{"items":[{"id":"a"}], "links":{"next":"%s/room/1/history?start-index=1"}}`, server.URL)
+			return
+		}
+		fmt.Fprintf(w, `{"items":[], "links":{"next":"%s/room/1/history?start-index=1"}}`, server.URL)
+	})
+
+	var ids []string
+	it := client.Room.IterateHistory("1", &HistoryOptions{Date: "2016-01-01"})
+	for it.Next() {
+		ids = append(ids, it.Message().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("MessageIterator returned an error %v", err)
+	}
+	if want := []string{"a"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("MessageIterator returned %v, want %v", ids, want)
+	}
+}
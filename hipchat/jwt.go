@@ -0,0 +1,116 @@
+package hipchat
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Errors returned by ParseSignedParams for invalid tokens. They may be wrapped
+// with details, test them with errors.Is. See also ErrTokenExpired,
+// ErrTokenReplayed and ErrTenantNotAllowed.
+var (
+	// ErrTokenMissing is returned when the request carries no JWT.
+	ErrTokenMissing = errors.New("no token present in request")
+	// ErrMalformedToken is returned when the token cannot be decoded.
+	ErrMalformedToken = errors.New("token is malformed")
+	// ErrUnknownIssuer is returned when no key is known for the issuer of the token.
+	ErrUnknownIssuer = errors.New("token issuer is unknown")
+	// ErrBadSignature is returned when the signature of the token does not
+	// match, or is not an HMAC.
+	ErrBadSignature = errors.New("token signature is invalid")
+	// ErrInvalidClaims is returned when the claims of the token are missing,
+	// of the wrong type, or do not match the request.
+	ErrInvalidClaims = errors.New("token claims are invalid")
+)
+
+// ErrNoTokenInRequest is returned by ParseSignedParams when the request
+// carries no JWT.
+//
+// Deprecated: use ErrTokenMissing.
+var ErrNoTokenInRequest = ErrTokenMissing
+
+// TokenErrorStatus returns the HTTP status to answer a request whose token
+// was rejected with err: 400 for malformed tokens and claims, 401 for missing,
+// expired, replayed or badly signed tokens, 403 for unknown or disallowed
+// tenants, 413 for oversized requests and 500 for other failures.
+func TokenErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrMalformedToken), errors.Is(err, ErrInvalidClaims):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrTokenMissing), errors.Is(err, ErrTokenExpired),
+		errors.Is(err, ErrTokenReplayed), errors.Is(err, ErrBadSignature):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrUnknownIssuer), errors.Is(err, ErrTenantNotAllowed):
+		return http.StatusForbidden
+	case isBodyTooLarge(err):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
+
+// tokenCodec parses and signs the JWTs used by the package, keeping the JWT
+// library in use out of the rest of the code.
+type tokenCodec interface {
+	// Parse verifies the HMAC signature of tokenStr with the keys returned by
+	// keyFunc for its claims, and returns the claims. The signature must match
+	// one of the keys. Time claims are left to the caller.
+	Parse(tokenStr string, keyFunc func(claims map[string]interface{}) ([][]byte, error)) (map[string]interface{}, error)
+	// Sign returns a token carrying claims, signed with key using HS256.
+	Sign(claims map[string]interface{}, key []byte) (string, error)
+}
+
+var codec tokenCodec = golangJWTCodec{}
+
+// golangJWTCodec is the tokenCodec backed by github.com/golang-jwt/jwt.
+type golangJWTCodec struct{}
+
+func (golangJWTCodec) Parse(tokenStr string, keyFunc func(claims map[string]interface{}) ([][]byte, error)) (map[string]interface{}, error) {
+	parser := &jwt.Parser{
+		ValidMethods:         []string{"HS256", "HS384", "HS512"},
+		SkipClaimsValidation: true,
+	}
+
+	var keys [][]byte
+	var keyErr error
+	for n := 0; ; n++ {
+		claims := jwt.MapClaims{}
+		_, err := parser.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+			if keys == nil {
+				if keys, keyErr = keyFunc(claims); keyErr != nil {
+					return nil, keyErr
+				}
+				if len(keys) == 0 {
+					keyErr = ErrUnknownIssuer
+					return nil, keyErr
+				}
+			}
+			return keys[n], nil
+		})
+		if err == nil {
+			return claims, nil
+		}
+		if keyErr != nil {
+			return nil, keyErr
+		}
+		vErr, ok := err.(*jwt.ValidationError)
+		switch {
+		case !ok:
+			return nil, err
+		case vErr.Errors&jwt.ValidationErrorMalformed != 0:
+			return nil, fmt.Errorf("%w: %v", ErrMalformedToken, err)
+		case vErr.Errors == jwt.ValidationErrorSignatureInvalid && n+1 < len(keys):
+			// Try the next key
+		case vErr.Errors&(jwt.ValidationErrorSignatureInvalid|jwt.ValidationErrorUnverifiable) != 0:
+			return nil, fmt.Errorf("%w: %v", ErrBadSignature, err)
+		default:
+			return nil, err
+		}
+	}
+}
+
+func (golangJWTCodec) Sign(claims map[string]interface{}, key []byte) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims(claims)).SignedString(key)
+}
@@ -0,0 +1,84 @@
+package hipchat
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCodecParse_KeyRotation(t *testing.T) {
+	tok, err := codec.Sign(map[string]interface{}{"iss": "oauth"}, []byte("new"))
+	if err != nil {
+		t.Fatalf("Sign returned an error %v", err)
+	}
+
+	keys := func(map[string]interface{}) ([][]byte, error) {
+		return [][]byte{[]byte("old"), []byte("new")}, nil
+	}
+	claims, err := codec.Parse(tok, keys)
+	if err != nil {
+		t.Fatalf("Parse returned an error %v", err)
+	}
+	if claims["iss"] != "oauth" {
+		t.Errorf("Parse returned iss %v, want oauth", claims["iss"])
+	}
+
+	oldOnly := func(map[string]interface{}) ([][]byte, error) {
+		return [][]byte{[]byte("old")}, nil
+	}
+	if _, err := codec.Parse(tok, oldOnly); err == nil {
+		t.Errorf("Parse accepted a token signed with an unknown key")
+	}
+}
+
+func TestCodecParse_Errors(t *testing.T) {
+	tok, _ := codec.Sign(map[string]interface{}{"iss": "oauth"}, []byte("secret"))
+	tests := []struct {
+		token  string
+		keys   [][]byte
+		want   error
+		status int
+	}{
+		{"not.a.jwt", [][]byte{[]byte("secret")}, ErrMalformedToken, 400},
+		{tok, nil, ErrUnknownIssuer, 403},
+		{tok, [][]byte{[]byte("other")}, ErrBadSignature, 401},
+	}
+	for _, tt := range tests {
+		_, err := codec.Parse(tt.token, func(map[string]interface{}) ([][]byte, error) {
+			return tt.keys, nil
+		})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Parse(%q) returned %v, want %v", tt.token, err, tt.want)
+		}
+		if status := TokenErrorStatus(err); status != tt.status {
+			t.Errorf("TokenErrorStatus(%v) returned %d, want %d", err, status, tt.status)
+		}
+	}
+}
+
+func TestParseSignedParams_Errors(t *testing.T) {
+	i := NewIntegration(nil, WithKeyResolver(testKeys))
+	encode := func(v string) string { return base64.RawURLEncoding.EncodeToString([]byte(v)) }
+	payload := encode(`{"iss": "oauth", "sub": "1"}`)
+	numericIssuer, _ := codec.Sign(map[string]interface{}{"iss": 1}, []byte("secret"))
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"unsigned", encode(`{"alg": "none", "typ": "JWT"}`) + "." + payload + ".", ErrBadSignature},
+		{"asymmetric algorithm", encode(`{"alg": "RS256", "typ": "JWT"}`) + "." + payload + "." + encode("sig"), ErrBadSignature},
+		{"truncated", encode(`{"alg": "HS256", "typ": "JWT"}`) + "." + payload, ErrMalformedToken},
+		{"issuer of wrong type", numericIssuer, ErrInvalidClaims},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/panel", nil)
+		r.Header.Set("Authorization", "JWT "+tt.token)
+		params, err := i.ParseSignedParams(r)
+		if !errors.Is(err, tt.want) || params != nil {
+			t.Errorf("ParseSignedParams of %s token returned %v, %v, want %v", tt.name, params, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package hipchat
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultJWTLifetime is the lifetime of the JWTs created by a JWTSigner with a
+// zero TTL.
+const DefaultJWTLifetime = 3 * time.Minute
+
+// JWTSigner creates JWTs authenticating requests with the shared secret of an
+// installation, for the HipChat endpoints accepting JWT authentication.
+type JWTSigner struct {
+	// Issuer is the oauthId of the installation.
+	Issuer string
+	// Secret is the oauthSecret of the installation.
+	Secret string
+	// TTL is the lifetime of the tokens. Zero means DefaultJWTLifetime.
+	TTL time.Duration
+	// BasePath is removed from request paths before computing the qsh claim.
+	BasePath string
+}
+
+// NewJWTSigner returns a JWTSigner for the given installation credentials.
+func NewJWTSigner(credentials *InstallRecord) *JWTSigner {
+	return &JWTSigner{Issuer: credentials.OAuthID, Secret: credentials.OAuthSecret}
+}
+
+// Sign returns a JWT for a request with the given method and URL, carrying the
+// iss, iat, exp and qsh claims.
+func (s *JWTSigner) Sign(method string, u *url.URL) (string, error) {
+	ttl := s.TTL
+	if ttl == 0 {
+		ttl = DefaultJWTLifetime
+	}
+
+	now := time.Now()
+	return codec.Sign(map[string]interface{}{
+		"iss": s.Issuer,
+		"iat": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+		"qsh": QueryStringHash(method, u, s.BasePath),
+	}, []byte(s.Secret))
+}
+
+// SignRequest sets the Authorization header of req to a JWT signed for it.
+func (s *JWTSigner) SignRequest(req *http.Request) error {
+	token, err := s.Sign(req.Method, req.URL)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "JWT "+token)
+	return nil
+}
@@ -0,0 +1,73 @@
+package hipchat
+
+import "context"
+
+// KeyResolver looks up the keys that may have signed the JWTs of an issuer,
+// the oauthId of an installation. Tokens are accepted if any of the keys
+// verifies their signature, which allows several secret versions to be valid
+// while a secret is rotated.
+type KeyResolver interface {
+	ResolveKeys(issuer string) ([][]byte, error)
+}
+
+// ContextKeyResolver is a KeyResolver whose lookups can be cancelled. It is
+// used by ParseSignedParamsContext when the configured KeyResolver
+// implements it.
+type ContextKeyResolver interface {
+	KeyResolver
+	ResolveKeysContext(ctx context.Context, issuer string) ([][]byte, error)
+}
+
+// The KeyResolverFunc type is an adapter to allow the use of ordinary
+// functions as KeyResolver.
+type KeyResolverFunc func(issuer string) ([][]byte, error)
+
+// ResolveKeys calls f(issuer).
+func (f KeyResolverFunc) ResolveKeys(issuer string) ([][]byte, error) {
+	return f(issuer)
+}
+
+// WithKeyResolver sets the KeyResolver used to verify incoming JWTs. By
+// default the oauth secret of the installation is read from the Store.
+func WithKeyResolver(resolver KeyResolver) IntegrationOption {
+	return func(i *Integration) {
+		i.keyResolver = resolver
+	}
+}
+
+// resolveKeys returns the keys of issuer. Lookups that do not support
+// cancellation are abandoned, but not interrupted, when ctx is done.
+func (i *Integration) resolveKeys(ctx context.Context, issuer string) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if resolver, ok := i.keyResolver.(ContextKeyResolver); ok {
+		return resolver.ResolveKeysContext(ctx, issuer)
+	}
+
+	type result struct {
+		keys [][]byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var r result
+		if i.keyResolver != nil {
+			r.keys, r.err = i.keyResolver.ResolveKeys(issuer)
+		} else {
+			var secret string
+			secret, r.err = i.Store.GetOAuthSecret(issuer)
+			if secret != "" {
+				r.keys = [][]byte{[]byte(secret)}
+			}
+		}
+		done <- r
+	}()
+
+	select {
+	case r := <-done:
+		return r.keys, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
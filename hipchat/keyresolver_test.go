@@ -0,0 +1,75 @@
+package hipchat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// contextKeys is a ContextKeyResolver whose lookups last until ctx is done.
+type contextKeys struct {
+	calls int
+}
+
+func (k *contextKeys) ResolveKeys(issuer string) ([][]byte, error) {
+	return k.ResolveKeysContext(context.Background(), issuer)
+}
+
+func (k *contextKeys) ResolveKeysContext(ctx context.Context, issuer string) ([][]byte, error) {
+	k.calls++
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestParseSignedParamsContext_Deadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	blocking := KeyResolverFunc(func(string) ([][]byte, error) {
+		<-release
+		return [][]byte{[]byte("secret")}, nil
+	})
+	tests := []struct {
+		name     string
+		resolver KeyResolver
+		timeout  time.Duration
+	}{
+		{"resolver without context", blocking, 20 * time.Millisecond},
+		{"context resolver", &contextKeys{}, 20 * time.Millisecond},
+		{"done before lookup", &contextKeys{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params []*SignedParams
+			_, handler := newSignedIntegration(&params, WithKeyResolver(tt.resolver))
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			r := httptest.NewRequest("GET", "/panel", nil).WithContext(ctx)
+			signTestRequest(t, r, testClaims("oauth"), "secret")
+			w := httptest.NewRecorder()
+
+			done := make(chan struct{})
+			go func() {
+				handler.ServeHTTP(w, r)
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("The request was not abandoned when its context was done")
+			}
+
+			if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), ErrorCodeStoreError) {
+				t.Errorf("GET /panel answered %d %s, want %d", w.Code, w.Body, http.StatusInternalServerError)
+			}
+			if len(params) != 0 {
+				t.Errorf("Request reached the module")
+			}
+			if keys, ok := tt.resolver.(*contextKeys); ok && tt.timeout == 0 && keys.calls != 0 {
+				t.Errorf("Keys were looked up %d times for a done context", keys.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,868 @@
+package hipchat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gorillaMux "github.com/gorilla/mux"
+)
+
+// memoryStore is a Store and AuditStore keeping installations in memory.
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]InstallRecord
+	events  []*AuditEvent
+}
+
+func newMemoryStore(records ...*InstallRecord) *memoryStore {
+	s := &memoryStore{records: make(map[string]InstallRecord)}
+	for _, r := range records {
+		s.records[r.OAuthID] = *r
+	}
+	return s
+}
+
+func (s *memoryStore) SaveCredentials(r *InstallRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[r.OAuthID] = *r
+	return nil
+}
+
+func (s *memoryStore) DeleteCredentials(oAuthID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, oAuthID)
+	return nil
+}
+
+func (s *memoryStore) find(match func(r InstallRecord) bool) *InstallRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.records {
+		if match(r) {
+			return &r
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) GetCredentials(groupID, roomID uint32) (*InstallRecord, error) {
+	return s.find(func(r InstallRecord) bool {
+		return r.GroupID == uint64(groupID) && r.RoomID != nil && *r.RoomID == uint64(roomID)
+	}), nil
+}
+
+func (s *memoryStore) GetCredentialsByOAuthID(oAuthID string) (*InstallRecord, error) {
+	return s.find(func(r InstallRecord) bool { return r.OAuthID == oAuthID }), nil
+}
+
+func (s *memoryStore) GetGlobalCredentials(groupID uint32) (*InstallRecord, error) {
+	return s.find(func(r InstallRecord) bool { return r.GroupID == uint64(groupID) && r.IsGlobal() }), nil
+}
+
+func (s *memoryStore) GetGroupID(roomID uint32) (uint32, error) {
+	r := s.find(func(r InstallRecord) bool { return r.RoomID != nil && *r.RoomID == uint64(roomID) })
+	if r == nil {
+		return 0, fmt.Errorf("No installation in room %v", roomID)
+	}
+	return uint32(r.GroupID), nil
+}
+
+func (s *memoryStore) GetOAuthSecret(oAuthID string) (string, error) {
+	if r := s.find(func(r InstallRecord) bool { return r.OAuthID == oAuthID }); r != nil {
+		return r.OAuthSecret, nil
+	}
+	return "", nil
+}
+
+func (s *memoryStore) RecordEvent(event *AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memoryStore) ListEvents(groupID uint32) ([]*AuditEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []*AuditEvent
+	for _, e := range s.events {
+		if e.GroupID == uint64(groupID) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// fakeHipChat serves the token endpoint of the HipChat API, issuing tokens
+// for the credentials it knows.
+type fakeHipChat struct {
+	*httptest.Server
+	mu      sync.Mutex
+	secrets map[string]string
+	issued  int
+}
+
+func newFakeHipChat(secrets map[string]string) *fakeHipChat {
+	h := &fakeHipChat{secrets: secrets}
+	h.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/oauth/token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		id, secret, _ := r.BasicAuth()
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if known, ok := h.secrets[id]; !ok || known != secret {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": {"code": 401, "message": "Invalid OAuth credentials"}}`)
+			return
+		}
+		h.issued++
+		fmt.Fprintf(w, `{"access_token": "token-%s", "expires_in": 3600}`, secret)
+	}))
+	return h
+}
+
+func (h *fakeHipChat) tokensIssued() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.issued
+}
+
+// newLifecycleIntegration returns a synchronous integration using store and
+// the API of hipchat, and a handler serving its Routes.
+func newLifecycleIntegration(store Store, hipchat *fakeHipChat, opts ...IntegrationOption) (*Integration, http.Handler) {
+	opts = append([]IntegrationOption{
+		WithAPIBaseURL(hipchat.URL + "/v2/"),
+		WithSynchronousCallbacks(0),
+		WithLogger(log.New(ioutil.Discard, "", 0)),
+	}, opts...)
+	i := NewIntegration(store, opts...)
+	return i, routesHandler(i)
+}
+
+// routesHandler returns a handler serving the Routes of i.
+func routesHandler(i *Integration) http.Handler {
+	router := gorillaMux.NewRouter()
+	for _, route := range i.Routes() {
+		router.Path(route.Pattern).Methods(route.Method).HandlerFunc(route.Handler)
+	}
+	return router
+}
+
+// serveLifecycle sends a lifecycle request with the JSON encoding of body, if
+// any, to handler.
+func serveLifecycle(handler http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	r := httptest.NewRequest(method, path, &buf)
+	r.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// testClaims returns the claims of a token HipChat would sign for a user of
+// the installation oauthID in room 3 of group 2, valid for five minutes.
+func testClaims(oauthID string) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss": oauthID,
+		"sub": "1",
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+		"context": map[string]interface{}{
+			"room_id":  3,
+			"group_id": 2,
+			"user_tz":  "UTC",
+		},
+	}
+}
+
+// testToken returns a token carrying claims, bound to r unless claims has a
+// qsh, and signed with secret.
+func testToken(t *testing.T, r *http.Request, claims map[string]interface{}, secret string) string {
+	if _, ok := claims["qsh"]; !ok {
+		claims["qsh"] = QueryStringHash(r.Method, r.URL, "")
+	}
+	tok, err := codec.Sign(claims, []byte(secret))
+	if err != nil {
+		t.Fatalf("Sign returned an error %v", err)
+	}
+	return tok
+}
+
+// signTestRequest sets the Authorization header of r to a token carrying
+// claims, bound to r, and signed with secret.
+func signTestRequest(t *testing.T, r *http.Request, claims map[string]interface{}, secret string) {
+	r.Header.Set("Authorization", "JWT "+testToken(t, r, claims, secret))
+}
+
+// testKeys is a KeyResolver knowing the secret "secret" of installation oauth.
+var testKeys = KeyResolverFunc(func(issuer string) ([][]byte, error) {
+	if issuer == "oauth" {
+		return [][]byte{[]byte("secret")}, nil
+	}
+	return nil, nil
+})
+
+func TestHandleUpdated_SecretRotation(t *testing.T) {
+	room := uint64(3)
+	tests := []struct {
+		name       string
+		secret     string
+		wantStatus int
+		wantSecret string
+		wantToken  string
+		// wantUpdated is the number of times the updated callbacks run.
+		wantUpdated int
+	}{
+		{"accepted by HipChat", "new", http.StatusOK, "new", "token-new", 1},
+		{"rejected by HipChat", "forged", http.StatusForbidden, "old", "token-old", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hipchat := newFakeHipChat(map[string]string{"oauth": "new"})
+			defer hipchat.Close()
+			store := newMemoryStore(&InstallRecord{OAuthID: "oauth", OAuthSecret: "old", GroupID: 2, RoomID: &room})
+			tokens := NewMemoryTokenCache()
+			tokens.Set("2:3", "token-old", time.Time{})
+			updated := 0
+			i, handler := newLifecycleIntegration(store, hipchat, WithTokenCache(tokens))
+			i.AddUpdatedCallback(func() { updated++ })
+
+			w := serveLifecycle(handler, "POST", "/updated", &InstallRecord{OAuthID: "oauth", OAuthSecret: tt.secret})
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("POST /updated answered %d, want %d", w.Code, tt.wantStatus)
+			}
+			if record, _ := store.GetCredentialsByOAuthID("oauth"); record.OAuthSecret != tt.wantSecret {
+				t.Errorf("Stored secret is %q, want %q", record.OAuthSecret, tt.wantSecret)
+			}
+			if token, _ := tokens.Get("2:3"); token != tt.wantToken {
+				t.Errorf("Cached token is %q, want %q", token, tt.wantToken)
+			}
+			if updated != tt.wantUpdated {
+				t.Errorf("Updated callbacks ran %d times, want %d", updated, tt.wantUpdated)
+			}
+		})
+	}
+}
+
+func TestHandleUpdated_APIUnavailable(t *testing.T) {
+	hipchat := newFakeHipChat(nil)
+	hipchat.Close()
+	store := newMemoryStore(&InstallRecord{OAuthID: "oauth", OAuthSecret: "old", GroupID: 2})
+	_, handler := newLifecycleIntegration(store, hipchat)
+
+	w := serveLifecycle(handler, "POST", "/updated", &InstallRecord{OAuthID: "oauth", OAuthSecret: "new"})
+
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), ErrorCodeAPIUnavailable) {
+		t.Errorf("POST /updated answered %d %s, want %d", w.Code, w.Body, http.StatusServiceUnavailable)
+	}
+	if record, _ := store.GetCredentialsByOAuthID("oauth"); record.OAuthSecret != "old" {
+		t.Errorf("Stored secret is %q, want old", record.OAuthSecret)
+	}
+}
+
+func TestHandleRemoved_Source(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []IntegrationOption
+		wantStatus  int
+		wantRemoved bool
+	}{
+		{"no allowlist", nil, http.StatusOK, true},
+		{"allowed network", []IntegrationOption{WithAllowedCIDRs("10.0.0.0/8")}, http.StatusOK, true},
+		{"other network", []IntegrationOption{WithAllowedCIDRs("192.168.0.0/16")}, http.StatusForbidden, false},
+		{"allowed host", []IntegrationOption{WithAllowedHosts("api.hipchat.com")}, http.StatusOK, true},
+		{"other host", []IntegrationOption{WithAllowedHosts("hipchat.example.com")}, http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hipchat := newFakeHipChat(nil)
+			defer hipchat.Close()
+			store := newMemoryStore(&InstallRecord{
+				OAuthID:         "oauth",
+				OAuthSecret:     "secret",
+				GroupID:         2,
+				CapabilitiesURL: "https://api.hipchat.com/v2/capabilities",
+			})
+			removed := false
+			i, handler := newLifecycleIntegration(store, hipchat, tt.opts...)
+			i.AddRemovedCallback(func(string, *InstallRecord) { removed = true })
+
+			w := serveLifecycle(handler, "DELETE", "/installed/oauth", nil)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("DELETE /installed/oauth answered %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusForbidden && !strings.Contains(w.Body.String(), ErrorCodeForbiddenSource) {
+				t.Errorf("DELETE /installed/oauth answered %s, want %s", w.Body, ErrorCodeForbiddenSource)
+			}
+			if record, _ := store.GetCredentialsByOAuthID("oauth"); (record == nil) != tt.wantRemoved {
+				t.Errorf("Installation removed is %v, want %v", record == nil, tt.wantRemoved)
+			}
+			if removed != tt.wantRemoved {
+				t.Errorf("Removed callbacks ran is %v, want %v", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+// slowStore is a memoryStore taking some time to answer lookups, as a remote
+// store would.
+type slowStore struct {
+	*memoryStore
+}
+
+func (s slowStore) GetCredentialsByOAuthID(oAuthID string) (*InstallRecord, error) {
+	record, err := s.memoryStore.GetCredentialsByOAuthID(oAuthID)
+	time.Sleep(10 * time.Millisecond)
+	return record, err
+}
+
+func TestHandleInstalled_ConcurrentRetries(t *testing.T) {
+	hipchat := newFakeHipChat(map[string]string{"oauth": "secret"})
+	defer hipchat.Close()
+	store := slowStore{newMemoryStore()}
+	var installed int32
+	i, handler := newLifecycleIntegration(store, hipchat)
+	i.AddInstallationCallback(func() { atomic.AddInt32(&installed, 1) })
+
+	record := &InstallRecord{
+		OAuthID:         "oauth",
+		OAuthSecret:     "secret",
+		GroupID:         2,
+		CapabilitiesURL: hipchat.URL + "/v2/capabilities",
+	}
+	var wg sync.WaitGroup
+	codes := make([]int, 5)
+	for n := range codes {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			codes[n] = serveLifecycle(handler, "POST", "/installed", record).Code
+		}(n)
+	}
+	wg.Wait()
+
+	for n, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("POST /installed %d answered %d, want %d", n, code, http.StatusOK)
+		}
+	}
+	if n := atomic.LoadInt32(&installed); n != 1 {
+		t.Errorf("Installation callbacks ran %d times, want 1", n)
+	}
+	if n := hipchat.tokensIssued(); n != 1 {
+		t.Errorf("HipChat issued %d tokens, want 1", n)
+	}
+}
+
+func TestShutdown_DrainsInstallations(t *testing.T) {
+	hipchat := newFakeHipChat(map[string]string{"oauth": "secret"})
+	defer hipchat.Close()
+	// Unlike newLifecycleIntegration, installations complete in the
+	// background.
+	i := NewIntegration(newMemoryStore(), WithAPIBaseURL(hipchat.URL+"/v2/"), WithLogger(log.New(ioutil.Discard, "", 0)))
+	handler := routesHandler(i)
+	started, release := make(chan struct{}), make(chan struct{})
+	var finished int32
+	i.AddInstallationCallback(func() {
+		close(started)
+		<-release
+		atomic.StoreInt32(&finished, 1)
+	})
+
+	w := serveLifecycle(handler, "POST", "/installed", &InstallRecord{OAuthID: "oauth", OAuthSecret: "secret", GroupID: 2})
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /installed answered %d, want %d", w.Code, http.StatusOK)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := i.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown with a pending callback returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	w = serveLifecycle(handler, "POST", "/installed", &InstallRecord{OAuthID: "other", OAuthSecret: "secret", GroupID: 3})
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), ErrorCodeShuttingDown) {
+		t.Errorf("POST /installed after Shutdown answered %d %s, want %d", w.Code, w.Body, http.StatusServiceUnavailable)
+	}
+
+	close(release)
+	if err := i.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown returned %v", err)
+	}
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Errorf("Shutdown returned before the installation callback finished")
+	}
+}
+
+func TestHandleInstalled_Global(t *testing.T) {
+	room := uint64(3)
+	tests := []struct {
+		name       string
+		roomID     *uint64
+		wantGroup  bool
+		wantRoom   bool
+		wantGlobal bool
+	}{
+		{"global installation", nil, true, false, true},
+		{"room installation", &room, false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hipchat := newFakeHipChat(map[string]string{"oauth": "secret"})
+			defer hipchat.Close()
+			store := newMemoryStore()
+			i, handler := newLifecycleIntegration(store, hipchat)
+
+			w := serveLifecycle(handler, "POST", "/installed", &InstallRecord{OAuthID: "oauth", OAuthSecret: "secret", GroupID: 2, RoomID: tt.roomID})
+			if w.Code != http.StatusOK {
+				t.Fatalf("POST /installed answered %d, want %d", w.Code, http.StatusOK)
+			}
+
+			if record, _ := store.GetGlobalCredentials(2); (record != nil) != tt.wantGlobal {
+				t.Errorf("Global credentials found is %v, want %v", record != nil, tt.wantGlobal)
+			}
+			if token, err := i.GetTokenForGroup(2); (err == nil) != tt.wantGroup || (err == nil && token != "token-secret") {
+				t.Errorf("GetTokenForGroup returned %q, %v", token, err)
+			}
+			if token, err := i.GetTokenForRoom(3); (err == nil) != tt.wantRoom || (err == nil && token != "token-secret") {
+				t.Errorf("GetTokenForRoom returned %q, %v", token, err)
+			}
+			if n := hipchat.tokensIssued(); n != 1 {
+				t.Errorf("HipChat issued %d tokens, want 1", n)
+			}
+		})
+	}
+}
+
+func TestLifecycle_Source(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []IntegrationOption
+		remoteAddr string
+		wantStatus int
+	}{
+		{"no allowlist", nil, "192.168.0.1:1234", http.StatusOK},
+		{"allowed network", []IntegrationOption{WithAllowedCIDRs("10.0.0.0/8", "192.168.0.0/16")}, "192.168.0.1:1234", http.StatusOK},
+		{"other network", []IntegrationOption{WithAllowedCIDRs("10.0.0.0/8")}, "192.168.0.1:1234", http.StatusForbidden},
+		{"address without port", []IntegrationOption{WithAllowedCIDRs("10.0.0.0/8")}, "10.0.0.1", http.StatusOK},
+		{"unparsable address", []IntegrationOption{WithAllowedCIDRs("10.0.0.0/8")}, "unknown", http.StatusForbidden},
+		{"allowed host", []IntegrationOption{WithAllowedHosts("API.hipchat.com")}, "10.0.0.1:1234", http.StatusOK},
+		{"other host", []IntegrationOption{WithAllowedHosts("hipchat.example.com")}, "10.0.0.1:1234", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		for _, path := range []string{"/installed", "/updated"} {
+			t.Run(tt.name+" "+path, func(t *testing.T) {
+				hipchat := newFakeHipChat(map[string]string{"oauth": "secret"})
+				defer hipchat.Close()
+				store := newMemoryStore()
+				if path == "/updated" {
+					store = newMemoryStore(&InstallRecord{OAuthID: "oauth", OAuthSecret: "old", GroupID: 2})
+				}
+				_, handler := newLifecycleIntegration(store, hipchat, tt.opts...)
+
+				body, _ := json.Marshal(&InstallRecord{
+					OAuthID:         "oauth",
+					OAuthSecret:     "secret",
+					GroupID:         2,
+					CapabilitiesURL: "https://api.hipchat.com/v2/capabilities",
+				})
+				r := httptest.NewRequest("POST", path, bytes.NewReader(body))
+				r.RemoteAddr = tt.remoteAddr
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+
+				if w.Code != tt.wantStatus {
+					t.Errorf("POST %s answered %d %s, want %d", path, w.Code, w.Body, tt.wantStatus)
+				}
+				record, _ := store.GetCredentialsByOAuthID("oauth")
+				if saved := record != nil && record.OAuthSecret == "secret"; saved != (tt.wantStatus == http.StatusOK) {
+					t.Errorf("Installation saved is %v, want %v", saved, tt.wantStatus == http.StatusOK)
+				}
+			})
+		}
+	}
+}
+
+func TestHandleInstalled_Strict(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            []IntegrationOption
+		capabilitiesURL string
+		wantStatus      int
+		wantCode        string
+	}{
+		{"HipChat cloud", nil, "https://api.hipchat.com/v2/capabilities", http.StatusOK, ""},
+		{"other host", nil, "https://evil.example.com/v2/capabilities", http.StatusBadRequest, ErrorCodeUnexpectedHost},
+		{"lookalike host", nil, "https://evilhipchat.com/v2/capabilities", http.StatusBadRequest, ErrorCodeUnexpectedHost},
+		{"missing capabilities", nil, "", http.StatusBadRequest, ErrorCodeBadPayload},
+		{"HipChat Server", []IntegrationOption{WithHipChatHosts("hipchat.example.com")}, "https://hipchat.example.com/v2/capabilities", http.StatusOK, ""},
+		{"cloud with HipChat Server", []IntegrationOption{WithHipChatHosts("hipchat.example.com")}, "https://api.hipchat.com/v2/capabilities", http.StatusBadRequest, ErrorCodeUnexpectedHost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hipchat := newFakeHipChat(map[string]string{"oauth": "secret"})
+			defer hipchat.Close()
+			store := newMemoryStore()
+			installed := 0
+			i, handler := newLifecycleIntegration(store, hipchat, append(tt.opts, WithStrictValidation())...)
+			i.AddInstallationCallback(func() { installed++ })
+
+			w := serveLifecycle(handler, "POST", "/installed", &InstallRecord{
+				OAuthID:         "oauth",
+				OAuthSecret:     "secret",
+				GroupID:         2,
+				CapabilitiesURL: tt.capabilitiesURL,
+			})
+
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantCode) {
+				t.Errorf("POST /installed answered %d %s, want %d %s", w.Code, w.Body, tt.wantStatus, tt.wantCode)
+			}
+			wantInstalled := tt.wantStatus == http.StatusOK
+			if record, _ := store.GetCredentialsByOAuthID("oauth"); (record != nil) != wantInstalled {
+				t.Errorf("Installation saved is %v, want %v", record != nil, wantInstalled)
+			}
+			if (installed == 1) != wantInstalled {
+				t.Errorf("Installation callbacks ran %d times", installed)
+			}
+		})
+	}
+}
+
+func TestLifecycle_AuditEvents(t *testing.T) {
+	hipchat := newFakeHipChat(map[string]string{"oauth": "secret"})
+	defer hipchat.Close()
+	store := newMemoryStore()
+	_, handler := newLifecycleIntegration(store, hipchat)
+	room := uint64(3)
+
+	requests := []struct {
+		method string
+		path   string
+		body   interface{}
+	}{
+		{"POST", "/installed", &InstallRecord{OAuthID: "oauth", OAuthSecret: "secret", GroupID: 2, RoomID: &room}},
+		{"POST", "/updated", &InstallRecord{OAuthID: "oauth", OAuthSecret: "forged"}},
+		{"DELETE", "/installed/oauth", nil},
+		{"DELETE", "/installed/oauth", nil},
+	}
+	for _, req := range requests {
+		serveLifecycle(handler, req.method, req.path, req.body)
+	}
+
+	want := []struct {
+		eventType string
+		result    string
+	}{
+		{AuditEventInstalled, AuditResultOK},
+		{AuditEventUpdated, ErrorCodeRejected},
+		{AuditEventRemoved, AuditResultOK},
+	}
+	events, err := store.ListEvents(2)
+	if err != nil {
+		t.Fatalf("ListEvents returned an error %v", err)
+	}
+	if len(events) != len(want) {
+		t.Fatalf("ListEvents returned %d events, want %d", len(events), len(want))
+	}
+	for n, e := range events {
+		if e.Type != want[n].eventType || e.Result != want[n].result {
+			t.Errorf("Event %d is %s %s, want %s %s", n, e.Type, e.Result, want[n].eventType, want[n].result)
+		}
+		if e.OAuthID != "oauth" || e.RoomID == nil || *e.RoomID != room || e.Source != "10.0.0.1:1234" || e.Time.IsZero() {
+			t.Errorf("Event %d is %+v", n, e)
+		}
+	}
+
+	// The removal of an unknown installation has no group.
+	if events, _ := store.ListEvents(0); len(events) != 1 || events[0].Result != ErrorCodeNotFound {
+		t.Errorf("ListEvents(0) returned %v, want a %s removal", events, ErrorCodeNotFound)
+	}
+}
+
+func TestGetToken_Expiry(t *testing.T) {
+	room := uint64(3)
+	tests := []struct {
+		name       string
+		expiresAt  time.Time
+		wantToken  string
+		wantIssued int
+	}{
+		{"valid token", time.Now().Add(time.Minute), "token-cached", 0},
+		{"expired token", time.Now().Add(-time.Second), "token-secret", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hipchat := newFakeHipChat(map[string]string{"oauth": "secret"})
+			defer hipchat.Close()
+			store := newMemoryStore(
+				&InstallRecord{OAuthID: "oauth", OAuthSecret: "secret", GroupID: 2, RoomID: &room},
+				&InstallRecord{OAuthID: "global", OAuthSecret: "secret", GroupID: 2})
+			hipchat.secrets["global"] = "secret"
+			tokens := NewMemoryTokenCache()
+			tokens.Set("2:3", "token-cached", tt.expiresAt)
+			tokens.Set("2:", "token-cached", tt.expiresAt)
+			i, _ := newLifecycleIntegration(store, hipchat, WithTokenCache(tokens))
+
+			if token, err := i.GetTokenForRoom(3); err != nil || token != tt.wantToken {
+				t.Errorf("GetTokenForRoom returned %q, %v, want %q", token, err, tt.wantToken)
+			}
+			if token, err := i.GetTokenForGroup(2); err != nil || token != tt.wantToken {
+				t.Errorf("GetTokenForGroup returned %q, %v, want %q", token, err, tt.wantToken)
+			}
+			if n := hipchat.tokensIssued(); n != 2*tt.wantIssued {
+				t.Errorf("HipChat issued %d tokens, want %d", n, 2*tt.wantIssued)
+			}
+
+			// The renewed tokens are cached until shortly before they expire.
+			i.GetTokenForRoom(3)
+			i.GetTokenForGroup(2)
+			if n := hipchat.tokensIssued(); n != 2*tt.wantIssued {
+				t.Errorf("HipChat issued %d tokens after a second lookup, want %d", n, 2*tt.wantIssued)
+			}
+		})
+	}
+}
+
+func TestLifecycle_TokenEviction(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      interface{}
+		wantToken string
+	}{
+		{"removal", "DELETE", "/installed/oauth", nil, ""},
+		{"reinstall", "POST", "/installed", &InstallRecord{OAuthID: "oauth", OAuthSecret: "new", GroupID: 2}, "token-new"},
+		{"secret rotation", "POST", "/updated", &InstallRecord{OAuthID: "oauth", OAuthSecret: "new"}, "token-new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hipchat := newFakeHipChat(map[string]string{"oauth": "new"})
+			defer hipchat.Close()
+			store := newMemoryStore(&InstallRecord{OAuthID: "oauth", OAuthSecret: "old", GroupID: 2})
+			tokens := NewMemoryTokenCache()
+			tokens.Set("2:", "token-old", time.Now().Add(time.Hour))
+			i, handler := newLifecycleIntegration(store, hipchat, WithTokenCache(tokens))
+
+			if w := serveLifecycle(handler, tt.method, tt.path, tt.body); w.Code != http.StatusOK {
+				t.Fatalf("%s %s answered %d %s", tt.method, tt.path, w.Code, w.Body)
+			}
+
+			token, err := i.GetTokenForGroup(2)
+			if token != tt.wantToken || (err == nil) != (tt.wantToken != "") {
+				t.Errorf("GetTokenForGroup returned %q, %v, want %q", token, err, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestHandleInstalled_SynchronousFailure(t *testing.T) {
+	hipchat := newFakeHipChat(map[string]string{})
+	defer hipchat.Close()
+	store := newMemoryStore()
+	installed := 0
+	i, handler := newLifecycleIntegration(store, hipchat)
+	i.AddInstallationCallback(func() { installed++ })
+	record := &InstallRecord{OAuthID: "oauth", OAuthSecret: "secret", GroupID: 2}
+
+	w := serveLifecycle(handler, "POST", "/installed", record)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), ErrorCodeAPIUnavailable) {
+		t.Errorf("POST /installed without token answered %d %s, want %d", w.Code, w.Body, http.StatusServiceUnavailable)
+	}
+	if r, _ := store.GetCredentialsByOAuthID("oauth"); r != nil {
+		t.Errorf("Failed installation was saved")
+	}
+	if installed != 0 {
+		t.Errorf("Installation callbacks ran for a failed installation")
+	}
+
+	// The retry of HipChat is not taken for a duplicate.
+	hipchat.mu.Lock()
+	hipchat.secrets["oauth"] = "secret"
+	hipchat.mu.Unlock()
+	if w := serveLifecycle(handler, "POST", "/installed", record); w.Code != http.StatusOK {
+		t.Errorf("Retried POST /installed answered %d, want %d", w.Code, http.StatusOK)
+	}
+	if installed != 1 {
+		t.Errorf("Installation callbacks ran %d times after the retry, want 1", installed)
+	}
+}
+
+func TestHandleInstalled_CallbackTimeout(t *testing.T) {
+	hipchat := newFakeHipChat(map[string]string{"oauth": "secret"})
+	defer hipchat.Close()
+	i, handler := newLifecycleIntegration(newMemoryStore(), hipchat, WithSynchronousCallbacks(20*time.Millisecond))
+	release := make(chan struct{})
+	var finished int32
+	i.AddInstallationCallback(func() {
+		<-release
+		atomic.StoreInt32(&finished, 1)
+	})
+
+	done := make(chan int)
+	go func() {
+		done <- serveLifecycle(handler, "POST", "/installed", &InstallRecord{OAuthID: "oauth", OAuthSecret: "secret", GroupID: 2}).Code
+	}()
+	select {
+	case code := <-done:
+		if code != http.StatusOK {
+			t.Errorf("POST /installed answered %d, want %d", code, http.StatusOK)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("POST /installed waited for the callback beyond its timeout")
+	}
+
+	// The overrunning callback is not stopped, and Shutdown waits for it.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := i.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(release)
+	i.Wait()
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Errorf("Wait returned before the callback finished")
+	}
+}
+
+func TestHandleInstalled_PayloadTooLarge(t *testing.T) {
+	hipchat := newFakeHipChat(map[string]string{"oauth": "secret"})
+	defer hipchat.Close()
+	store := newMemoryStore()
+	_, handler := newLifecycleIntegration(store, hipchat, WithMaxBodyBytes(64))
+
+	w := serveLifecycle(handler, "POST", "/installed", &InstallRecord{
+		OAuthID:         "oauth",
+		OAuthSecret:     strings.Repeat("s", 64),
+		CapabilitiesURL: "https://api.hipchat.com/v2/capabilities",
+		GroupID:         2,
+	})
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), ErrorCodePayloadTooLarge) {
+		t.Errorf("POST /installed answered %d %s, want %d %s", w.Code, w.Body, http.StatusRequestEntityTooLarge, ErrorCodePayloadTooLarge)
+	}
+	if record, _ := store.GetCredentialsByOAuthID("oauth"); record != nil {
+		t.Error("Oversized installation was saved")
+	}
+}
+
+func TestLifecycle_HookVeto(t *testing.T) {
+	hipchat := newFakeHipChat(map[string]string{"oauth": "secret", "other": "secret"})
+	defer hipchat.Close()
+	store := newMemoryStore(&InstallRecord{
+		OAuthID:         "oauth",
+		OAuthSecret:     "secret",
+		GroupID:         2,
+		CapabilitiesURL: "https://api.hipchat.com/v2/capabilities",
+	})
+	i, handler := newLifecycleIntegration(store, hipchat)
+	var postSaved, postDeleted, installed, removed bool
+	i.AddPreSaveHook(func(*InstallRecord) error { return errors.New("Group 3 is not a customer") })
+	i.AddPostSaveHook(func(*InstallRecord) { postSaved = true })
+	i.AddPreDeleteHook(func(string, *InstallRecord) error { return errors.New("Subscription still active") })
+	i.AddPostDeleteHook(func(string, *InstallRecord) { postDeleted = true })
+	i.AddInstallationCallback(func() { installed = true })
+	i.AddRemovedCallback(func(string, *InstallRecord) { removed = true })
+
+	w := serveLifecycle(handler, "POST", "/installed", &InstallRecord{
+		OAuthID:         "other",
+		OAuthSecret:     "secret",
+		GroupID:         3,
+		CapabilitiesURL: "https://api.hipchat.com/v2/capabilities",
+	})
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), ErrorCodeRejected) {
+		t.Errorf("POST /installed answered %d %s, want %d %s", w.Code, w.Body, http.StatusForbidden, ErrorCodeRejected)
+	}
+	if record, _ := store.GetCredentialsByOAuthID("other"); record != nil {
+		t.Error("Vetoed installation was saved")
+	}
+	if postSaved || installed {
+		t.Errorf("Post-save hooks ran is %v and installation callbacks ran is %v, want false", postSaved, installed)
+	}
+
+	w = serveLifecycle(handler, "DELETE", "/installed/oauth", nil)
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), ErrorCodeRejected) {
+		t.Errorf("DELETE /installed/oauth answered %d %s, want %d %s", w.Code, w.Body, http.StatusForbidden, ErrorCodeRejected)
+	}
+	if record, _ := store.GetCredentialsByOAuthID("oauth"); record == nil {
+		t.Error("Vetoed removal deleted the installation")
+	}
+	if postDeleted || removed {
+		t.Errorf("Post-delete hooks ran is %v and removed callbacks ran is %v, want false", postDeleted, removed)
+	}
+}
+
+// chiRouter records the routes registered by RegisterChi.
+type chiRouter map[string]http.HandlerFunc
+
+func (r chiRouter) MethodFunc(method, pattern string, h http.HandlerFunc) {
+	r[method+" "+pattern] = h
+}
+
+func TestRoutes(t *testing.T) {
+	i := NewIntegration(newMemoryStore(), WithLogger(log.New(ioutil.Discard, "", 0)))
+	i.AddWebPanel("/panel", WebPanelModule{Key: "panel"}, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	want := []string{
+		"POST /installed",
+		"DELETE /installed/{oAuthId}",
+		"POST /updated",
+		"GET /capabilities",
+		"GET /atlassian-connect.json",
+		"GET /healthz",
+		"GET /readyz",
+		"GET /panel",
+	}
+
+	var routes, echoRoutes []string
+	for _, route := range i.Routes() {
+		routes = append(routes, route.Method+" "+route.Pattern)
+		echoRoutes = append(echoRoutes, route.Method+" "+route.EchoPattern())
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("Routes returned %v, want %v", routes, want)
+	}
+	wantEcho := append([]string(nil), want...)
+	wantEcho[1] = "DELETE /installed/:oAuthId"
+	if !reflect.DeepEqual(echoRoutes, wantEcho) {
+		t.Errorf("Routes returned the echo patterns %v, want %v", echoRoutes, wantEcho)
+	}
+
+	chi := make(chiRouter)
+	i.RegisterChi(chi)
+	if len(chi) != len(want) {
+		t.Errorf("RegisterChi registered %d routes, want %d", len(chi), len(want))
+	}
+	for _, route := range want {
+		if chi[route] == nil {
+			t.Errorf("RegisterChi did not register %s", route)
+		}
+	}
+	w := httptest.NewRecorder()
+	chi["GET /healthz"](w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "OK\n" {
+		t.Errorf("GET /healthz registered on chi answered %d %s", w.Code, w.Body)
+	}
+}
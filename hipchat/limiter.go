@@ -0,0 +1,132 @@
+package hipchat
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Default rate limit of HipChat API tokens.
+const (
+	DefaultRateLimit  = 100
+	DefaultRatePeriod = 5 * time.Minute
+)
+
+// RateLimiter paces API requests with a token bucket per API token, so that
+// clients stay under the rate limit of HipChat instead of being answered 429
+// Too Many Requests. A RateLimiter is safe for concurrent use and can be shared
+// by all the clients of an add-on: requests made with the same API token share
+// a bucket.
+type RateLimiter struct {
+	limit   float64
+	perNano float64
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// blockedUntil is set when HipChat reports that no request is left.
+	blockedUntil time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing limit requests per period and
+// per API token, in bursts of up to limit requests.
+// It panics if limit or period is not positive.
+func NewRateLimiter(limit int, period time.Duration) *RateLimiter {
+	if limit <= 0 || period <= 0 {
+		panic(fmt.Errorf("Invalid rate limit of %d requests per %v", limit, period))
+	}
+	return &RateLimiter{
+		limit:   float64(limit),
+		perNano: float64(limit) / float64(period),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// NewDefaultRateLimiter returns a RateLimiter matching the documented rate
+// limit of HipChat, DefaultRateLimit requests per DefaultRatePeriod.
+func NewDefaultRateLimiter() *RateLimiter {
+	return NewRateLimiter(DefaultRateLimit, DefaultRatePeriod)
+}
+
+// SetRateLimiter makes the client wait for limiter before each request. A nil
+// limiter disables rate limiting, which is the default.
+func (c *Client) SetRateLimiter(limiter *RateLimiter) {
+	c.limiter = limiter
+}
+
+// Wait blocks until a request can be made with the given API token, or ctx is
+// done.
+func (l *RateLimiter) Wait(ctx context.Context, token string) error {
+	for {
+		delay := l.reserve(token, time.Now())
+		if delay <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a request from the bucket of token if one is available, or
+// returns how long to wait for one.
+func (l *RateLimiter) reserve(token string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(token, now)
+	if now.Before(b.blockedUntil) {
+		return b.blockedUntil.Sub(now)
+	}
+	b.tokens += float64(now.Sub(b.updated)) * l.perNano
+	if b.tokens > l.limit {
+		b.tokens = l.limit
+	}
+	b.updated = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / l.perNano)
+}
+
+// observe adjusts the bucket of token to the rate limit reported by HipChat,
+// which accounts for the requests made by other processes with the same token.
+func (l *RateLimiter) observe(token string, rate Rate, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(token, now)
+	if float64(rate.Remaining) < b.tokens {
+		b.tokens = float64(rate.Remaining)
+	}
+	if rate.Remaining == 0 && rate.Reset.After(now) {
+		b.blockedUntil = rate.Reset
+	}
+}
+
+func (l *RateLimiter) bucket(token string, now time.Time) *bucket {
+	b, ok := l.buckets[token]
+	if !ok {
+		b = &bucket{tokens: l.limit, updated: now}
+		l.buckets[token] = b
+	}
+	return b
+}
+
+// limiterKey returns the key of the bucket the requests of the client draw
+// from.
+func (c *Client) limiterKey() string {
+	if c.signer != nil {
+		return "jwt:" + c.signer.Issuer
+	}
+	return c.authToken
+}
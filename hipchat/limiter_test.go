@@ -0,0 +1,93 @@
+package hipchat
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	l := NewRateLimiter(2, time.Minute)
+	now := time.Now()
+
+	for n := 0; n < 2; n++ {
+		if delay := l.reserve("a", now); delay != 0 {
+			t.Fatalf("Request %d waits %v, want no wait", n, delay)
+		}
+	}
+	if delay := l.reserve("a", now); delay != 30*time.Second {
+		t.Errorf("Third request waits %v, want 30s", delay)
+	}
+	if delay := l.reserve("b", now); delay != 0 {
+		t.Errorf("Request with another token waits %v, want no wait", delay)
+	}
+	if delay := l.reserve("a", now.Add(30*time.Second)); delay != 0 {
+		t.Errorf("Request after refill waits %v, want no wait", delay)
+	}
+}
+
+func TestRateLimiterObserve(t *testing.T) {
+	l := NewRateLimiter(100, time.Minute)
+	now := time.Now()
+
+	l.observe("a", Rate{Limit: 100, Remaining: 0, Reset: now.Add(time.Minute)}, now)
+	if delay := l.reserve("a", now); delay != time.Minute {
+		t.Errorf("Request waits %v, want 1m until the reset", delay)
+	}
+}
+
+func TestRateLimiterWait_Canceled(t *testing.T) {
+	l := NewRateLimiter(1, time.Hour)
+	l.reserve("a", time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := l.Wait(ctx, "a"); err != context.Canceled {
+		t.Errorf("Wait returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestClientRateLimiter(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Limit", "100")
+		w.Header().Set("X-Ratelimit-Remaining", "0")
+		w.Header().Set("X-Ratelimit-Reset", "4000000000")
+		w.Write([]byte(`{}`))
+	})
+	client.SetRateLimiter(NewDefaultRateLimiter())
+
+	if _, _, err := client.Room.Get("1"); err != nil {
+		t.Fatalf("Room.Get returns an error %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := client.Room.GetContext(ctx, "1"); err == nil {
+		t.Error("Room.GetContext did not wait for the rate limit reset")
+	}
+}
+
+func TestNewRateLimiter_Invalid(t *testing.T) {
+	tests := []struct {
+		limit  int
+		period time.Duration
+	}{
+		{0, time.Minute},
+		{-1, time.Minute},
+		{100, 0},
+		{100, -time.Minute},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewRateLimiter(%d, %v) did not panic", tt.limit, tt.period)
+				}
+			}()
+			NewRateLimiter(tt.limit, tt.period)
+		}()
+	}
+}
@@ -0,0 +1,64 @@
+package hipchat
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Mentions of groups of users. HipChat only expands mentions in messages of
+// the text format.
+const (
+	// MentionAll notifies all the users of a room.
+	MentionAll = "@all"
+	// MentionHere notifies the users of a room who are available.
+	MentionHere = "@here"
+)
+
+// ErrMentionInHTML is returned by NotificationRequest.Mention for HTML
+// messages, in which HipChat renders mentions as literal text.
+var ErrMentionInHTML = errors.New("mentions are not expanded in HTML messages")
+
+// Mention returns the mention of the user with the given mention name, with
+// or without its leading "@". Characters a mention name cannot contain, such
+// as spaces, are removed.
+func Mention(mentionName string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '@' || r == '<' || r == '>' || r == '&' {
+			return -1
+		}
+		return r
+	}, mentionName)
+	return "@" + name
+}
+
+// EscapeMessage escapes text to be included verbatim in a message of the given
+// format: HTML special characters are escaped in HTML messages, and text
+// messages are left untouched.
+func EscapeMessage(format, text string) string {
+	if format == MessageFormatHTML {
+		return html.EscapeString(text)
+	}
+	return text
+}
+
+// Mention prepends mentions, built with Mention, MentionAll or MentionHere, to
+// the message of the notification and sets its format to text, the only one in
+// which HipChat expands mentions. ErrMentionInHTML is returned if the message
+// is HTML.
+func (n *NotificationRequest) Mention(mentions ...string) error {
+	format := n.MessageFormat
+	if format == "" {
+		format = DetectMessageFormat(n.Message)
+	}
+	if format == MessageFormatHTML {
+		return ErrMentionInHTML
+	}
+	n.MessageFormat = MessageFormatText
+	if len(mentions) == 0 {
+		return nil
+	}
+	n.Message = strings.Join(mentions, " ") + " " + n.Message
+	return nil
+}
@@ -0,0 +1,41 @@
+package hipchat
+
+import "testing"
+
+func TestMention(t *testing.T) {
+	tests := map[string]string{
+		"jdoe":       "@jdoe",
+		"@jdoe":      "@jdoe",
+		" John Doe ": "@JohnDoe",
+		"<b>x</b>":   "@bx/b",
+	}
+	for name, want := range tests {
+		if got := Mention(name); got != want {
+			t.Errorf("Mention(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestEscapeMessage(t *testing.T) {
+	if got := EscapeMessage(MessageFormatHTML, "a < b & c"); got != "a &lt; b &amp; c" {
+		t.Errorf("EscapeMessage html = %q", got)
+	}
+	if got := EscapeMessage(MessageFormatText, "a < b"); got != "a < b" {
+		t.Errorf("EscapeMessage text = %q", got)
+	}
+}
+
+func TestNotificationRequestMention(t *testing.T) {
+	n := &NotificationRequest{Message: "deploy is done"}
+	if err := n.Mention(MentionHere, Mention("jdoe")); err != nil {
+		t.Fatalf("Mention returned an error %v", err)
+	}
+	if n.Message != "@here @jdoe deploy is done" || n.MessageFormat != MessageFormatText {
+		t.Errorf("Mention set message %q with format %q", n.Message, n.MessageFormat)
+	}
+
+	n = &NotificationRequest{Message: "<b>done</b>"}
+	if err := n.Mention(MentionAll); err != ErrMentionInHTML {
+		t.Errorf("Mention returned %v, want %v", err, ErrMentionInHTML)
+	}
+}
@@ -0,0 +1,46 @@
+package hipchat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+type signedParamsKey struct{}
+
+// RequireSignedParams wraps next so that it is only called for requests
+// carrying a valid JWT signed by HipChat. The parsed SignedParams are added to
+// the request context, see SignedParamsFromContext, so that calling
+// ParseSignedParams again in next is free. Other requests are rejected with
+// the status returned by TokenErrorStatus.
+func (i *Integration) RequireSignedParams(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params, err := i.ParseSignedParams(r)
+		status := TokenErrorStatus(err)
+		switch {
+		case err == nil:
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), signedParamsKey{}, params)))
+		case errors.Is(err, ErrTokenMissing):
+			writeError(w, status, ErrorCodeMissingToken, "The request is not signed.")
+		case errors.Is(err, ErrTokenExpired):
+			writeError(w, status, ErrorCodeTokenExpired, "The token has expired.")
+		case errors.Is(err, ErrUnknownIssuer), errors.Is(err, ErrTenantNotAllowed):
+			writeError(w, status, ErrorCodeForbiddenTenant, "The installation is not allowed.")
+		case status == http.StatusRequestEntityTooLarge:
+			writeError(w, status, ErrorCodePayloadTooLarge, "The request is too large.")
+		case status == http.StatusInternalServerError:
+			i.logger.Printf("Error checking signed request to %s: %v", r.URL.Path, err)
+			writeError(w, status, ErrorCodeStoreError, "The token could not be checked.")
+		default:
+			i.logger.Printf("Rejected signed request to %s: %v", r.URL.Path, err)
+			writeError(w, status, ErrorCodeInvalidToken, "The token is invalid.")
+		}
+	})
+}
+
+// SignedParamsFromContext returns the SignedParams stored in ctx by
+// RequireSignedParams, if any.
+func SignedParamsFromContext(ctx context.Context) (*SignedParams, bool) {
+	params, ok := ctx.Value(signedParamsKey{}).(*SignedParams)
+	return params, ok
+}
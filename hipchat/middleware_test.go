@@ -0,0 +1,236 @@
+package hipchat
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSignedIntegration returns an integration knowing installation oauth,
+// with a web panel at GET /panel and a webhook at POST /hook requiring signed
+// requests, and a handler serving its Routes. The SignedParams of the requests
+// reaching the modules are appended to params.
+func newSignedIntegration(params *[]*SignedParams, opts ...IntegrationOption) (*Integration, http.Handler) {
+	store := newMemoryStore(&InstallRecord{OAuthID: "oauth", OAuthSecret: "secret", GroupID: 2})
+	opts = append([]IntegrationOption{WithLogger(log.New(ioutil.Discard, "", 0))}, opts...)
+	i := NewIntegration(store, opts...)
+	module := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := SignedParamsFromContext(r.Context()); ok {
+			*params = append(*params, p)
+		}
+	})
+	i.AddWebPanel("/panel", WebPanelModule{Key: "panel"}, module)
+	i.AddWebhook("/hook", WebhookModule{Event: WebhookEventRoomMessage, Authentication: WebhookAuthenticationJWT}, module)
+	return i, routesHandler(i)
+}
+
+func TestRequireSignedParams(t *testing.T) {
+	failingKeys := KeyResolverFunc(func(string) ([][]byte, error) {
+		return nil, errors.New("store is down")
+	})
+	tests := []struct {
+		name       string
+		opts       []IntegrationOption
+		claims     func(claims map[string]interface{})
+		secret     string
+		header     string
+		wantStatus int
+		wantCode   string
+	}{
+		{"valid token", nil, nil, "secret", "", http.StatusOK, ""},
+		{"missing token", nil, nil, "", "", http.StatusUnauthorized, ErrorCodeMissingToken},
+		{"malformed token", nil, nil, "", "JWT not.a.jwt", http.StatusBadRequest, ErrorCodeInvalidToken},
+		{"other scheme", nil, nil, "", "Bearer token", http.StatusUnauthorized, ErrorCodeMissingToken},
+		{"bad signature", nil, nil, "other", "", http.StatusUnauthorized, ErrorCodeInvalidToken},
+		{"unknown issuer", nil, func(c map[string]interface{}) { c["iss"] = "unknown" }, "secret", "", http.StatusForbidden, ErrorCodeForbiddenTenant},
+		{"expired token", nil, func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, "secret", "", http.StatusUnauthorized, ErrorCodeTokenExpired},
+		{"other request", nil, func(c map[string]interface{}) { c["qsh"] = "0123" }, "secret", "", http.StatusBadRequest, ErrorCodeInvalidToken},
+		{"context qsh", nil, func(c map[string]interface{}) { c["qsh"] = ContextQSH }, "secret", "", http.StatusOK, ""},
+		{"wrong claim type", nil, func(c map[string]interface{}) { c["sub"] = []int{1} }, "secret", "", http.StatusBadRequest, ErrorCodeInvalidToken},
+		{"key lookup error", []IntegrationOption{WithKeyResolver(failingKeys)}, nil, "secret", "", http.StatusInternalServerError, ErrorCodeStoreError},
+	}
+	for _, tt := range tests {
+		for _, method := range []string{"GET", "POST"} {
+			t.Run(tt.name+" "+method, func(t *testing.T) {
+				var params []*SignedParams
+				_, handler := newSignedIntegration(&params, tt.opts...)
+				path := map[string]string{"GET": "/panel", "POST": "/hook"}[method]
+				r := httptest.NewRequest(method, path, nil)
+				switch {
+				case tt.header != "":
+					r.Header.Set("Authorization", tt.header)
+				case tt.secret != "":
+					claims := testClaims("oauth")
+					if tt.claims != nil {
+						tt.claims(claims)
+					}
+					signTestRequest(t, r, claims, tt.secret)
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+
+				if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantCode) {
+					t.Errorf("%s %s answered %d %s, want %d %s", method, path, w.Code, w.Body, tt.wantStatus, tt.wantCode)
+				}
+				if tt.wantStatus != http.StatusOK {
+					if len(params) != 0 {
+						t.Errorf("Rejected request reached the module")
+					}
+					return
+				}
+				if len(params) != 1 || params[0].Issuer != "oauth" || params[0].UserID != "1" || params[0].GroupID != 2 || params[0].RoomID != 3 {
+					t.Errorf("Module received %v, want the params of the token", params)
+				}
+			})
+		}
+	}
+}
+
+func TestRequireSignedParams_SignedRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		location   string
+		wantStatus int
+	}{
+		{"query of a GET", "GET", "/panel", "query", http.StatusOK},
+		{"query with other parameters", "GET", "/panel?theme=dark", "query", http.StatusOK},
+		{"form of a POST", "POST", "/hook", "form", http.StatusOK},
+		{"query of a POST", "POST", "/hook", "query", http.StatusOK},
+		{"query with tampered parameters", "GET", "/panel?theme=dark", "tampered", http.StatusBadRequest},
+		{"header before query", "GET", "/panel", "bad header", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params []*SignedParams
+			_, handler := newSignedIntegration(&params)
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			tok := testToken(t, r, testClaims("oauth"), "secret")
+			switch tt.location {
+			case "query", "tampered", "bad header":
+				r.URL.RawQuery = strings.TrimPrefix(r.URL.RawQuery+"&signed_request="+tok, "&")
+				if tt.location == "tampered" {
+					r.URL.RawQuery = strings.Replace(r.URL.RawQuery, "dark", "light", 1)
+				}
+				if tt.location == "bad header" {
+					r.Header.Set("Authorization", "JWT not.a.jwt")
+				}
+			case "form":
+				r = httptest.NewRequest(tt.method, tt.path, strings.NewReader("signed_request="+tok))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("%s %s answered %d %s, want %d", tt.method, r.URL, w.Code, w.Body, tt.wantStatus)
+			}
+			if reached := len(params) == 1; reached != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("Request reached the module is %v", reached)
+			}
+		})
+	}
+}
+
+func TestRequireSignedParams_Cookie(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []IntegrationOption
+		cookie     string
+		expired    bool
+		header     string
+		wantStatus int
+		wantCode   string
+	}{
+		{"cookie", []IntegrationOption{WithTokenCookie("hipchat_jwt")}, "hipchat_jwt", false, "", http.StatusOK, ""},
+		{"other cookie", []IntegrationOption{WithTokenCookie("hipchat_jwt")}, "session", false, "", http.StatusUnauthorized, ErrorCodeMissingToken},
+		{"cookies disabled", nil, "hipchat_jwt", false, "", http.StatusUnauthorized, ErrorCodeMissingToken},
+		{"expired cookie", []IntegrationOption{WithTokenCookie("hipchat_jwt")}, "hipchat_jwt", true, "", http.StatusUnauthorized, ErrorCodeTokenExpired},
+		{"header before cookie", []IntegrationOption{WithTokenCookie("hipchat_jwt")}, "hipchat_jwt", false, "JWT not.a.jwt", http.StatusBadRequest, ErrorCodeInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params []*SignedParams
+			_, handler := newSignedIntegration(&params, tt.opts...)
+			r := httptest.NewRequest("GET", "/panel", nil)
+			claims := testClaims("oauth")
+			claims["qsh"] = ContextQSH
+			if tt.expired {
+				claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			}
+			r.AddCookie(&http.Cookie{Name: tt.cookie, Value: testToken(t, r, claims, "secret")})
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantCode) {
+				t.Errorf("GET /panel answered %d %s, want %d %s", w.Code, w.Body, tt.wantStatus, tt.wantCode)
+			}
+			if reached := len(params) == 1; reached != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("Request reached the module is %v", reached)
+			}
+		})
+	}
+}
+
+func TestRequireSignedParams_Memoized(t *testing.T) {
+	lookups := 0
+	keys := KeyResolverFunc(func(string) ([][]byte, error) {
+		lookups++
+		return [][]byte{[]byte("secret")}, nil
+	})
+	var params []*SignedParams
+	i, _ := newSignedIntegration(&params, WithKeyResolver(keys), WithReplayGuard(NewMemoryReplayGuard()))
+	var parsed *SignedParams
+	var parseErr error
+	i.AddWebPanel("/memo", WebPanelModule{Key: "memo"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parsed, parseErr = i.ParseSignedParams(r)
+		fromContext, _ := SignedParamsFromContext(r.Context())
+		if parsed != fromContext {
+			t.Errorf("ParseSignedParams returned %v, want the params of the context %v", parsed, fromContext)
+		}
+	}))
+	r := httptest.NewRequest("GET", "/memo", nil)
+	claims := testClaims("oauth")
+	claims["jti"] = "a"
+	signTestRequest(t, r, claims, "secret")
+	w := httptest.NewRecorder()
+	routesHandler(i).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /memo answered %d %s, want %d", w.Code, w.Body, http.StatusOK)
+	}
+	// Parsing the token again would have found it replayed.
+	if parseErr != nil || parsed == nil || parsed.TokenID != "a" {
+		t.Errorf("ParseSignedParams in the handler returned %v, %v", parsed, parseErr)
+	}
+	if lookups != 1 {
+		t.Errorf("Keys were looked up %d times, want 1", lookups)
+	}
+}
+
+func TestRequireSignedParams_PayloadTooLarge(t *testing.T) {
+	var params []*SignedParams
+	_, handler := newSignedIntegration(&params, WithMaxBodyBytes(64))
+	r := httptest.NewRequest("POST", "/hook", nil)
+	tok := testToken(t, r, testClaims("oauth"), "secret")
+	r = httptest.NewRequest("POST", "/hook", strings.NewReader("signed_request="+tok))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), ErrorCodePayloadTooLarge) {
+		t.Errorf("POST /hook answered %d %s, want %d %s", w.Code, w.Body, http.StatusRequestEntityTooLarge, ErrorCodePayloadTooLarge)
+	}
+	if len(params) != 0 {
+		t.Errorf("Oversized request reached the module")
+	}
+}
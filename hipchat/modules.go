@@ -0,0 +1,98 @@
+package hipchat
+
+import (
+	"net/http"
+	"strings"
+)
+
+// moduleRoute is a route of an Integration serving a module of its descriptor.
+type moduleRoute struct {
+	method  string
+	path    string
+	handler http.Handler
+	// declare adds the module, served at url, to the descriptor, if the
+	// route declares one.
+	declare func(b *DescriptorBuilder, url string)
+}
+
+func (m moduleRoute) route() Route {
+	return Route{Method: m.method, Pattern: m.path, Handler: m.handler.ServeHTTP}
+}
+
+// addModuleRoute serves m along with the lifecycle endpoints.
+func (i *Integration) addModuleRoute(m moduleRoute) {
+	m.path = "/" + strings.TrimPrefix(m.path, "/")
+	i.moduleRoutes = append(i.moduleRoutes, m)
+	route := m.route()
+	i.router.Path(route.Pattern).Methods(route.Method).HandlerFunc(route.Handler)
+}
+
+// AddWebPanel serves a web panel at path, relative to the routes of the
+// integration, and declares it in the descriptor with the URL derived from
+// path. handler is only called for the requests signed by HipChat, see
+// RequireSignedParams, and gets the SignedParams of the user viewing the
+// panel from SignedParamsFromContext.
+//
+// Modules must be added before the handler of the integration serves
+// requests, and before Routes is called when registering them on another
+// router.
+func (i *Integration) AddWebPanel(path string, panel WebPanelModule, handler http.Handler) {
+	i.addModuleRoute(moduleRoute{
+		method:  "GET",
+		path:    path,
+		handler: i.RequireSignedParams(handler),
+		declare: func(b *DescriptorBuilder, url string) {
+			panel.URL = url
+			b.AddWebPanel(panel)
+		},
+	})
+}
+
+// AddWebhook serves a webhook at path, relative to the routes of the
+// integration, and declares it in the descriptor with the URL derived from
+// path, e.g.
+//
+//	i.AddWebhook("/hello", hipchat.WebhookModule{
+//		Event:          hipchat.WebhookEventRoomMessage,
+//		Pattern:        "^/hello",
+//		Authentication: hipchat.WebhookAuthenticationJWT,
+//	}, handler)
+//
+// When its Authentication is WebhookAuthenticationJWT, handler is only called
+// for the requests signed by HipChat, as with RequireSignedParams. Like web
+// panels, webhooks must be added before the integration serves requests.
+func (i *Integration) AddWebhook(path string, webhook WebhookModule, handler http.Handler) {
+	if webhook.Authentication == WebhookAuthenticationJWT {
+		handler = i.RequireSignedParams(handler)
+	}
+	i.addModuleRoute(moduleRoute{
+		method:  "POST",
+		path:    path,
+		handler: handler,
+		declare: func(b *DescriptorBuilder, url string) {
+			webhook.URL = url
+			b.AddWebhook(webhook)
+		},
+	})
+}
+
+// AddConfigurationPage serves the configuration page of the add-on at path,
+// relative to the routes of the integration, and declares it in the
+// descriptor. handler is called for GET and POST requests, so that the page
+// can submit its form to itself, and only for those signed by HipChat, see
+// RequireSignedParams. POST requests must also carry the CSRF token of the
+// user, see CSRFField and RequireCSRF, so the integration needs a session
+// secret. handler typically reads and saves the settings of the installation
+// with Settings and SaveSettings. Like web panels, the page must be added
+// before the integration serves requests.
+func (i *Integration) AddConfigurationPage(path string, handler http.Handler) {
+	i.addModuleRoute(moduleRoute{
+		method:  "GET",
+		path:    path,
+		handler: i.RequireSignedParams(handler),
+		declare: func(b *DescriptorBuilder, url string) {
+			b.Configurable(url)
+		},
+	})
+	i.addModuleRoute(moduleRoute{method: "POST", path: path, handler: i.RequireSignedParams(i.RequireCSRF(handler))})
+}
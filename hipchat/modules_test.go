@@ -0,0 +1,190 @@
+package hipchat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIntegrationAddWebPanel(t *testing.T) {
+	i := NewIntegration(nil,
+		WithAddOn("com.example.addon", "Example", ""),
+		WithBaseURL("https://addon.example.com"),
+		WithRoutePrefix("/hipchat"))
+	i.AddWebPanel("panel", WebPanelModule{Key: "p", Name: Name{Value: "Panel"}}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Unsigned request reached the web panel")
+	}))
+
+	d, err := i.Descriptor()
+	if err != nil {
+		t.Fatalf("Descriptor returns an error %v", err)
+	}
+	want := `[{
+		"key": "p",
+		"name": {"value": "Panel"},
+		"url": "https://addon.example.com/hipchat/panel",
+		"location": "hipchat.sidebar.right"
+	}]`
+	testJSONEqual(t, d.Capabilities.WebPanels, want)
+
+	w := httptest.NewRecorder()
+	i.GetHandler().ServeHTTP(w, httptest.NewRequest("GET", "/hipchat/panel", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Unsigned web panel request answered %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	routes := i.Routes()
+	if last := routes[len(routes)-1]; last.Pattern != "/panel" || last.Method != "GET" {
+		t.Errorf("Routes ends with %v %v, want GET /panel", last.Method, last.Pattern)
+	}
+}
+
+func TestIntegrationAddWebhook(t *testing.T) {
+	i := NewIntegration(nil,
+		WithAddOn("com.example.addon", "Example", ""),
+		WithBaseURL("https://addon.example.com"),
+		WithRoutePrefix("/hipchat"))
+	i.AddWebhook("/hello", WebhookModule{
+		Key:            "hello",
+		Event:          WebhookEventRoomMessage,
+		Pattern:        "^/hello",
+		Authentication: WebhookAuthenticationJWT,
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Unsigned request reached the webhook")
+	}))
+	entered := false
+	i.AddWebhook("/enter", WebhookModule{Event: WebhookEventRoomEnter}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered = true
+	}))
+
+	d, err := i.Descriptor()
+	if err != nil {
+		t.Fatalf("Descriptor returns an error %v", err)
+	}
+	want := `[{
+		"key": "hello",
+		"event": "room_message",
+		"pattern": "^/hello",
+		"authentication": "jwt",
+		"url": "https://addon.example.com/hipchat/hello"
+	}, {
+		"event": "room_enter",
+		"url": "https://addon.example.com/hipchat/enter"
+	}]`
+	testJSONEqual(t, d.Capabilities.Webhooks, want)
+
+	w := httptest.NewRecorder()
+	i.GetHandler().ServeHTTP(w, httptest.NewRequest("POST", "/hipchat/hello", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Unsigned webhook request answered %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	i.GetHandler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/hipchat/enter", nil))
+	if !entered {
+		t.Errorf("Webhook without authentication was not called")
+	}
+}
+
+func TestIntegrationAddConfigurationPage(t *testing.T) {
+	i := NewIntegration(nil,
+		WithAddOn("com.example.addon", "Example", ""),
+		WithBaseURL("https://addon.example.com"))
+	i.AddConfigurationPage("/configure", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Unsigned request reached the configuration page")
+	}))
+
+	d, err := i.Descriptor()
+	if err != nil {
+		t.Fatalf("Descriptor returns an error %v", err)
+	}
+	testJSONEqual(t, d.Capabilities.Configurable, `{"url": "https://addon.example.com/configure"}`)
+
+	for _, method := range []string{"GET", "POST"} {
+		w := httptest.NewRecorder()
+		i.GetHandler().ServeHTTP(w, httptest.NewRequest(method, "/configure", nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Unsigned %s configuration request answered %d, want %d", method, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestIntegrationAddAction(t *testing.T) {
+	i := NewIntegration(nil,
+		WithAddOn("com.example.addon", "Example", ""),
+		WithBaseURL("https://addon.example.com"))
+	i.AddAction("/ticket", ActionModule{
+		Key:      "ticket",
+		Name:     Name{Value: "Create ticket"},
+		Target:   "ticket-dialog",
+		Location: ActionLocationMessage,
+	}, func(w http.ResponseWriter, r *http.Request, params *SignedParams, payload *ActionPayload) {
+		t.Error("Unsigned request reached the action")
+	})
+
+	d, err := i.Descriptor()
+	if err != nil {
+		t.Fatalf("Descriptor returns an error %v", err)
+	}
+	want := `[{
+		"key": "ticket",
+		"name": {"value": "Create ticket"},
+		"target": "ticket-dialog",
+		"location": "hipchat.message.action"
+	}]`
+	testJSONEqual(t, d.Capabilities.Actions, want)
+
+	w := httptest.NewRecorder()
+	i.GetHandler().ServeHTTP(w, httptest.NewRequest("POST", "/ticket", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Unsigned action request answered %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestIntegrationAddConfigurationPage_CSRF(t *testing.T) {
+	i := NewIntegration(nil,
+		WithAddOn("com.example.addon", "Example", ""),
+		WithBaseURL("https://addon.example.com"),
+		WithKeyResolver(testKeys),
+		WithSessionSecret([]byte("session")))
+	saved := 0
+	i.AddConfigurationPage("/configure", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			saved++
+		}
+	}))
+	token, err := i.CSRFToken(&SignedParams{Issuer: "oauth", UserID: "1", RoomID: 3})
+	if err != nil {
+		t.Fatalf("CSRFToken returned an error %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		csrf       string
+		wantStatus int
+		wantSaved  int
+	}{
+		{"GET without token", "GET", "", http.StatusOK, 0},
+		{"POST without token", "POST", "", http.StatusForbidden, 0},
+		{"POST with bad token", "POST", "1.bad", http.StatusForbidden, 0},
+		{"POST with token", "POST", token, http.StatusOK, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved = 0
+			r := httptest.NewRequest(tt.method, "/configure", nil)
+			if tt.csrf != "" {
+				r.Header.Set(CSRFHeaderName, tt.csrf)
+			}
+			signTestRequest(t, r, testClaims("oauth"), "secret")
+			w := httptest.NewRecorder()
+			i.GetHandler().ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("%s answered %d %s, want %d", tt.name, w.Code, w.Body, tt.wantStatus)
+			}
+			if saved != tt.wantSaved {
+				t.Errorf("Handler saved %d times, want %d", saved, tt.wantSaved)
+			}
+		})
+	}
+}
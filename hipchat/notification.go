@@ -0,0 +1,88 @@
+package hipchat
+
+import (
+	"context"
+	"net/http"
+)
+
+// Background colors of notifications.
+const (
+	ColorYellow = "yellow"
+	ColorGreen  = "green"
+	ColorRed    = "red"
+	ColorPurple = "purple"
+	ColorGray   = "gray"
+	ColorRandom = "random"
+)
+
+// NotificationOptions specifies the optional parameters of a notification.
+// The zero value sends a yellow notification that does not notify the users
+// of the room, in the format detected from the message.
+type NotificationOptions struct {
+	// Color is the background color of the notification, one of the Color
+	// constants. Empty means ColorYellow.
+	Color string
+	// Notify makes the notification trigger a user notification, e.g. a
+	// sound or a popup.
+	Notify bool
+	// From is a label shown next to the name of the add-on.
+	From string
+	// MessageFormat is MessageFormatText or MessageFormatHTML. Empty means
+	// the format detected by DetectMessageFormat.
+	MessageFormat string
+	// AttachTo is the id of the message the notification is attached to.
+	AttachTo string
+	// Card is displayed instead of the message by the clients that support
+	// cards, the message being the fallback of the others.
+	Card *Card
+}
+
+// NewNotificationRequest returns the request of a notification with the given
+// message and options, which may be nil.
+func NewNotificationRequest(message string, opts *NotificationOptions) *NotificationRequest {
+	if opts == nil {
+		opts = &NotificationOptions{}
+	}
+	n := &NotificationRequest{
+		Message:       message,
+		Color:         opts.Color,
+		Notify:        opts.Notify,
+		From:          opts.From,
+		MessageFormat: opts.MessageFormat,
+		AttachTo:      opts.AttachTo,
+		Card:          opts.Card,
+	}
+	if n.MessageFormat == "" {
+		n.MessageFormat = DetectMessageFormat(message)
+	}
+	return n
+}
+
+// Attach sends notifReq, typically a card notification, attached to the
+// earlier message of the room with the given id, so that it is displayed as a
+// reply threaded under it. notifReq itself is not modified.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/send_room_notification
+func (r *RoomService) Attach(id, messageID string, notifReq *NotificationRequest) (*http.Response, error) {
+	return r.AttachContext(context.Background(), id, messageID, notifReq)
+}
+
+// AttachContext is like Attach, with ctx controlling the request.
+func (r *RoomService) AttachContext(ctx context.Context, id, messageID string, notifReq *NotificationRequest) (*http.Response, error) {
+	attached := *notifReq
+	attached.AttachTo = messageID
+	return r.NotificationContext(ctx, id, &attached)
+}
+
+// Notify sends a notification with the given message and options, which may
+// be nil, to the room.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/send_room_notification
+func (r *RoomService) Notify(id, message string, opts *NotificationOptions) (*http.Response, error) {
+	return r.NotifyContext(context.Background(), id, message, opts)
+}
+
+// NotifyContext is like Notify, with ctx controlling the request.
+func (r *RoomService) NotifyContext(ctx context.Context, id, message string, opts *NotificationOptions) (*http.Response, error) {
+	return r.NotificationContext(ctx, id, NewNotificationRequest(message, opts))
+}
@@ -0,0 +1,71 @@
+package hipchat
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestNewNotificationRequest(t *testing.T) {
+	got := NewNotificationRequest("<b>done</b>", nil)
+	want := &NotificationRequest{Message: "<b>done</b>", MessageFormat: MessageFormatHTML}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewNotificationRequest returned %+v, want %+v", got, want)
+	}
+}
+
+func TestRoomNotify(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &NotificationRequest{
+		Message:       "done",
+		Color:         ColorGreen,
+		Notify:        true,
+		From:          "CI",
+		MessageFormat: MessageFormatText,
+		AttachTo:      "m1",
+	}
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		v := new(NotificationRequest)
+		json.NewDecoder(r.Body).Decode(v)
+
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("Request body %+v, want %+v", v, want)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	opts := &NotificationOptions{Color: ColorGreen, Notify: true, From: "CI", AttachTo: "m1"}
+	if _, err := client.Room.Notify("1", "done", opts); err != nil {
+		t.Fatalf("Room.Notify returns an error %v", err)
+	}
+}
+
+func TestRoomAttach(t *testing.T) {
+	setup()
+	defer teardown()
+
+	card, _ := NewCard(CardStyleApplication, "Deployed").Build()
+	args := &NotificationRequest{Message: "Deployed", Card: card}
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		v := new(NotificationRequest)
+		json.NewDecoder(r.Body).Decode(v)
+
+		want := &NotificationRequest{Message: "Deployed", Card: card, AttachTo: "m1"}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("Request body %+v, want %+v", v, want)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if _, err := client.Room.Attach("1", "m1", args); err != nil {
+		t.Fatalf("Room.Attach returns an error %v", err)
+	}
+	if args.AttachTo != "" {
+		t.Errorf("Room.Attach modified the request")
+	}
+}
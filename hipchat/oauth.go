@@ -1,6 +1,7 @@
 package hipchat
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -30,10 +31,21 @@ func (t *OAuthAccessToken) CreateClient() *Client {
 	return NewClient(t.AccessToken)
 }
 
+// CreateClientWithHTTPClient creates a new client from this OAuth token that
+// performs its requests with httpClient.
+func (t *OAuthAccessToken) CreateClientWithHTTPClient(httpClient *http.Client) *Client {
+	return NewClientWithHTTPClient(t.AccessToken, httpClient)
+}
+
 // GenerateToken returns back an access token for a given integration's client ID and client secret
 //
 //  HipChat API documentation: https://www.hipchat.com/docs/apiv2/method/generate_token
 func (c *Client) GenerateToken(credentials ClientCredentials, scopes []string) (*OAuthAccessToken, *http.Response, error) {
+	return c.GenerateTokenContext(context.Background(), credentials, scopes)
+}
+
+// GenerateTokenContext is like GenerateToken, with ctx controlling the request.
+func (c *Client) GenerateTokenContext(ctx context.Context, credentials ClientCredentials, scopes []string) (*OAuthAccessToken, *http.Response, error) {
 	rel, err := url.Parse("oauth/token")
 
 	if err != nil {
@@ -53,8 +65,7 @@ func (c *Client) GenerateToken(credentials ClientCredentials, scopes []string) (
 	req.SetBasicAuth(credentials.ClientID, credentials.ClientSecret)
 	req.Header.Set("Content-type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.client.Do(req.WithContext(ctx))
 
 	if err != nil {
 		return nil, resp, err
@@ -78,6 +89,67 @@ func (c *Client) GenerateToken(credentials ClientCredentials, scopes []string) (
 	return &token, resp, nil
 }
 
+// OAuthSession represents the session of an OAuth access token: its scopes
+// and who it was issued to.
+type OAuthSession struct {
+	AccessToken string              `json:"access_token"`
+	ExpiresIn   int                 `json:"expires_in"`
+	Scopes      []string            `json:"scopes"`
+	Owner       *User               `json:"owner,omitempty"`
+	Client      *OAuthSessionClient `json:"client,omitempty"`
+}
+
+// OAuthSessionClient represents the OAuth client, e.g. the installation of an
+// add-on, an access token was issued to.
+type OAuthSessionClient struct {
+	ID    string `json:"id"`
+	Room  *Room  `json:"room,omitempty"`
+	Group *Group `json:"group,omitempty"`
+}
+
+// MissingScopes returns the scopes, among the given ones, the session does not
+// have. Add-ons can use it to detect tokens issued before their descriptor
+// requested new scopes.
+func (s *OAuthSession) MissingScopes(scopes ...string) []string {
+	granted := make(map[string]bool, len(s.Scopes))
+	for _, scope := range s.Scopes {
+		granted[scope] = true
+	}
+	var missing []string
+	for _, scope := range scopes {
+		if !granted[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// GetSession returns the session of an access token, or of the token of the
+// client if accessToken is empty.
+//
+// HipChat API documentation: https://www.hipchat.com/docs/apiv2/method/get_session
+func (c *Client) GetSession(accessToken string) (*OAuthSession, *http.Response, error) {
+	return c.GetSessionContext(context.Background(), accessToken)
+}
+
+// GetSessionContext is like GetSession, with ctx controlling the request.
+func (c *Client) GetSessionContext(ctx context.Context, accessToken string) (*OAuthSession, *http.Response, error) {
+	if accessToken == "" {
+		accessToken = c.authToken
+	}
+	req, err := c.NewRequest("GET", fmt.Sprintf("oauth/token/%s", url.PathEscape(accessToken)), nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session := new(OAuthSession)
+	resp, err := c.DoContext(ctx, req, session)
+	if err != nil {
+		return nil, resp, err
+	}
+	return session, resp, nil
+}
+
 const (
 	// ScopeAdminGroup - Perform group administrative tasks
 	ScopeAdminGroup = "admin_group"
@@ -77,3 +77,66 @@ func TestCreateClientFromAccessToken(t *testing.T) {
 		)
 	}
 }
+
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestGenerateToken_HTTPClient(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token":"t"}`)
+	})
+	transport := &countingTransport{}
+	client.SetHTTPClient(&http.Client{Transport: transport})
+
+	_, _, err := client.GenerateToken(ClientCredentials{ClientID: "id", ClientSecret: "secret"}, nil)
+	if err != nil {
+		t.Fatalf("Client.GenerateToken returns an error %v", err)
+	}
+	if transport.requests != 1 {
+		t.Errorf("Client.GenerateToken made %d requests with the HTTP client, want 1", transport.requests)
+	}
+}
+
+func TestGetSession(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/oauth/token/AuthToken", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprintf(w, `{
+			"access_token": "AuthToken",
+			"expires_in": 3599,
+			"scopes": ["send_notification", "view_room"],
+			"owner": {"id": 1, "name": "n"},
+			"client": {"id": "c", "room": {"id": 2}}
+		}`)
+	})
+	want := &OAuthSession{
+		AccessToken: "AuthToken",
+		ExpiresIn:   3599,
+		Scopes:      []string{ScopeSendNotification, ScopeViewRoom},
+		Owner:       &User{ID: 1, Name: "n"},
+		Client:      &OAuthSessionClient{ID: "c", Room: &Room{ID: 2}},
+	}
+
+	session, _, err := client.GetSession("")
+	if err != nil {
+		t.Fatalf("Client.GetSession returns an error %v", err)
+	}
+	if !reflect.DeepEqual(session, want) {
+		t.Errorf("Client.GetSession returned %+v, want %+v", session, want)
+	}
+	missing := session.MissingScopes(ScopeSendNotification, ScopeAdminRoom)
+	if !reflect.DeepEqual(missing, []string{ScopeAdminRoom}) {
+		t.Errorf("MissingScopes returned %v, want [%v]", missing, ScopeAdminRoom)
+	}
+}
@@ -0,0 +1,154 @@
+package hipchat
+
+import (
+	"crypto/tls"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// IntegrationOption configures an Integration created by NewIntegration.
+type IntegrationOption func(*Integration)
+
+// WithScopes sets the scopes requested for the tokens of installations.
+func WithScopes(scopes ...string) IntegrationOption {
+	return func(i *Integration) {
+		i.scopes = scopes
+	}
+}
+
+// WithBaseURL sets the public base URL the integration is served from, used to
+// derive the URLs HipChat calls back.
+func WithBaseURL(baseURL string) IntegrationOption {
+	return func(i *Integration) {
+		i.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithForwardedProto makes the integration trust the X-Forwarded-Proto header
+// of the requests to derive its base URL when none is set with WithBaseURL,
+// e.g. behind a reverse proxy terminating TLS. Only use it when that proxy
+// sets or strips the header.
+func WithForwardedProto() IntegrationOption {
+	return func(i *Integration) {
+		i.forwardedProto = true
+	}
+}
+
+// WithAPIBaseURL sets the base URL of the HipChat API the integration requests
+// tokens from and pushes updates to, e.g. "https://hipchat.example.com/v2/" for
+// a HipChat Server. By default the HipChat cloud API is used. See also
+// WithHipChatHosts.
+func WithAPIBaseURL(baseURL string) IntegrationOption {
+	return func(i *Integration) {
+		i.apiBaseURL = baseURL
+	}
+}
+
+// WithRateLimiter paces the API requests of the integration with limiter,
+// shared by the clients of all installations.
+func WithRateLimiter(limiter *RateLimiter) IntegrationOption {
+	return func(i *Integration) {
+		i.rateLimiter = limiter
+	}
+}
+
+// WithLogger sets the logger used to report the integration's activity.
+// By default messages are written to standard error.
+func WithLogger(logger *log.Logger) IntegrationOption {
+	return func(i *Integration) {
+		i.logger = logger
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for the integration's outgoing
+// requests: token requests, capabilities fetches, health checks and API calls.
+// If a nil httpClient is provided, http.DefaultClient will be used.
+func WithHTTPClient(httpClient *http.Client) IntegrationOption {
+	return func(i *Integration) {
+		if httpClient == nil {
+			httpClient = http.DefaultClient
+		}
+		i.httpClient = httpClient
+	}
+}
+
+// WithTLSConfig makes the outgoing requests of the integration use config,
+// e.g. one returned by NewTLSConfig or PinPublicKeys. It applies to the HTTP
+// client set by WithHTTPClient, whose transport must then be an
+// *http.Transport: NewIntegration panics otherwise.
+func WithTLSConfig(config *tls.Config) IntegrationOption {
+	return func(i *Integration) {
+		i.tlsConfig = config
+	}
+}
+
+// WithProxy sends the outgoing requests of the integration through the proxy
+// at proxyURL, e.g. "http://proxy.example.com:3128". Like WithTLSConfig, it
+// requires the HTTP client of the integration to use an *http.Transport. By
+// default, the proxy is given by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables.
+func WithProxy(proxyURL *url.URL) IntegrationOption {
+	return func(i *Integration) {
+		i.proxyURL = proxyURL
+	}
+}
+
+// WithRoutePrefix serves the lifecycle endpoints of GetHandler under prefix,
+// e.g. "/hipchat" serves "/hipchat/installed".
+func WithRoutePrefix(prefix string) IntegrationOption {
+	return func(i *Integration) {
+		i.routePrefix = "/" + strings.Trim(prefix, "/")
+	}
+}
+
+// WithTokenCache sets the cache used for the access tokens of installations.
+// By default tokens are cached in memory.
+func WithTokenCache(cache TokenCache) IntegrationOption {
+	return func(i *Integration) {
+		i.tokens = cache
+	}
+}
+
+// WithStrictValidation rejects installation payloads with 400 Bad Request when they
+// are missing any of the fields required to complete the installation, or when
+// their capabilitiesUrl is not served by one of the HipChat hosts.
+func WithStrictValidation() IntegrationOption {
+	return func(i *Integration) {
+		i.strict = true
+	}
+}
+
+// WithMaxBodyBytes limits the size of incoming request bodies.
+func WithMaxBodyBytes(n int64) IntegrationOption {
+	return func(i *Integration) {
+		i.bodyLimit = n
+	}
+}
+
+// WithTokenCookie makes ParseSignedParams also read the JWT from the cookie
+// with the given name, when the request carries it neither in the
+// Authorization header nor in a signed_request parameter. Frontends often keep
+// their session token in such a cookie between iframe navigations.
+func WithTokenCookie(name string) IntegrationOption {
+	return func(i *Integration) {
+		i.tokenCookie = name
+	}
+}
+
+// WithSynchronousCallbacks completes installations and runs callbacks before
+// responding, waiting up to timeout for each callback. Zero means no timeout.
+// Installations that cannot be completed, because no token could be obtained,
+// are undone and answered with 503 Service Unavailable so that HipChat retries
+// them.
+//
+// The timeout only stops the waiting: a callback that overruns it keeps
+// running in the background, and Wait and Shutdown still wait for it.
+func WithSynchronousCallbacks(timeout time.Duration) IntegrationOption {
+	return func(i *Integration) {
+		i.synchronous = true
+		i.callbackTimeout = timeout
+	}
+}
@@ -13,4 +13,26 @@ CREATE TABLE installation (
 DROP INDEX IF EXISTS installation_uniq CASCADE;
 CREATE UNIQUE INDEX installation_uniq ON installation (
     groupId, roomId
-);
\ No newline at end of file
+);
+
+DROP INDEX IF EXISTS installation_global_uniq CASCADE;
+CREATE UNIQUE INDEX installation_global_uniq ON installation (
+    groupId
+) WHERE roomId IS NULL;
+
+DROP TABLE IF EXISTS installation_event CASCADE;
+CREATE TABLE installation_event (
+    id integer PRIMARY KEY DEFAULT nextval('serial'),
+    type varchar(32) NOT NULL,
+    time timestamp with time zone NOT NULL,
+    oauthId varchar(255) NOT NULL,
+    groupId integer NOT NULL,
+    roomId integer,
+    source varchar(255) NOT NULL,
+    result varchar(64) NOT NULL
+);
+
+DROP INDEX IF EXISTS installation_event_group CASCADE;
+CREATE INDEX installation_event_group ON installation_event (
+    groupId, time
+);
@@ -0,0 +1,26 @@
+package hipchat
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// SetProxy sends the requests of the client through the proxy at proxyURL,
+// e.g. "http://proxy.example.com:3128". A nil proxyURL restores the default,
+// which is to use the proxy given by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables. As with SetTLSConfig, the HTTP client of the client
+// is replaced by a copy, and its transport must be an *http.Transport.
+func (c *Client) SetProxy(proxyURL *url.URL) error {
+	proxy := http.ProxyFromEnvironment
+	if proxyURL != nil {
+		proxy = http.ProxyURL(proxyURL)
+	}
+	httpClient, err := withTransport(c.client, func(t *http.Transport) {
+		t.Proxy = proxy
+	})
+	if err != nil {
+		return err
+	}
+	c.client = httpClient
+	return nil
+}
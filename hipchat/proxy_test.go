@@ -0,0 +1,48 @@
+package hipchat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClientSetProxy(t *testing.T) {
+	proxied := false
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = true
+		if r.URL.String() != "http://api.hipchat.invalid/v2/room/1/notification" {
+			t.Errorf("Proxy received a request for %v", r.URL)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	c := NewClient("AuthToken")
+	c.SetBaseURL("http://api.hipchat.invalid/v2/")
+	proxyURL, _ := url.Parse(proxy.URL)
+	if err := c.SetProxy(proxyURL); err != nil {
+		t.Fatalf("SetProxy returns an error %v", err)
+	}
+
+	if _, err := c.Room.Notification("1", &NotificationRequest{Message: "m"}); err != nil {
+		t.Fatalf("Room.Notification returns an error %v", err)
+	}
+	if !proxied {
+		t.Error("Room.Notification did not go through the proxy")
+	}
+}
+
+func TestWithProxy(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.example.com:3128")
+	i := NewIntegration(nil, WithProxy(proxyURL))
+
+	transport, ok := i.httpClient.Transport.(*http.Transport)
+	if !ok || transport.Proxy == nil {
+		t.Fatal("WithProxy did not configure the transport of the integration")
+	}
+	req, _ := http.NewRequest("GET", "https://api.hipchat.com/v2/capabilities", nil)
+	if got, _ := transport.Proxy(req); got == nil || got.String() != proxyURL.String() {
+		t.Errorf("Transport proxy is %v, want %v", got, proxyURL)
+	}
+}
@@ -0,0 +1,96 @@
+package hipchat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ContextQSH is the qsh value of tokens that are not bound to a particular
+// request, such as those handed to add-on iframes. It is never checked against
+// the request.
+const ContextQSH = "context-qsh"
+
+// WithoutQSHValidation disables the verification of the qsh claim of incoming
+// JWTs. Use it when tokens are legitimately replayed against other URLs than
+// the one they were issued for, e.g. when an iframe forwards its token to the
+// add-on backend.
+func WithoutQSHValidation() IntegrationOption {
+	return func(i *Integration) {
+		i.skipQSH = true
+	}
+}
+
+// CanonicalRequest returns the canonical form of a request hashed into the qsh
+// claim of a JWT: the upper-cased method, the path relative to basePath and the
+// sorted query parameters, separated by "&". The jwt and signed_request
+// parameters, which carry the token itself, are left out.
+func CanonicalRequest(method string, u *url.URL, basePath string) string {
+	p := u.EscapedPath()
+	basePath = strings.TrimSuffix(basePath, "/")
+	if basePath != "" && strings.HasPrefix(p, basePath) {
+		p = p[len(basePath):]
+	}
+	if len(p) > 1 {
+		p = strings.TrimSuffix(p, "/")
+	}
+	if p == "" {
+		p = "/"
+	}
+	p = strings.Replace(p, "&", "%26", -1)
+
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		if k == "jwt" || k == "signed_request" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	params := make([]string, len(keys))
+	for n, k := range keys {
+		values := make([]string, len(query[k]))
+		for m, v := range query[k] {
+			values[m] = percentEncode(v)
+		}
+		sort.Strings(values)
+		params[n] = percentEncode(k) + "=" + strings.Join(values, ",")
+	}
+
+	return strings.ToUpper(method) + "&" + p + "&" + strings.Join(params, "&")
+}
+
+// QueryStringHash returns the qsh claim value for a request, the hex-encoded
+// SHA-256 hash of its CanonicalRequest.
+func QueryStringHash(method string, u *url.URL, basePath string) string {
+	sum := sha256.Sum256([]byte(CanonicalRequest(method, u, basePath)))
+	return hex.EncodeToString(sum[:])
+}
+
+// percentEncode escapes s as specified by RFC 3986.
+func percentEncode(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+// checkQSH verifies that the qsh claim of a token, if any, matches the request.
+func (i *Integration) checkQSH(req *http.Request, claims *Claims) error {
+	qsh := claims.QSH
+	if i.skipQSH || qsh == "" || qsh == ContextQSH {
+		return nil
+	}
+
+	var basePath string
+	if u, err := url.Parse(i.baseURL); err == nil {
+		basePath = u.Path
+	}
+	if qsh != QueryStringHash(req.Method, req.URL, basePath) {
+		return fmt.Errorf("%w: qsh claim does not match the request", ErrInvalidClaims)
+	}
+	return nil
+}
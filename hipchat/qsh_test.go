@@ -0,0 +1,34 @@
+package hipchat
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCanonicalRequest(t *testing.T) {
+	tests := []struct {
+		method   string
+		rawurl   string
+		basePath string
+		want     string
+	}{
+		{"get", "https://example.com/", "", "GET&/&"},
+		{"GET", "https://example.com/addon/glance/?b=2&a=1&jwt=x", "/addon", "GET&/glance&a=1&b=2"},
+		{"POST", "https://example.com/dialog?z=b&z=a&q=a%20b*", "", "POST&/dialog&q=a%20b%2A&z=a,b"},
+		{"GET", "https://example.com/a&b?signed_request=x", "", "GET&/a%26b&"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.rawurl)
+		if got := CanonicalRequest(tt.method, u, tt.basePath); got != tt.want {
+			t.Errorf("CanonicalRequest(%q, %q, %q) returned %q, want %q", tt.method, tt.rawurl, tt.basePath, got, tt.want)
+		}
+	}
+}
+
+func TestQueryStringHash(t *testing.T) {
+	u, _ := url.Parse("https://example.com/")
+	want := "c88caad15a1c1a900b8ac08aa9686f4e8184539bea1deda36e2f649430df3239"
+	if got := QueryStringHash("GET", u, ""); got != want {
+		t.Errorf("QueryStringHash returned %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package hipchat
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultQueueWorkers is the number of workers of a NotificationQueue created
+// with zero workers.
+const DefaultQueueWorkers = 4
+
+// ErrQueueClosed is returned by SendAsync once the queue is closed.
+var ErrQueueClosed = errors.New("notification queue is closed")
+
+// NotificationQueue sends room notifications in the background, so that
+// webhook handlers can enqueue their replies and return immediately.
+// Notifications to the same room are sent one at a time, in the order they
+// were enqueued, while different rooms are served concurrently. Failed sends
+// are retried with backoff.
+type NotificationQueue struct {
+	client *Client
+	retry  RetryPolicy
+	// ctx is canceled when Close gives up waiting, aborting the sends.
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	cond   *sync.Cond
+	// pending holds the notifications waiting to be sent to each room, and
+	// ready the rooms with pending notifications no worker is sending.
+	pending map[string][]*queuedNotification
+	ready   []string
+	closed  bool
+	done    chan struct{}
+	workers sync.WaitGroup
+}
+
+type queuedNotification struct {
+	req  *NotificationRequest
+	done func(error)
+}
+
+// NewNotificationQueue starts a queue sending notifications with client, with
+// the given number of workers or DefaultQueueWorkers if zero. Failed sends
+// are retried according to retry, or with the defaults of RetryPolicy if it is
+// nil. Unlike the retries of Client, notifications are retried on 5xx
+// responses and network errors, and are then sent at least once. The retry
+// policy of client is not applied on top of retry.
+func NewNotificationQueue(client *Client, workers int, retry *RetryPolicy) *NotificationQueue {
+	if workers <= 0 {
+		workers = DefaultQueueWorkers
+	}
+	q := &NotificationQueue{
+		client:  client,
+		pending: make(map[string][]*queuedNotification),
+		done:    make(chan struct{}),
+	}
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	if retry != nil {
+		q.retry = *retry
+	}
+	q.retry.RetryNonIdempotent = true
+	q.cond = sync.NewCond(&q.mu)
+
+	q.workers.Add(workers)
+	for n := 0; n < workers; n++ {
+		go q.work()
+	}
+	go func() {
+		q.workers.Wait()
+		q.cancel()
+		close(q.done)
+	}()
+	return q
+}
+
+// SendAsync enqueues a notification to room. done, if not nil, is called from
+// a worker goroutine once the notification is sent, with nil, or once it
+// failed for good, with the last error.
+func (q *NotificationQueue) SendAsync(room string, req *NotificationRequest, done func(error)) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	if _, ok := q.pending[room]; !ok {
+		q.ready = append(q.ready, room)
+		q.cond.Signal()
+	}
+	q.pending[room] = append(q.pending[room], &queuedNotification{req, done})
+	return nil
+}
+
+// Close stops accepting notifications and waits until the queued ones are
+// sent, or ctx is done. In the latter case the notifications still being sent
+// or queued are abandoned: their requests are canceled and they fail with
+// context.Canceled.
+func (q *NotificationQueue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		return ctx.Err()
+	}
+}
+
+// work sends notifications until the queue is closed and empty.
+func (q *NotificationQueue) work() {
+	defer q.workers.Done()
+	for {
+		q.mu.Lock()
+		for len(q.ready) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.ready) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		room := q.ready[0]
+		q.ready = q.ready[1:]
+		n := q.pending[room][0]
+		q.mu.Unlock()
+
+		err := q.send(room, n.req)
+		if n.done != nil {
+			n.done(err)
+		}
+
+		q.mu.Lock()
+		if rest := q.pending[room][1:]; len(rest) > 0 {
+			q.pending[room] = rest
+			q.ready = append(q.ready, room)
+			q.cond.Signal()
+		} else {
+			delete(q.pending, room)
+		}
+		q.mu.Unlock()
+	}
+}
+
+// send sends a notification, retrying it on transient failures.
+func (q *NotificationQueue) send(room string, req *NotificationRequest) error {
+	maxAttempts := q.retry.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	ctx := withoutRetries(q.ctx)
+	for attempt := 1; ; attempt++ {
+		if err := q.ctx.Err(); err != nil {
+			return err
+		}
+		resp, err := q.client.Room.NotificationContext(ctx, room, req)
+		if err == nil {
+			return nil
+		}
+		var errResp *ErrorResponse
+		if errors.As(err, &errResp) {
+			if !q.retry.shouldRetry(resp.Request, resp, nil) {
+				return err
+			}
+		}
+		if attempt >= maxAttempts {
+			return err
+		}
+		timer := time.NewTimer(q.retry.backoff(attempt, resp))
+		select {
+		case <-q.ctx.Done():
+			timer.Stop()
+			return q.ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
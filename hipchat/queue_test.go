@@ -0,0 +1,156 @@
+package hipchat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNotificationQueue(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var (
+		mu       sync.Mutex
+		received = make(map[string][]string)
+		failures = 1
+	)
+	mux.HandleFunc("/room/", func(w http.ResponseWriter, r *http.Request) {
+		v := new(NotificationRequest)
+		json.NewDecoder(r.Body).Decode(v)
+		mu.Lock()
+		defer mu.Unlock()
+		if v.Message == "a2" && failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if v.Message == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received[r.URL.Path] = append(received[r.URL.Path], v.Message)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	q := NewNotificationQueue(client, 2, &RetryPolicy{MinBackoff: time.Millisecond})
+	var (
+		errMu sync.Mutex
+		errs  = make(map[string]error)
+	)
+	send := func(room, msg string) {
+		err := q.SendAsync(room, &NotificationRequest{Message: msg}, func(err error) {
+			errMu.Lock()
+			errs[msg] = err
+			errMu.Unlock()
+		})
+		if err != nil {
+			t.Fatalf("SendAsync returned an error %v", err)
+		}
+	}
+	send("a", "a1")
+	send("a", "a2")
+	send("b", "b1")
+	send("a", "a3")
+	send("b", "bad")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.Close(ctx); err != nil {
+		t.Fatalf("Close returned an error %v", err)
+	}
+
+	want := map[string][]string{
+		"/room/a/notification": {"a1", "a2", "a3"},
+		"/room/b/notification": {"b1"},
+	}
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("Server received %v, want %v", received, want)
+	}
+	if errs["a2"] != nil || !errors.Is(errs["bad"], ErrBadRequest) || len(errs) != 5 {
+		t.Errorf("Completion errors are %v", errs)
+	}
+	if err := q.SendAsync("a", &NotificationRequest{}, nil); err != ErrQueueClosed {
+		t.Errorf("SendAsync after Close returned %v, want %v", err, ErrQueueClosed)
+	}
+}
+
+func TestNotificationQueue_ClientRetries(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	mux.HandleFunc("/room/a/notification", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	client.SetRetryPolicy(&RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, RetryNonIdempotent: true})
+
+	q := NewNotificationQueue(client, 1, &RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond})
+	if err := q.SendAsync("a", &NotificationRequest{Message: "hi"}, nil); err != nil {
+		t.Fatalf("SendAsync returned an error %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.Close(ctx); err != nil {
+		t.Fatalf("Close returned an error %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("Server received %d attempts, want 2", attempts)
+	}
+}
+
+func TestNotificationQueue_CloseDeadline(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	mux.HandleFunc("/room/a/notification", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	q := NewNotificationQueue(client, 1, &RetryPolicy{MaxAttempts: 100, MinBackoff: time.Hour})
+	errc := make(chan error, 2)
+	for _, msg := range []string{"a1", "a2"} {
+		if err := q.SendAsync("a", &NotificationRequest{Message: msg}, func(err error) { errc <- err }); err != nil {
+			t.Fatalf("SendAsync returned an error %v", err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.Close(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Close returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	for n := 0; n < 2; n++ {
+		select {
+		case err := <-errc:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Abandoned notification failed with %v, want %v", err, context.Canceled)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Workers kept sending after Close gave up")
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 1 {
+		t.Errorf("Server received %d attempts, want 1", attempts)
+	}
+}
@@ -0,0 +1,61 @@
+package hipchat
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Rate-limit headers of HipChat API responses.
+const (
+	headerRateLimit     = "X-Ratelimit-Limit"
+	headerRateRemaining = "X-Ratelimit-Remaining"
+	headerRateReset     = "X-Ratelimit-Reset"
+)
+
+// Rate represents the rate limit of a token, as reported by HipChat.
+type Rate struct {
+	// Limit is the number of requests allowed per period.
+	Limit int
+	// Remaining is the number of requests left in the current period.
+	Remaining int
+	// Reset is when the current period ends.
+	Reset time.Time
+}
+
+// ParseRate returns the rate limit reported in the headers of resp, and
+// whether there was one.
+func ParseRate(resp *http.Response) (Rate, bool) {
+	if resp == nil {
+		return Rate{}, false
+	}
+	limit, err := strconv.Atoi(resp.Header.Get(headerRateLimit))
+	if err != nil {
+		return Rate{}, false
+	}
+	rate := Rate{Limit: limit}
+	if remaining, err := strconv.Atoi(resp.Header.Get(headerRateRemaining)); err == nil {
+		rate.Remaining = remaining
+	}
+	if reset, err := strconv.ParseInt(resp.Header.Get(headerRateReset), 10, 64); err == nil {
+		rate.Reset = time.Unix(reset, 0)
+	}
+	return rate, true
+}
+
+// Rate returns the rate limit reported by the last API response received by
+// the client, the zero Rate if none was.
+func (c *Client) Rate() Rate {
+	c.rateMu.Lock()
+	defer c.rateMu.Unlock()
+	return c.rate
+}
+
+// updateRate records the rate limit reported by resp, if any.
+func (c *Client) updateRate(resp *http.Response) {
+	if rate, ok := ParseRate(resp); ok {
+		c.rateMu.Lock()
+		c.rate = rate
+		c.rateMu.Unlock()
+	}
+}
@@ -0,0 +1,38 @@
+package hipchat
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Limit", "100")
+		w.Header().Set("X-Ratelimit-Remaining", "42")
+		w.Header().Set("X-Ratelimit-Reset", "1500000000")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	want := Rate{Limit: 100, Remaining: 42, Reset: time.Unix(1500000000, 0)}
+
+	resp, err := client.Room.Notification("1", &NotificationRequest{Message: "m"})
+	if err != nil {
+		t.Fatalf("Room.Notification returns an error %v", err)
+	}
+	if rate, ok := ParseRate(resp); !ok || !reflect.DeepEqual(rate, want) {
+		t.Errorf("ParseRate returned %+v, %v, want %+v", rate, ok, want)
+	}
+	if rate := client.Rate(); !reflect.DeepEqual(rate, want) {
+		t.Errorf("Client.Rate returned %+v, want %+v", rate, want)
+	}
+}
+
+func TestParseRate_NoHeaders(t *testing.T) {
+	if _, ok := ParseRate(&http.Response{Header: http.Header{}}); ok {
+		t.Error("ParseRate found a rate limit without headers")
+	}
+}
@@ -0,0 +1,85 @@
+package hipchat
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTokenReplayed is returned by ParseSignedParams when a replay guard is
+// configured and the jti of a token has already been seen.
+var ErrTokenReplayed = errors.New("token has already been used")
+
+// DefaultReplayWindow is how long a MemoryReplayGuard remembers tokens that
+// carry no exp claim.
+const DefaultReplayWindow = 15 * time.Minute
+
+// ReplayGuard remembers the ids (jti claims) of the JWTs already accepted, so
+// that a token cannot be used twice. Implementations backed by a shared
+// store such as Redis let several add-on instances share the same guard.
+type ReplayGuard interface {
+	// Seen records id as used until expiresAt and reports whether it had
+	// already been recorded.
+	Seen(id string, expiresAt time.Time) (bool, error)
+}
+
+// WithReplayGuard rejects signed requests whose token id has already been seen
+// by guard. Tokens without a jti claim are not checked.
+func WithReplayGuard(guard ReplayGuard) IntegrationOption {
+	return func(i *Integration) {
+		i.replayGuard = guard
+	}
+}
+
+// MemoryReplayGuard is a ReplayGuard safe for concurrent use that keeps token
+// ids in memory until they expire.
+type MemoryReplayGuard struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewMemoryReplayGuard returns an empty MemoryReplayGuard.
+func NewMemoryReplayGuard() *MemoryReplayGuard {
+	return &MemoryReplayGuard{seen: make(map[string]time.Time)}
+}
+
+// Seen records id as used until expiresAt, or for DefaultReplayWindow if
+// expiresAt is zero, and reports whether it had already been recorded.
+func (g *MemoryReplayGuard) Seen(id string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(DefaultReplayWindow)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for seenID, exp := range g.seen {
+		if now.After(exp) {
+			delete(g.seen, seenID)
+		}
+	}
+	if _, ok := g.seen[id]; ok {
+		return true, nil
+	}
+	g.seen[id] = expiresAt
+	return false, nil
+}
+
+// checkReplay rejects params whose token id has already been seen.
+func (i *Integration) checkReplay(params *SignedParams) error {
+	if i.replayGuard == nil || params.TokenID == "" {
+		return nil
+	}
+	expiresAt := params.ExpiresAt
+	if !expiresAt.IsZero() {
+		expiresAt = expiresAt.Add(i.clockSkew)
+	}
+	seen, err := i.replayGuard.Seen(params.Issuer+":"+params.TokenID, expiresAt)
+	if err != nil {
+		return err
+	}
+	if seen {
+		return ErrTokenReplayed
+	}
+	return nil
+}
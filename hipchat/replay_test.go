@@ -0,0 +1,58 @@
+package hipchat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayGuard(t *testing.T) {
+	var params []*SignedParams
+	_, handler := newSignedIntegration(&params, WithReplayGuard(NewMemoryReplayGuard()))
+
+	tests := []struct {
+		jti        string
+		wantStatus int
+	}{
+		{"a", http.StatusOK},
+		{"a", http.StatusUnauthorized},
+		{"b", http.StatusOK},
+		{"", http.StatusOK},
+		{"", http.StatusOK},
+	}
+	for n, tt := range tests {
+		r := httptest.NewRequest("GET", "/panel", nil)
+		claims := testClaims("oauth")
+		if tt.jti != "" {
+			claims["jti"] = tt.jti
+		}
+		signTestRequest(t, r, claims, "secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("Request %d with jti %q answered %d, want %d", n, tt.jti, w.Code, tt.wantStatus)
+		}
+		if w.Code == http.StatusUnauthorized && !strings.Contains(w.Body.String(), ErrorCodeInvalidToken) {
+			t.Errorf("Replayed request answered %s, want %s", w.Body, ErrorCodeInvalidToken)
+		}
+	}
+	if len(params) != 4 {
+		t.Errorf("%d requests reached the module, want 4", len(params))
+	}
+}
+
+func TestMemoryReplayGuard_Expiry(t *testing.T) {
+	g := NewMemoryReplayGuard()
+	if seen, _ := g.Seen("oauth:a", time.Now().Add(-time.Second)); seen {
+		t.Errorf("Seen reported a new id as seen")
+	}
+	if seen, _ := g.Seen("oauth:a", time.Time{}); seen {
+		t.Errorf("Seen reported an expired id as seen")
+	}
+	if seen, _ := g.Seen("oauth:a", time.Time{}); !seen {
+		t.Errorf("Seen did not report a replayed id")
+	}
+}
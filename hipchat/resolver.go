@@ -0,0 +1,129 @@
+package hipchat
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultResolverTTL is how long a UserResolver caches users by default.
+const DefaultResolverTTL = 10 * time.Minute
+
+// UserResolver resolves @mention names and emails to user ids, caching the
+// users it fetched so that a message mentioning the same users again does not
+// issue one API call per mention. It is safe for concurrent use.
+type UserResolver struct {
+	users *UserService
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]resolverEntry
+}
+
+type resolverEntry struct {
+	id      int
+	expires time.Time
+}
+
+// NewUserResolver returns a resolver fetching users with client and caching
+// them for ttl, or DefaultResolverTTL if ttl is not positive.
+func NewUserResolver(client *Client, ttl time.Duration) *UserResolver {
+	if ttl <= 0 {
+		ttl = DefaultResolverTTL
+	}
+	return &UserResolver{
+		users:   client.User,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]resolverEntry),
+	}
+}
+
+// resolverKey returns the cache key of an @mention name, with or without its
+// @, or an email.
+func resolverKey(ref string) string {
+	return strings.ToLower(strings.TrimPrefix(ref, "@"))
+}
+
+// Resolve returns the id of the user whose @mention name, with or without the
+// @, or email is ref.
+func (r *UserResolver) Resolve(ref string) (int, error) {
+	return r.ResolveContext(context.Background(), ref)
+}
+
+// ResolveContext is like Resolve, with ctx controlling the request.
+func (r *UserResolver) ResolveContext(ctx context.Context, ref string) (int, error) {
+	key := resolverKey(ref)
+	r.mu.Lock()
+	e, ok := r.entries[key]
+	r.mu.Unlock()
+	if ok && r.now().Before(e.expires) {
+		return e.id, nil
+	}
+
+	id := key
+	if !strings.Contains(key, "@") {
+		id = "@" + key
+	}
+	user, _, err := r.users.ViewContext(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	e = resolverEntry{id: user.ID, expires: r.now().Add(r.ttl)}
+	r.mu.Lock()
+	r.entries[key] = e
+	// The user is as likely to be referred to by the other.
+	if user.MentionName != "" {
+		r.entries[resolverKey(user.MentionName)] = e
+	}
+	if user.Email != "" {
+		r.entries[resolverKey(user.Email)] = e
+	}
+	r.mu.Unlock()
+	return user.ID, nil
+}
+
+// ResolveAll returns the ids of the users refs refers to, in order.
+func (r *UserResolver) ResolveAll(refs ...string) ([]int, error) {
+	return r.ResolveAllContext(context.Background(), refs...)
+}
+
+// ResolveAllContext is like ResolveAll, with ctx controlling the requests.
+func (r *UserResolver) ResolveAllContext(ctx context.Context, refs ...string) ([]int, error) {
+	ids := make([]int, len(refs))
+	for i, ref := range refs {
+		id, err := r.ResolveContext(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// Invalidate removes the user ref refers to from the cache, e.g. after
+// receiving a webhook telling their mention name changed.
+func (r *UserResolver) Invalidate(ref string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[resolverKey(ref)]
+	if !ok {
+		return
+	}
+	for key, other := range r.entries {
+		if other.id == e.id {
+			delete(r.entries, key)
+		}
+	}
+}
+
+// Purge empties the cache.
+func (r *UserResolver) Purge() {
+	r.mu.Lock()
+	r.entries = make(map[string]resolverEntry)
+	r.mu.Unlock()
+}
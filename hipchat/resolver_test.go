@@ -0,0 +1,52 @@
+package hipchat
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUserResolver(t *testing.T) {
+	setup()
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc("/user/", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		calls++
+		if r.URL.Path == "/user/@bob" {
+			fmt.Fprintf(w, `{"id": 1, "mention_name": "Bob", "email": "bob@example.com"}`)
+		} else {
+			fmt.Fprintf(w, `{"id": 2, "mention_name": "alice", "email": "alice@example.com"}`)
+		}
+	})
+
+	resolver := NewUserResolver(client, time.Minute)
+	now := time.Now()
+	resolver.now = func() time.Time { return now }
+
+	ids, err := resolver.ResolveAll("@bob", "bob", "bob@example.com", "alice@example.com", "@alice")
+	if err != nil {
+		t.Fatalf("UserResolver.ResolveAll returns an error %v", err)
+	}
+	if want := []int{1, 1, 1, 2, 2}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("UserResolver.ResolveAll returned %v, want %v", ids, want)
+	}
+	if calls != 2 {
+		t.Errorf("UserResolver fetched %d users, want 2", calls)
+	}
+
+	resolver.Invalidate("bob@example.com")
+	resolver.Resolve("bob")
+	if calls != 3 {
+		t.Errorf("UserResolver fetched %d users after Invalidate, want 3", calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	resolver.Resolve("alice")
+	if calls != 4 {
+		t.Errorf("UserResolver fetched %d users after the TTL, want 4", calls)
+	}
+}
@@ -0,0 +1,110 @@
+package hipchat
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// HeaderFromCache is set on the responses served from the ResponseCache of a
+// Client after HipChat answered 304 Not Modified.
+const HeaderFromCache = "X-From-Cache"
+
+// ResponseCache caches the bodies of GET responses along with their ETag, so
+// that unchanged resources are not transferred again.
+type ResponseCache interface {
+	Get(key string) (etag string, body []byte, ok bool)
+	Set(key, etag string, body []byte)
+}
+
+// MemoryResponseCache is a ResponseCache safe for concurrent use that keeps
+// responses in memory.
+type MemoryResponseCache struct {
+	mu      sync.RWMutex
+	entries map[string]cachedResponse
+}
+
+type cachedResponse struct {
+	etag string
+	body []byte
+}
+
+// NewMemoryResponseCache returns an empty MemoryResponseCache.
+func NewMemoryResponseCache() *MemoryResponseCache {
+	return &MemoryResponseCache{entries: make(map[string]cachedResponse)}
+}
+
+// Get returns the ETag and body cached for key, if any.
+func (c *MemoryResponseCache) Get(key string) (string, []byte, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[key]
+	return entry.etag, entry.body, ok
+}
+
+// Set caches the ETag and body of the response for key.
+func (c *MemoryResponseCache) Set(key, etag string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cachedResponse{etag, body}
+}
+
+// SetResponseCache makes the client send the ETag of cached GET responses in
+// If-None-Match, and use the cached body when HipChat answers 304 Not
+// Modified. A nil cache disables caching, which is the default.
+func (c *Client) SetResponseCache(cache ResponseCache) {
+	c.cache = cache
+}
+
+// cacheKey returns the key of the response to req, which depends on the
+// credentials of the client as HipChat answers differently to each token.
+func (c *Client) cacheKey(req *http.Request) string {
+	return c.limiterKey() + " " + req.URL.String()
+}
+
+// addETag asks HipChat to only answer req if the cached response changed.
+func (c *Client) addETag(req *http.Request) {
+	if c.cache == nil || req.Method != http.MethodGet {
+		return
+	}
+	if etag, _, ok := c.cache.Get(c.cacheKey(req)); ok {
+		req.Header.Set("If-None-Match", etag)
+	}
+}
+
+// useCache serves 304 responses from the cache, and caches the other
+// successful GET responses that carry an ETag.
+func (c *Client) useCache(req *http.Request, resp *http.Response) error {
+	if c.cache == nil || req.Method != http.MethodGet {
+		return nil
+	}
+	key := c.cacheKey(req)
+
+	if resp.StatusCode == http.StatusNotModified {
+		_, body, ok := c.cache.Get(key)
+		if !ok {
+			return nil
+		}
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.ContentLength = int64(len(body))
+		resp.Header.Set(HeaderFromCache, "1")
+		return nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	c.cache.Set(key, etag, body)
+	return nil
+}
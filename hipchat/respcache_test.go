@@ -0,0 +1,40 @@
+package hipchat
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestResponseCache(t *testing.T) {
+	setup()
+	defer teardown()
+
+	requests := 0
+	mux.HandleFunc("/emoticon/s", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprintf(w, `{"id":1, "shortcut":"s"}`)
+	})
+	client.SetResponseCache(NewMemoryResponseCache())
+
+	for n := 0; n < 2; n++ {
+		emo, resp, err := client.Emoticon.Get("s")
+		if err != nil {
+			t.Fatalf("Emoticon.Get returned an error %v", err)
+		}
+		if emo.Shortcut != "s" {
+			t.Errorf("Emoticon.Get returned %+v", emo)
+		}
+		if fromCache := resp.Header.Get(HeaderFromCache) != ""; fromCache != (n == 1) {
+			t.Errorf("Request %d served from cache: %v", n, fromCache)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Server received %d requests, want 2", requests)
+	}
+}
@@ -0,0 +1,188 @@
+package hipchat
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Defaults of the zero fields of a RetryPolicy.
+const (
+	DefaultMaxAttempts = 3
+	DefaultMinBackoff  = 500 * time.Millisecond
+	DefaultMaxBackoff  = 30 * time.Second
+)
+
+// RetryPolicy configures how a Client retries requests that failed
+// transiently. Requests are retried when HipChat answers 429 Too Many Requests
+// and, for idempotent methods, on 5xx responses and network errors. The delay
+// between attempts doubles from MinBackoff up to MaxBackoff, unless the
+// response carries a Retry-After header, which is honored instead.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent, including the
+	// first one. Zero means DefaultMaxAttempts.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry. Zero means
+	// DefaultMinBackoff.
+	MinBackoff time.Duration
+	// MaxBackoff caps the delay between attempts. Zero means DefaultMaxBackoff.
+	MaxBackoff time.Duration
+	// Jitter randomizes each delay between half and all of its value, so that
+	// clients throttled together do not retry together.
+	Jitter bool
+	// RetryNonIdempotent also retries POST requests on 5xx responses and
+	// network errors, at the risk of sending a notification twice.
+	RetryNonIdempotent bool
+}
+
+// SetRetryPolicy makes the client retry failed requests according to policy.
+// A nil policy disables retries, which is the default.
+func (c *Client) SetRetryPolicy(policy *RetryPolicy) {
+	c.retry = policy
+}
+
+type noRetriesKey struct{}
+
+// withoutRetries returns a copy of ctx under which requests are sent once,
+// whatever the retry policy of the client, for callers retrying on their own.
+func withoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetriesKey{}, true)
+}
+
+// send performs req, retrying it according to the retry policy of the client.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	p := c.retry
+	if p == nil || req.Context().Value(noRetriesKey{}) != nil {
+		return c.sendOnce(req)
+	}
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.sendOnce(req)
+		if attempt >= maxAttempts || !p.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+		if attempt == 1 && req.GetBody == nil && req.Body != nil && req.Body != http.NoBody {
+			// The body is consumed and cannot be sent again.
+			return resp, err
+		}
+
+		delay := p.backoff(attempt, resp)
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// sendOnce performs req once, after waiting for the rate limiter of the
+// client.
+func (c *Client) sendOnce(req *http.Request) (*http.Response, error) {
+	if c.limiter == nil {
+		return c.roundTrip(req)
+	}
+	key := c.limiterKey()
+	if err := c.limiter.Wait(req.Context(), key); err != nil {
+		return nil, err
+	}
+	resp, err := c.roundTrip(req)
+	if rate, ok := ParseRate(resp); ok {
+		c.limiter.observe(key, rate, time.Now())
+	}
+	return resp, err
+}
+
+// shouldRetry reports whether a request that got resp or err can be retried.
+func (p *RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		if req.Context().Err() != nil {
+			return false
+		}
+		return p.idempotent(req)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		return p.idempotent(req)
+	}
+	return false
+}
+
+// idempotent reports whether req can safely be sent more than once.
+func (p *RetryPolicy) idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return p.RetryNonIdempotent
+}
+
+// backoff returns the delay before the attempt following the given one.
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if delay, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return delay
+		}
+	}
+
+	min, max := p.MinBackoff, p.MaxBackoff
+	if min <= 0 {
+		min = DefaultMinBackoff
+	}
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+	delay := min
+	for n := 1; n < attempt && delay < max; n++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	if p.Jitter {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+	return delay
+}
+
+// retryAfter parses the value of a Retry-After header, either a number of
+// seconds or an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		delay := time.Until(t)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,95 @@
+package hipchat
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var bodies []string
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	client.SetRetryPolicy(&RetryPolicy{MaxAttempts: 3})
+
+	_, err := client.Room.Notification("1", &NotificationRequest{Message: "m"})
+	if err != nil {
+		t.Fatalf("Room.Notification returns an error %v", err)
+	}
+	if len(bodies) != 3 || bodies[2] != bodies[0] {
+		t.Errorf("Server received %q, want the same body 3 times", bodies)
+	}
+}
+
+func TestRetry_GiveUp(t *testing.T) {
+	setup()
+	defer teardown()
+
+	attempts := 0
+	mux.HandleFunc("/room/1", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	client.SetRetryPolicy(&RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond})
+
+	_, resp, err := client.Room.Get("1")
+	if err == nil {
+		t.Fatal("Room.Get did not return an error")
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || attempts != 2 {
+		t.Errorf("Room.Get returned status %d after %d attempts, want 503 after 2", resp.StatusCode, attempts)
+	}
+}
+
+func TestRetry_NonIdempotent(t *testing.T) {
+	setup()
+	defer teardown()
+
+	attempts := 0
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	client.SetRetryPolicy(&RetryPolicy{MinBackoff: time.Millisecond})
+
+	client.Room.Notification("1", &NotificationRequest{Message: "m"})
+	if attempts != 1 {
+		t.Errorf("POST was sent %d times, want 1", attempts)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempt, want := range []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		if attempt == 0 {
+			continue
+		}
+		if got := p.backoff(attempt, nil); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"7"}}}
+	if got := p.backoff(1, resp); got != 7*time.Second {
+		t.Errorf("backoff with Retry-After = %v, want 7s", got)
+	}
+
+	p.Jitter = true
+	for n := 0; n < 100; n++ {
+		if got := p.backoff(2, nil); got < time.Second || got > 2*time.Second {
+			t.Fatalf("backoff with jitter = %v, want between 1s and 2s", got)
+		}
+	}
+}
@@ -2,8 +2,11 @@ package hipchat
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 )
 
@@ -30,11 +33,12 @@ type Room struct {
 	Created           string         `json:"created"`
 	IsArchived        bool           `json:"is_archived"`
 	Privacy           string         `json:"privacy"`
-	IsGuestAccessible bool           `json:"is_guess_accessible"`
+	IsGuestAccessible bool           `json:"is_guest_accessible"`
 	Topic             string         `json:"topic"`
 	Participants      []User         `json:"participants"`
 	Owner             User           `json:"owner"`
 	GuestAccessURL    string         `json:"guest_access_url"`
+	AvatarURL         string         `json:"avatar_url"`
 }
 
 // RoomStatistics represents the HipChat room statistics.
@@ -79,6 +83,9 @@ type NotificationRequest struct {
 	MessageFormat string `json:"message_format,omitempty"`
 	From          string `json:"from,omitempty"`
 	Card          *Card  `json:"card,omitempty"`
+	// AttachTo is the id of an earlier message the notification is threaded
+	// under, e.g. to post status updates of the event it announced.
+	AttachTo string `json:"attach_to,omitempty"`
 }
 
 // RoomMessageRequest represents a Hipchat room message request.
@@ -93,7 +100,7 @@ type Card struct {
 	Format      string          `json:"format,omitempty"`
 	URL         string          `json:"url,omitempty"`
 	Title       string          `json:"title"`
-	Thumbnail   *Icon           `json:"thumbnail,omitempty"`
+	Thumbnail   *Thumbnail      `json:"thumbnail,omitempty"`
 	Activity    *Activity       `json:"activity,omitempty"`
 	Attributes  []Attribute     `json:"attributes,omitempty"`
 	ID          string          `json:"id,omitempty"`
@@ -182,9 +189,29 @@ type Thumbnail struct {
 	URL    string `json:"url"`
 	URL2x  string `json:"url@2x,omitempty"`
 	Width  uint   `json:"width,omitempty"`
-	Height uint   `json:"url,omitempty"`
+	Height uint   `json:"height,omitempty"`
 }
 
+const (
+	// AttributeStyleLozengeSuccess renders an attribute value as a green lozenge
+	AttributeStyleLozengeSuccess = "lozenge-success"
+
+	// AttributeStyleLozengeError renders an attribute value as a red lozenge
+	AttributeStyleLozengeError = "lozenge-error"
+
+	// AttributeStyleLozengeCurrent renders an attribute value as a yellow lozenge
+	AttributeStyleLozengeCurrent = "lozenge-current"
+
+	// AttributeStyleLozengeComplete renders an attribute value as a blue lozenge
+	AttributeStyleLozengeComplete = "lozenge-complete"
+
+	// AttributeStyleLozengeMoved renders an attribute value as an orange lozenge
+	AttributeStyleLozengeMoved = "lozenge-moved"
+
+	// AttributeStyleLozenge renders an attribute value as a grey lozenge
+	AttributeStyleLozenge = "lozenge"
+)
+
 // Attribute represents an attribute on a Card
 type Attribute struct {
 	Label string         `json:"label,omitempty"`
@@ -205,6 +232,12 @@ type Activity struct {
 	HTML string `json:"html,omitempty"`
 }
 
+// ShareLinkRequest represents a HipChat room link share request.
+type ShareLinkRequest struct {
+	Link    string `json:"link"`
+	Message string `json:"message,omitempty"`
+}
+
 // ShareFileRequest represents a HipChat room file share request.
 type ShareFileRequest struct {
 	Path     string `json:"path"`
@@ -222,13 +255,56 @@ type History struct {
 
 // Message represents a HipChat message.
 type Message struct {
-	Date          string      `json:"date"`
-	From          interface{} `json:"from"` // string | obj <- weak
-	ID            string      `json:"id"`
-	Mentions      []User      `json:"mentions"`
-	Message       string      `json:"message"`
-	MessageFormat string      `json:"message_format"`
-	Type          string      `json:"type"`
+	Date          string       `json:"date"`
+	From          interface{}  `json:"from"` // string | obj <- weak, see Sender
+	ID            string       `json:"id"`
+	Mentions      []User       `json:"mentions"`
+	Message       string       `json:"message"`
+	MessageFormat string       `json:"message_format"`
+	Type          string       `json:"type"`
+	Color         string       `json:"color,omitempty"`
+	File          *MessageFile `json:"file,omitempty"`
+}
+
+// Types of the messages of a room history.
+const (
+	MessageTypeMessage      = "message"
+	MessageTypeNotification = "notification"
+	MessageTypeTopic        = "topic"
+	MessageTypeGuestAccess  = "guest_access"
+)
+
+// MessageFile represents the file shared with a HipChat message.
+type MessageFile struct {
+	Name     string `json:"name"`
+	Size     int    `json:"size"`
+	URL      string `json:"url"`
+	ThumbURL string `json:"thumb_url,omitempty"`
+}
+
+// MessageSender represents the sender of a HipChat message. Notifications
+// are sent by add-ons or integrations, which only have a name.
+type MessageSender struct {
+	ID          int
+	Name        string
+	MentionName string
+}
+
+// Sender returns the sender of the message, decoded from From.
+func (m *Message) Sender() MessageSender {
+	switch from := m.From.(type) {
+	case string:
+		return MessageSender{Name: from}
+	case map[string]interface{}:
+		sender := MessageSender{}
+		if id, ok := from["id"].(float64); ok {
+			sender.ID = int(id)
+		}
+		sender.Name, _ = from["name"].(string)
+		sender.MentionName, _ = from["mention_name"].(string)
+		return sender
+	}
+	return MessageSender{}
 }
 
 // SetTopicRequest represents a hipchat update topic request
@@ -236,9 +312,15 @@ type SetTopicRequest struct {
 	Topic string `json:"topic"`
 }
 
+// SetAvatarRequest represents a hipchat room avatar update request. Avatar is
+// the base64-encoded image.
+type SetAvatarRequest struct {
+	Avatar string `json:"avatar"`
+}
+
 // InviteRequest represents a hipchat invite to room request
 type InviteRequest struct {
-	Reason string `json:"reason"`
+	Reason string `json:"reason,omitempty"`
 }
 
 // GlanceRequest represents a hipchat glance request
@@ -265,13 +347,18 @@ func (c *Card) AddAttribute(mainLabel, subLabel, url, iconURL string) {
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/get_all_rooms
 func (r *RoomService) List() (*Rooms, *http.Response, error) {
+	return r.ListContext(context.Background())
+}
+
+// ListContext is like List, with ctx controlling the request.
+func (r *RoomService) ListContext(ctx context.Context) (*Rooms, *http.Response, error) {
 	req, err := r.client.NewRequest("GET", "room", nil, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	rooms := new(Rooms)
-	resp, err := r.client.Do(req, rooms)
+	resp, err := r.client.DoContext(ctx, req, rooms)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -282,13 +369,38 @@ func (r *RoomService) List() (*Rooms, *http.Response, error) {
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/get_room
 func (r *RoomService) Get(id string) (*Room, *http.Response, error) {
-	req, err := r.client.NewRequest("GET", fmt.Sprintf("room/%s", id), nil, nil)
+	return r.GetContext(context.Background(), id)
+}
+
+// GetContext is like Get, with ctx controlling the request.
+func (r *RoomService) GetContext(ctx context.Context, id string) (*Room, *http.Response, error) {
+	return r.GetWithOptionsContext(ctx, id, nil)
+}
+
+// RoomGetOptions specifies the optional parameters of the RoomService.GetWithOptions method.
+type RoomGetOptions struct {
+	// Comma separated list of the fields to expand in the response, e.g.
+	// "participants,statistics".
+	Expand string `url:"expand,omitempty"`
+}
+
+// GetWithOptions returns the room specified by the id, with the fields listed
+// in opt.Expand expanded.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/get_room
+func (r *RoomService) GetWithOptions(id string, opt *RoomGetOptions) (*Room, *http.Response, error) {
+	return r.GetWithOptionsContext(context.Background(), id, opt)
+}
+
+// GetWithOptionsContext is like GetWithOptions, with ctx controlling the request.
+func (r *RoomService) GetWithOptionsContext(ctx context.Context, id string, opt *RoomGetOptions) (*Room, *http.Response, error) {
+	req, err := r.client.NewRequest("GET", fmt.Sprintf("room/%s", id), opt, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	room := new(Room)
-	resp, err := r.client.Do(req, room)
+	resp, err := r.client.DoContext(ctx, req, room)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -299,13 +411,18 @@ func (r *RoomService) Get(id string) (*Room, *http.Response, error) {
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/get_room_statistics
 func (r *RoomService) GetStatistics(id string) (*RoomStatistics, *http.Response, error) {
+	return r.GetStatisticsContext(context.Background(), id)
+}
+
+// GetStatisticsContext is like GetStatistics, with ctx controlling the request.
+func (r *RoomService) GetStatisticsContext(ctx context.Context, id string) (*RoomStatistics, *http.Response, error) {
 	req, err := r.client.NewRequest("GET", fmt.Sprintf("room/%s/statistics", id), nil, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	roomStatistics := new(RoomStatistics)
-	resp, err := r.client.Do(req, roomStatistics)
+	resp, err := r.client.DoContext(ctx, req, roomStatistics)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -316,49 +433,87 @@ func (r *RoomService) GetStatistics(id string) (*RoomStatistics, *http.Response,
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/send_room_notification
 func (r *RoomService) Notification(id string, notifReq *NotificationRequest) (*http.Response, error) {
+	return r.NotificationContext(context.Background(), id, notifReq)
+}
+
+// NotificationContext is like Notification, with ctx controlling the request.
+func (r *RoomService) NotificationContext(ctx context.Context, id string, notifReq *NotificationRequest) (*http.Response, error) {
 	req, err := r.client.NewRequest("POST", fmt.Sprintf("room/%s/notification", id), nil, notifReq)
 	if err != nil {
 		return nil, err
 	}
 
-	return r.client.Do(req, nil)
+	return r.client.DoContext(ctx, req, nil)
 }
 
 // Message sends a message to the room specified by the id.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/send_message
 func (r *RoomService) Message(id string, msgReq *RoomMessageRequest) (*http.Response, error) {
+	return r.MessageContext(context.Background(), id, msgReq)
+}
+
+// MessageContext is like Message, with ctx controlling the request.
+func (r *RoomService) MessageContext(ctx context.Context, id string, msgReq *RoomMessageRequest) (*http.Response, error) {
 	req, err := r.client.NewRequest("POST", fmt.Sprintf("room/%s/message", id), nil, msgReq)
 	if err != nil {
 		return nil, err
 	}
 
-	return r.client.Do(req, nil)
+	return r.client.DoContext(ctx, req, nil)
 }
 
 // ShareFile sends a file to the room specified by the id.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/share_file_with_room
 func (r *RoomService) ShareFile(id string, shareFileReq *ShareFileRequest) (*http.Response, error) {
+	return r.ShareFileContext(context.Background(), id, shareFileReq)
+}
+
+// ShareFileContext is like ShareFile, with ctx controlling the request.
+func (r *RoomService) ShareFileContext(ctx context.Context, id string, shareFileReq *ShareFileRequest) (*http.Response, error) {
 	req, err := r.client.NewFileUploadRequest("POST", fmt.Sprintf("room/%s/share/file", id), shareFileReq)
 	if err != nil {
 		return nil, err
 	}
 
-	return r.client.Do(req, nil)
+	return r.client.DoContext(ctx, req, nil)
+}
+
+// ShareLink shares a link with the room specified by the id. HipChat renders
+// the link with a preview.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/share_link_with_room
+func (r *RoomService) ShareLink(id string, linkReq *ShareLinkRequest) (*http.Response, error) {
+	return r.ShareLinkContext(context.Background(), id, linkReq)
+}
+
+// ShareLinkContext is like ShareLink, with ctx controlling the request.
+func (r *RoomService) ShareLinkContext(ctx context.Context, id string, linkReq *ShareLinkRequest) (*http.Response, error) {
+	req, err := r.client.NewRequest("POST", fmt.Sprintf("room/%s/share/link", id), nil, linkReq)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.client.DoContext(ctx, req, nil)
 }
 
 // Create creates a new room.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/create_room
 func (r *RoomService) Create(roomReq *CreateRoomRequest) (*Room, *http.Response, error) {
+	return r.CreateContext(context.Background(), roomReq)
+}
+
+// CreateContext is like Create, with ctx controlling the request.
+func (r *RoomService) CreateContext(ctx context.Context, roomReq *CreateRoomRequest) (*Room, *http.Response, error) {
 	req, err := r.client.NewRequest("POST", "room", nil, roomReq)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	room := new(Room)
-	resp, err := r.client.Do(req, room)
+	resp, err := r.client.DoContext(ctx, req, room)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -369,24 +524,34 @@ func (r *RoomService) Create(roomReq *CreateRoomRequest) (*Room, *http.Response,
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/delete_room
 func (r *RoomService) Delete(id string) (*http.Response, error) {
+	return r.DeleteContext(context.Background(), id)
+}
+
+// DeleteContext is like Delete, with ctx controlling the request.
+func (r *RoomService) DeleteContext(ctx context.Context, id string) (*http.Response, error) {
 	req, err := r.client.NewRequest("DELETE", fmt.Sprintf("room/%s", id), nil, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return r.client.Do(req, nil)
+	return r.client.DoContext(ctx, req, nil)
 }
 
 // Update updates an existing room.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/update_room
 func (r *RoomService) Update(id string, roomReq *UpdateRoomRequest) (*http.Response, error) {
+	return r.UpdateContext(context.Background(), id, roomReq)
+}
+
+// UpdateContext is like Update, with ctx controlling the request.
+func (r *RoomService) UpdateContext(ctx context.Context, id string, roomReq *UpdateRoomRequest) (*http.Response, error) {
 	req, err := r.client.NewRequest("PUT", fmt.Sprintf("room/%s", id), nil, roomReq)
 	if err != nil {
 		return nil, err
 	}
 
-	return r.client.Do(req, nil)
+	return r.client.DoContext(ctx, req, nil)
 }
 
 // HistoryOptions represents a HipChat room chat history request.
@@ -402,18 +567,31 @@ type HistoryOptions struct {
 	Timezone string `url:"timezone,omitempty"`
 
 	// Reverse the output such that the oldest message is first.
-	// For consistent paging, set to 'false'.
-	Reverse bool `url:"reverse,omitempty"`
+	// For consistent paging, set to 'false'. Always sent, as the API
+	// defaults to 'true'.
+	Reverse bool `url:"reverse"`
+
+	// Either the earliest date to fetch history for in ISO-8601 format, or 'null' to
+	// disable. Only messages posted between EndDate and Date are returned.
+	EndDate string `url:"end-date,omitempty"`
 }
 
 // History fetches a room's chat history.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/view_room_history
 func (r *RoomService) History(id string, opt *HistoryOptions) (*History, *http.Response, error) {
+	return r.HistoryContext(context.Background(), id, opt)
+}
+
+// HistoryContext is like History, with ctx controlling the request.
+func (r *RoomService) HistoryContext(ctx context.Context, id string, opt *HistoryOptions) (*History, *http.Response, error) {
 	u := fmt.Sprintf("room/%s/history", id)
 	req, err := r.client.NewRequest("GET", u, opt, nil)
+	if err != nil {
+		return nil, nil, err
+	}
 	h := new(History)
-	resp, err := r.client.Do(req, &h)
+	resp, err := r.client.DoContext(ctx, req, &h)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -434,24 +612,112 @@ type LatestHistoryOptions struct {
 	NotBefore string `url:"not-before,omitempty"`
 }
 
-// Latest fetches a room's chat history.
+// Latest fetches the most recent messages of a room's chat history. Polling
+// with opt.NotBefore set to the id of the last message seen only returns the
+// messages posted since.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/view_recent_room_history
 func (r *RoomService) Latest(id string, opt *LatestHistoryOptions) (*History, *http.Response, error) {
+	return r.LatestContext(context.Background(), id, opt)
+}
+
+// LatestContext is like Latest, with ctx controlling the request.
+func (r *RoomService) LatestContext(ctx context.Context, id string, opt *LatestHistoryOptions) (*History, *http.Response, error) {
 	u := fmt.Sprintf("room/%s/history/latest", id)
 	req, err := r.client.NewRequest("GET", u, opt, nil)
+	if err != nil {
+		return nil, nil, err
+	}
 	h := new(History)
-	resp, err := r.client.Do(req, &h)
+	resp, err := r.client.DoContext(ctx, req, &h)
 	if err != nil {
 		return nil, resp, err
 	}
 	return h, resp, nil
 }
 
+// DeleteMessage deletes a message from a room's chat history. It requires an
+// admin token with the admin_room scope.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/delete_message
+func (r *RoomService) DeleteMessage(id, messageID string) (*http.Response, error) {
+	return r.DeleteMessageContext(context.Background(), id, messageID)
+}
+
+// DeleteMessageContext is like DeleteMessage, with ctx controlling the request.
+func (r *RoomService) DeleteMessageContext(ctx context.Context, id, messageID string) (*http.Response, error) {
+	req, err := r.client.NewRequest("DELETE", fmt.Sprintf("room/%s/history/%s", id, messageID), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.client.DoContext(ctx, req, nil)
+}
+
+// GetMessage fetches a single message of a room's chat history.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/get_room_message
+func (r *RoomService) GetMessage(id, messageID string) (*Message, *http.Response, error) {
+	return r.GetMessageContext(context.Background(), id, messageID)
+}
+
+// GetMessageContext is like GetMessage, with ctx controlling the request.
+func (r *RoomService) GetMessageContext(ctx context.Context, id, messageID string) (*Message, *http.Response, error) {
+	req, err := r.client.NewRequest("GET", fmt.Sprintf("room/%s/history/%s", id, messageID), nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m := new(struct {
+		Message Message `json:"message"`
+	})
+	resp, err := r.client.DoContext(ctx, req, m)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &m.Message, resp, nil
+}
+
+// ParticipantsOptions specifies the optional parameters of the
+// RoomService.Participants method.
+type ParticipantsOptions struct {
+	ListOptions
+
+	// Include participants who are currently offline.
+	IncludeOffline bool `url:"include-offline,omitempty"`
+}
+
+// Participants returns the people currently in the room.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/get_all_participants
+func (r *RoomService) Participants(id string, opt *ParticipantsOptions) (*Users, *http.Response, error) {
+	return r.ParticipantsContext(context.Background(), id, opt)
+}
+
+// ParticipantsContext is like Participants, with ctx controlling the request.
+func (r *RoomService) ParticipantsContext(ctx context.Context, id string, opt *ParticipantsOptions) (*Users, *http.Response, error) {
+	req, err := r.client.NewRequest("GET", fmt.Sprintf("room/%s/participant", id), opt, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	users := new(Users)
+	resp, err := r.client.DoContext(ctx, req, users)
+	if err != nil {
+		return nil, resp, err
+	}
+	return users, resp, nil
+}
+
 // SetTopic sets Room topic.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/set_topic
 func (r *RoomService) SetTopic(id string, topic string) (*http.Response, error) {
+	return r.SetTopicContext(context.Background(), id, topic)
+}
+
+// SetTopicContext is like SetTopic, with ctx controlling the request.
+func (r *RoomService) SetTopicContext(ctx context.Context, id string, topic string) (*http.Response, error) {
 	topicReq := &SetTopicRequest{Topic: topic}
 
 	req, err := r.client.NewRequest("PUT", fmt.Sprintf("room/%s/topic", id), nil, topicReq)
@@ -459,13 +725,72 @@ func (r *RoomService) SetTopic(id string, topic string) (*http.Response, error)
 		return nil, err
 	}
 
-	return r.client.Do(req, nil)
+	return r.client.DoContext(ctx, req, nil)
+}
+
+// GetAvatar writes the avatar image of the Room to w.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/get_room_avatar
+func (r *RoomService) GetAvatar(id string, w io.Writer) (*http.Response, error) {
+	return r.GetAvatarContext(context.Background(), id, w)
+}
+
+// GetAvatarContext is like GetAvatar, with ctx controlling the request.
+func (r *RoomService) GetAvatarContext(ctx context.Context, id string, w io.Writer) (*http.Response, error) {
+	req, err := r.client.NewRequest("GET", fmt.Sprintf("room/%s/avatar", id), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.client.DoContext(ctx, req, w)
+}
+
+// SetAvatar sets the avatar of the Room to the given PNG, JPEG or GIF image.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/update_room_avatar
+func (r *RoomService) SetAvatar(id string, image []byte) (*http.Response, error) {
+	return r.SetAvatarContext(context.Background(), id, image)
+}
+
+// SetAvatarContext is like SetAvatar, with ctx controlling the request.
+func (r *RoomService) SetAvatarContext(ctx context.Context, id string, image []byte) (*http.Response, error) {
+	avatarReq := &SetAvatarRequest{Avatar: base64.StdEncoding.EncodeToString(image)}
+
+	req, err := r.client.NewRequest("PUT", fmt.Sprintf("room/%s/avatar", id), nil, avatarReq)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.client.DoContext(ctx, req, nil)
+}
+
+// DeleteAvatar removes the avatar of the Room, restoring the default one.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/delete_room_avatar
+func (r *RoomService) DeleteAvatar(id string) (*http.Response, error) {
+	return r.DeleteAvatarContext(context.Background(), id)
+}
+
+// DeleteAvatarContext is like DeleteAvatar, with ctx controlling the request.
+func (r *RoomService) DeleteAvatarContext(ctx context.Context, id string) (*http.Response, error) {
+	req, err := r.client.NewRequest("DELETE", fmt.Sprintf("room/%s/avatar", id), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.client.DoContext(ctx, req, nil)
 }
 
-// Invite someone to the Room.
+// Invite someone to the Room. The user is given by id, email or @mention
+// name, and reason may be empty.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/invite_user
 func (r *RoomService) Invite(room string, user string, reason string) (*http.Response, error) {
+	return r.InviteContext(context.Background(), room, user, reason)
+}
+
+// InviteContext is like Invite, with ctx controlling the request.
+func (r *RoomService) InviteContext(ctx context.Context, room string, user string, reason string) (*http.Response, error) {
 	reasonReq := &InviteRequest{Reason: reason}
 
 	req, err := r.client.NewRequest("POST", fmt.Sprintf("room/%s/invite/%s", room, user), nil, reasonReq)
@@ -473,33 +798,44 @@ func (r *RoomService) Invite(room string, user string, reason string) (*http.Res
 		return nil, err
 	}
 
-	return r.client.Do(req, nil)
+	return r.client.DoContext(ctx, req, nil)
 }
 
 // CreateGlance creates a glance in a room's sidebar
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/create_room_glance
 func (r *RoomService) CreateGlance(room string, glanceReq *GlanceRequest) (*http.Response, error) {
-	req, err := r.client.NewRequest("PUT", fmt.Sprintf("/v2/room/%s/extension/glance/%s", room, glanceReq.Key), nil, glanceReq)
+	return r.CreateGlanceContext(context.Background(), room, glanceReq)
+}
+
+// CreateGlanceContext is like CreateGlance, with ctx controlling the request.
+func (r *RoomService) CreateGlanceContext(ctx context.Context, room string, glanceReq *GlanceRequest) (*http.Response, error) {
+	req, err := r.client.NewRequest("PUT", fmt.Sprintf("room/%s/extension/glance/%s", room, glanceReq.Key), nil, glanceReq)
 	if err != nil {
 		return nil, err
 	}
 
-	return r.client.Do(req, nil)
+	return r.client.DoContext(ctx, req, nil)
 }
 
+// RoomAddOnUIUpdateReq represents an update of the glances of an add-on,
+// pushed with the AddOnService methods.
 type RoomAddOnUIUpdateReq struct {
 	Glances []GlanceUpdate `json:"glance"`
 }
 
+// GlanceUpdate represents the new content of the glance with the given key.
 type GlanceUpdate struct {
 	Content GlanceUpdateContent `json:"content"`
 	Key     string              `json:"key"`
 }
 
+// GlanceUpdateContent represents the content of a glance. Metadata holds the
+// values the conditions of the glance are evaluated against.
 type GlanceUpdateContent struct {
-	Status interface{} `json:"status,omitempty"`
-	Label  GlanceLabel  `json:"label"`
+	Status   interface{}            `json:"status,omitempty"`
+	Label    GlanceLabel            `json:"label"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
 type GlanceStatusLozenge struct {
@@ -565,17 +901,37 @@ func (c *GlanceUpdateContent) SetLozenge(lozengeType, lozengeLabel string) {
 	c.Status = &GlanceStatusLozenge{
 		Type: "lozenge",
 		Value: LozengeValue{
-			Type: lozengeType,
+			Type:  lozengeType,
 			Label: lozengeLabel,
 		},
 	}
 }
 
-func (r *RoomService) RoomAddOnUIUpdate(room string, addOnUIUpdateReq *RoomAddOnUIUpdateReq) (*http.Response, error) {
-	req, err := r.client.NewRequest("POST", fmt.Sprintf("/v2/addon/ui/room/%s", room), nil, addOnUIUpdateReq)
-	if err != nil {
-		return nil, err
+// SetIcon sets the status of the glance to an icon.
+func (gu *GlanceUpdate) SetIcon(url, url2x string) {
+	gu.Content.SetIcon(url, url2x)
+}
+
+// SetIcon sets the status of the glance to an icon.
+func (c *GlanceUpdateContent) SetIcon(url, url2x string) {
+	c.Status = &GlanceStatusIcon{
+		Type:  "icon",
+		Value: Icon{URL: url, URL2x: url2x},
 	}
+}
 
-	return r.client.Do(req, nil)
+// SetMetadata sets a metadata value of the glance, used to evaluate its
+// conditions.
+func (gu *GlanceUpdate) SetMetadata(key string, value interface{}) {
+	if gu.Content.Metadata == nil {
+		gu.Content.Metadata = make(map[string]interface{})
+	}
+	gu.Content.Metadata[key] = value
+}
+
+// RoomAddOnUIUpdate pushes glance updates to a room.
+//
+// Deprecated: use AddOnService.UpdateRoomUI.
+func (r *RoomService) RoomAddOnUIUpdate(room string, addOnUIUpdateReq *RoomAddOnUIUpdateReq) (*http.Response, error) {
+	return r.client.AddOn.UpdateRoomUI(room, addOnUIUpdateReq)
 }
@@ -1,10 +1,12 @@
 package hipchat
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"reflect"
 	"testing"
@@ -21,6 +23,7 @@ func TestRoomGet(t *testing.T) {
 			"id":1,
 			"name":"n",
 			"links":{"self":"s"},
+			"is_guest_accessible":true,
 			"Participants":[
 				{"Name":"n1"},
 				{"Name":"n2"}
@@ -29,11 +32,12 @@ func TestRoomGet(t *testing.T) {
 		}`)
 	})
 	want := &Room{
-		ID:           1,
-		Name:         "n",
-		Links:        RoomLinks{Links: Links{Self: "s"}},
-		Participants: []User{{Name: "n1"}, {Name: "n2"}},
-		Owner:        User{Name: "n1"},
+		ID:                1,
+		Name:              "n",
+		Links:             RoomLinks{Links: Links{Self: "s"}},
+		IsGuestAccessible: true,
+		Participants:      []User{{Name: "n1"}, {Name: "n2"}},
+		Owner:             User{Name: "n1"},
 	}
 
 	room, _, err := client.Room.Get("1")
@@ -45,6 +49,35 @@ func TestRoomGet(t *testing.T) {
 	}
 }
 
+func TestRoomGetWithOptions(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"expand": "participants,statistics"})
+		fmt.Fprintf(w, `
+		{
+			"id":1,
+			"statistics":{"messages_sent":3},
+			"participants":[{"name":"n1"}]
+		}`)
+	})
+	want := &Room{
+		ID:           1,
+		Statistics:   RoomStatistics{MessagesSent: 3},
+		Participants: []User{{Name: "n1"}},
+	}
+
+	room, _, err := client.Room.GetWithOptions("1", &RoomGetOptions{Expand: "participants,statistics"})
+	if err != nil {
+		t.Fatalf("Room.GetWithOptions returns an error %v", err)
+	}
+	if !reflect.DeepEqual(want, room) {
+		t.Errorf("Room.GetWithOptions returned %+v, want %+v", room, want)
+	}
+}
+
 func TestRoomList(t *testing.T) {
 	setup()
 	defer teardown()
@@ -142,6 +175,29 @@ func TestRoomMessage(t *testing.T) {
 	}
 }
 
+func TestRoomShareLink(t *testing.T) {
+	setup()
+	defer teardown()
+
+	args := &ShareLinkRequest{Link: "https://example.com", Message: "m"}
+
+	mux.HandleFunc("/room/1/share/link", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		v := new(ShareLinkRequest)
+		json.NewDecoder(r.Body).Decode(v)
+
+		if !reflect.DeepEqual(v, args) {
+			t.Errorf("Request body %+v, want %+v", v, args)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	_, err := client.Room.ShareLink("1", args)
+	if err != nil {
+		t.Fatalf("Room.ShareLink returns an error %v", err)
+	}
+}
+
 func TestRoomShareFile(t *testing.T) {
 	setup()
 	defer teardown()
@@ -153,13 +209,13 @@ func TestRoomShareFile(t *testing.T) {
 	want := "--hipfileboundary\n" +
 		"Content-Type: application/json; charset=UTF-8\n" +
 		"Content-Disposition: attachment; name=\"metadata\"\n\n" +
-		"{\"message\": \"Hello there\"}\n" +
+		"{\"message\":\"Hello there\"}\n" +
 		"--hipfileboundary\n" +
-		"Content-Type:  charset=UTF-8\n" +
+		"Content-Type: application/octet-stream\n" +
 		"Content-Transfer-Encoding: base64\n" +
-		"Content-Disposition: attachment; name=file; filename=hipfile\n\n" +
+		"Content-Disposition: attachment; name=\"file\"; filename=\"hipfile\"\n\n" +
 		"Z28gZ29waGVycw==\n" +
-		"--hipfileboundary\n"
+		"--hipfileboundary--\n"
 
 	mux.HandleFunc("/room/1/share/file", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "POST")
@@ -220,6 +276,62 @@ func TestRoomDelete(t *testing.T) {
 	}
 }
 
+func TestRoomGetAvatar(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/avatar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprintf(w, "PNG")
+	})
+
+	var buf bytes.Buffer
+	_, err := client.Room.GetAvatar("1", &buf)
+	if err != nil {
+		t.Fatalf("Room.GetAvatar returns an error %v", err)
+	}
+	if buf.String() != "PNG" {
+		t.Errorf("Room.GetAvatar returned %q, want %q", buf.String(), "PNG")
+	}
+}
+
+func TestRoomSetAvatar(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/avatar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PUT")
+		v := new(SetAvatarRequest)
+		json.NewDecoder(r.Body).Decode(v)
+
+		want := &SetAvatarRequest{Avatar: "UE5H"}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("Request body %+v, want %+v", v, want)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	_, err := client.Room.SetAvatar("1", []byte("PNG"))
+	if err != nil {
+		t.Fatalf("Room.SetAvatar returns an error %v", err)
+	}
+}
+
+func TestRoomDeleteAvatar(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/avatar", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	_, err := client.Room.DeleteAvatar("1")
+	if err != nil {
+		t.Fatalf("Room.DeleteAvatar returns an error %v", err)
+	}
+}
+
 func TestRoomUpdate(t *testing.T) {
 	setup()
 	defer teardown()
@@ -277,7 +389,7 @@ func TestRoomHistory(t *testing.T) {
 	})
 
 	opt := &HistoryOptions{
-		ListOptions{1, 100}, "date", "tz", true,
+		ListOptions: ListOptions{1, 100}, Date: "date", Timezone: "tz", Reverse: true,
 	}
 	hist, _, err := client.Room.History("1", opt)
 	if err != nil {
@@ -356,6 +468,34 @@ func TestSetTopic(t *testing.T) {
 	}
 }
 
+func TestCreateGlance(t *testing.T) {
+	setup()
+	defer teardown()
+	client.BaseURL, _ = url.Parse(server.URL + "/hipchat/v2/")
+
+	args := &GlanceRequest{Key: "g", Name: Name{Value: "Glance"}}
+
+	called := false
+	mux.HandleFunc("/hipchat/v2/room/1/extension/glance/g", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+		v := new(GlanceRequest)
+		json.NewDecoder(r.Body).Decode(v)
+
+		if !reflect.DeepEqual(v, args) {
+			t.Errorf("Request body %+v, want %+v", v, args)
+		}
+	})
+
+	_, err := client.Room.CreateGlance("1", args)
+	if err != nil {
+		t.Fatalf("Room.CreateGlance returns an error %v", err)
+	}
+	if !called {
+		t.Errorf("Room.CreateGlance did not request the glance under the base URL")
+	}
+}
+
 func TestInvite(t *testing.T) {
 	setup()
 	defer teardown()
@@ -378,6 +518,24 @@ func TestInvite(t *testing.T) {
 	}
 }
 
+func TestInvite_NoReason(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/invite/user@example.com", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		body, _ := ioutil.ReadAll(r.Body)
+		if want := "{}\n"; string(body) != want {
+			t.Errorf("Request body %q, want %q", body, want)
+		}
+	})
+
+	_, err := client.Room.Invite("1", "user@example.com", "")
+	if err != nil {
+		t.Fatalf("Room.Invite returns an error %v", err)
+	}
+}
+
 func TestCardDescriptionJSONEncodeWithString(t *testing.T) {
 	description := CardDescription{Value: "This is a test"}
 	expected := `"This is a test"`
@@ -445,3 +603,110 @@ func TestCardDescriptionJSONDecodeWithObject(t *testing.T) {
 		t.Fatalf("Unexpected CardDescription.Format: %v", actual.Format)
 	}
 }
+
+func TestNotificationWithCard(t *testing.T) {
+	setup()
+	defer teardown()
+
+	card := &Card{
+		Style:       CardStyleLink,
+		Title:       "t",
+		Description: CardDescription{Format: "text", Value: "d"},
+		Thumbnail:   &Thumbnail{URL: "u", Width: 10, Height: 20},
+		Activity:    &Activity{HTML: "h"},
+	}
+	card.AddAttribute("l", "v", "", "i")
+
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		body, _ := ioutil.ReadAll(r.Body)
+		want := `{"message":"m","card":{"style":"link","description":{"format":"text","value":"d"},` +
+			`"title":"t","thumbnail":{"url":"u","width":10,"height":20},"activity":{"html":"h"},` +
+			`"attributes":[{"label":"l","value":{"label":"v","icon":{"url":"i"}}}]}}` + "\n"
+		if string(body) != want {
+			t.Errorf("Request body %s, want %s", body, want)
+		}
+	})
+
+	_, err := client.Room.Notification("1", &NotificationRequest{Message: "m", Card: card})
+	if err != nil {
+		t.Fatalf("Room.Notification returns an error %v", err)
+	}
+}
+
+func TestMessageSender(t *testing.T) {
+	var h History
+	json.Unmarshal([]byte(`{"items":[
+		{"type":"message","from":{"id":1,"name":"First Last","mention_name":"FirstL"},
+		 "file":{"name":"f.png","size":3,"url":"u","thumb_url":"t"}},
+		{"type":"notification","from":"CI"}
+	]}`), &h)
+
+	if want := (MessageSender{ID: 1, Name: "First Last", MentionName: "FirstL"}); h.Items[0].Sender() != want {
+		t.Errorf("Message.Sender returned %+v, want %+v", h.Items[0].Sender(), want)
+	}
+	if want := (&MessageFile{Name: "f.png", Size: 3, URL: "u", ThumbURL: "t"}); !reflect.DeepEqual(h.Items[0].File, want) {
+		t.Errorf("Message.File is %+v, want %+v", h.Items[0].File, want)
+	}
+	if want := (MessageSender{Name: "CI"}); h.Items[1].Sender() != want {
+		t.Errorf("Message.Sender returned %+v, want %+v", h.Items[1].Sender(), want)
+	}
+}
+
+func TestRoomGetMessage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/history/m1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprintf(w, `{"message":{"id":"m1","message":"Hey there!","type":"message","from":"n"}}`)
+	})
+	want := &Message{ID: "m1", Message: "Hey there!", Type: MessageTypeMessage, From: "n"}
+
+	m, _, err := client.Room.GetMessage("1", "m1")
+	if err != nil {
+		t.Fatalf("Room.GetMessage returns an error %v", err)
+	}
+	if !reflect.DeepEqual(want, m) {
+		t.Errorf("Room.GetMessage returned %+v, want %+v", m, want)
+	}
+}
+
+func TestRoomDeleteMessage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/history/m1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	_, err := client.Room.DeleteMessage("1", "m1")
+	if err != nil {
+		t.Fatalf("Room.DeleteMessage returns an error %v", err)
+	}
+}
+
+func TestRoomParticipants(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/participant", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{
+			"max-results":     "10",
+			"include-offline": "true",
+		})
+		fmt.Fprintf(w, `{"items":[{"id":1,"name":"n"}],"startIndex":0,"maxResults":10,"links":{"self":"s"}}`)
+	})
+	want := &Users{Items: []User{{ID: 1, Name: "n"}}, MaxResults: 10, Links: PageLinks{Links: Links{Self: "s"}}}
+
+	opt := &ParticipantsOptions{ListOptions{MaxResults: 10}, true}
+	users, _, err := client.Room.Participants("1", opt)
+	if err != nil {
+		t.Fatalf("Room.Participants returns an error %v", err)
+	}
+	if !reflect.DeepEqual(want, users) {
+		t.Errorf("Room.Participants returned %+v, want %+v", users, want)
+	}
+}
@@ -3,20 +3,37 @@
 package hipchat
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
 
+// Events a room webhook can be registered for.
+const (
+	WebhookEventRoomArchived     = "room_archived"
+	WebhookEventRoomCreated      = "room_created"
+	WebhookEventRoomDeleted      = "room_deleted"
+	WebhookEventRoomEnter        = "room_enter"
+	WebhookEventRoomExit         = "room_exit"
+	WebhookEventRoomFileUpload   = "room_file_upload"
+	WebhookEventRoomMessage      = "room_message"
+	WebhookEventRoomNotification = "room_notification"
+	WebhookEventRoomTopicChange  = "room_topic_change"
+	WebhookEventRoomUnarchived   = "room_unarchived"
+)
+
 // Response Types
 
 // Webhook represents a HipChat webhook.
 type Webhook struct {
-	Links   Links  `json:"links"`
-	Name    string `json:"name"`
-	Event   string `json:"event"`
-	Pattern string `json:"pattern"`
-	URL     string `json:"url"`
-	ID      int    `json:"id,omitempty"`
+	Links          Links  `json:"links"`
+	Name           string `json:"name"`
+	Key            string `json:"key,omitempty"`
+	Event          string `json:"event"`
+	Pattern        string `json:"pattern"`
+	URL            string `json:"url"`
+	Authentication string `json:"authentication,omitempty"`
+	ID             int    `json:"id,omitempty"`
 }
 
 // WebhookList represents a HipChat webhook list.
@@ -36,16 +53,34 @@ type ListWebhooksOptions struct {
 
 // CreateWebhookRequest represents the body of the CreateWebhook method.
 type CreateWebhookRequest struct {
-	Name    string `json:"name"`
-	Event   string `json:"event"`
-	Pattern string `json:"pattern"`
+	Name string `json:"name,omitempty"`
+	// Key identifies the webhook among those of the add-on.
+	Key   string `json:"key,omitempty"`
+	Event string `json:"event"`
+	// Pattern is a regular expression room_message webhooks are only sent
+	// for messages matching it.
+	Pattern string `json:"pattern,omitempty"`
 	URL     string `json:"url"`
+	// Authentication is how the webhook requests are signed, one of the
+	// WebhookAuthentication constants.
+	Authentication string `json:"authentication,omitempty"`
 }
 
+// Authentication methods of the requests sent by a webhook.
+const (
+	WebhookAuthenticationJWT  = "jwt"
+	WebhookAuthenticationNone = "none"
+)
+
 // ListWebhooks returns all the webhooks for a given room.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/get_all_webhooks
 func (r *RoomService) ListWebhooks(id interface{}, opt *ListWebhooksOptions) (*WebhookList, *http.Response, error) {
+	return r.ListWebhooksContext(context.Background(), id, opt)
+}
+
+// ListWebhooksContext is like ListWebhooks, with ctx controlling the request.
+func (r *RoomService) ListWebhooksContext(ctx context.Context, id interface{}, opt *ListWebhooksOptions) (*WebhookList, *http.Response, error) {
 	u := fmt.Sprintf("room/%v/webhook", id)
 	req, err := r.client.NewRequest("GET", u, opt, nil)
 	if err != nil {
@@ -53,23 +88,52 @@ func (r *RoomService) ListWebhooks(id interface{}, opt *ListWebhooksOptions) (*W
 	}
 	whList := new(WebhookList)
 
-	resp, err := r.client.Do(req, whList)
+	resp, err := r.client.DoContext(ctx, req, whList)
 	if err != nil {
 		return nil, resp, err
 	}
 	return whList, resp, nil
 }
 
+// GetWebhook returns the given webhook.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/get_webhook
+func (r *RoomService) GetWebhook(id interface{}, webhookID interface{}) (*Webhook, *http.Response, error) {
+	return r.GetWebhookContext(context.Background(), id, webhookID)
+}
+
+// GetWebhookContext is like GetWebhook, with ctx controlling the request.
+func (r *RoomService) GetWebhookContext(ctx context.Context, id interface{}, webhookID interface{}) (*Webhook, *http.Response, error) {
+	req, err := r.client.NewRequest("GET", fmt.Sprintf("room/%v/webhook/%v", id, webhookID), nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wh := new(Webhook)
+
+	resp, err := r.client.DoContext(ctx, req, wh)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return wh, resp, nil
+}
+
 // DeleteWebhook removes the given webhook.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/delete_webhook
 func (r *RoomService) DeleteWebhook(id interface{}, webhookID interface{}) (*http.Response, error) {
+	return r.DeleteWebhookContext(context.Background(), id, webhookID)
+}
+
+// DeleteWebhookContext is like DeleteWebhook, with ctx controlling the request.
+func (r *RoomService) DeleteWebhookContext(ctx context.Context, id interface{}, webhookID interface{}) (*http.Response, error) {
 	req, err := r.client.NewRequest("DELETE", fmt.Sprintf("room/%v/webhook/%v", id, webhookID), nil, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := r.client.Do(req, nil)
+	resp, err := r.client.DoContext(ctx, req, nil)
 	if err != nil {
 		return resp, err
 	}
@@ -81,6 +145,11 @@ func (r *RoomService) DeleteWebhook(id interface{}, webhookID interface{}) (*htt
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/create_webhook
 func (r *RoomService) CreateWebhook(id interface{}, roomReq *CreateWebhookRequest) (*Webhook, *http.Response, error) {
+	return r.CreateWebhookContext(context.Background(), id, roomReq)
+}
+
+// CreateWebhookContext is like CreateWebhook, with ctx controlling the request.
+func (r *RoomService) CreateWebhookContext(ctx context.Context, id interface{}, roomReq *CreateWebhookRequest) (*Webhook, *http.Response, error) {
 	req, err := r.client.NewRequest("POST", fmt.Sprintf("room/%v/webhook", id), nil, roomReq)
 	if err != nil {
 		return nil, nil, err
@@ -88,7 +157,7 @@ func (r *RoomService) CreateWebhook(id interface{}, roomReq *CreateWebhookReques
 
 	wh := new(Webhook)
 
-	resp, err := r.client.Do(req, wh)
+	resp, err := r.client.DoContext(ctx, req, wh)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -3,6 +3,7 @@ package hipchat
 import (
 	// "encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"reflect"
 	"testing"
@@ -78,3 +79,63 @@ func TestWebhookDelete(t *testing.T) {
 		t.Fatalf("Room.Update returns an error %v", err)
 	}
 }
+
+func TestWebhookGet(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/webhook/2", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprintf(w, `{"name":"a", "pattern":"a", "event":"room_message", "url":"h", "id":2, "links":{"self":"s"}}`)
+	})
+
+	want := &Webhook{
+		Name:    "a",
+		Pattern: "a",
+		Event:   WebhookEventRoomMessage,
+		URL:     "h",
+		ID:      2,
+		Links:   Links{Self: "s"},
+	}
+
+	actual, _, err := client.Room.GetWebhook("1", 2)
+	if err != nil {
+		t.Fatalf("Room.GetWebhook returns an error %v", err)
+	}
+	if !reflect.DeepEqual(want, actual) {
+		t.Errorf("Room.GetWebhook returned %+v, want %+v", actual, want)
+	}
+}
+
+func TestWebhookCreate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	args := &CreateWebhookRequest{
+		Key:            "deploys",
+		Event:          WebhookEventRoomMessage,
+		Pattern:        "^/deploy",
+		URL:            "h",
+		Authentication: WebhookAuthenticationJWT,
+	}
+
+	mux.HandleFunc("/room/1/webhook", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		body, _ := ioutil.ReadAll(r.Body)
+		want := `{"key":"deploys","event":"room_message","pattern":"^/deploy","url":"h","authentication":"jwt"}` + "\n"
+		if string(body) != want {
+			t.Errorf("Request body %s, want %s", body, want)
+		}
+		fmt.Fprintf(w, `{"id":2, "links":{"self":"s"}}`)
+	})
+
+	want := &Webhook{ID: 2, Links: Links{Self: "s"}}
+
+	actual, _, err := client.Room.CreateWebhook("1", args)
+	if err != nil {
+		t.Fatalf("Room.CreateWebhook returns an error %v", err)
+	}
+	if !reflect.DeepEqual(want, actual) {
+		t.Errorf("Room.CreateWebhook returned %+v, want %+v", actual, want)
+	}
+}
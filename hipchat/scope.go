@@ -0,0 +1,69 @@
+package hipchat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ScopeError is the error returned by RequireScopes when the token of the
+// client lacks scopes, typically because they are missing from the
+// capabilities descriptor of the add-on or were added after its installation.
+type ScopeError struct {
+	// Missing are the required scopes the token does not have.
+	Missing []string
+	// Granted are the scopes the token has.
+	Granted []string
+}
+
+func (e *ScopeError) Error() string {
+	return fmt.Sprintf("hipchat: token lacks scope %s (granted: %s); declare it in the descriptor and reinstall the add-on",
+		strings.Join(e.Missing, ", "), strings.Join(e.Granted, ", "))
+}
+
+// Is reports whether target is ErrForbidden, the error HipChat would have
+// returned for the call.
+func (e *ScopeError) Is(target error) bool {
+	return target == ErrForbidden
+}
+
+// RequireScopes returns a *ScopeError if the token of the client lacks any of
+// the given scopes, so that a misconfigured add-on fails with a descriptive
+// error before making calls that would be rejected with a 403. The session of
+// the token is fetched once with GetSession and cached until it expires.
+func (c *Client) RequireScopes(scopes ...string) error {
+	return c.RequireScopesContext(context.Background(), scopes...)
+}
+
+// RequireScopesContext is like RequireScopes, with ctx controlling the request.
+func (c *Client) RequireScopesContext(ctx context.Context, scopes ...string) error {
+	session, err := c.cachedSession(ctx)
+	if err != nil {
+		return err
+	}
+	if missing := session.MissingScopes(scopes...); len(missing) > 0 {
+		return &ScopeError{Missing: missing, Granted: session.Scopes}
+	}
+	return nil
+}
+
+// cachedSession returns the session of the token of c, fetching it if it is
+// not cached or expired.
+func (c *Client) cachedSession(ctx context.Context) (*OAuthSession, error) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+
+	if c.session != nil {
+		expires := c.sessionAt.Add(time.Duration(c.session.ExpiresIn) * time.Second)
+		if c.session.ExpiresIn <= 0 || time.Now().Before(expires) {
+			return c.session, nil
+		}
+	}
+	session, _, err := c.GetSessionContext(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	c.session, c.sessionAt = session, time.Now()
+	return session, nil
+}
@@ -0,0 +1,39 @@
+package hipchat
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRequireScopes(t *testing.T) {
+	setup()
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc("/oauth/token/AuthToken", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		calls++
+		fmt.Fprintf(w, `{"access_token": "AuthToken", "expires_in": 3600, "scopes": ["send_notification"]}`)
+	})
+
+	if err := client.RequireScopes(ScopeSendNotification); err != nil {
+		t.Fatalf("RequireScopes returns an error %v", err)
+	}
+	err := client.RequireScopes(ScopeSendNotification, ScopeViewGroup)
+	var scopeErr *ScopeError
+	if !errors.As(err, &scopeErr) {
+		t.Fatalf("RequireScopes returned %v, want a *ScopeError", err)
+	}
+	if want := []string{ScopeViewGroup}; !reflect.DeepEqual(scopeErr.Missing, want) {
+		t.Errorf("ScopeError.Missing is %v, want %v", scopeErr.Missing, want)
+	}
+	if !errors.Is(err, ErrForbidden) {
+		t.Errorf("RequireScopes returned %v, want it to match ErrForbidden", err)
+	}
+	if calls != 1 {
+		t.Errorf("GetSession called %d times, want the session cached", calls)
+	}
+}
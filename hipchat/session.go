@@ -0,0 +1,82 @@
+package hipchat
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultSessionTTL is the lifetime of session tokens issued with a zero ttl.
+const DefaultSessionTTL = 15 * time.Minute
+
+// sessionAudience is the aud claim of session tokens, which tells them apart
+// from tokens signed by HipChat.
+const sessionAudience = "addon-session"
+
+// ErrNoSessionSecret is returned when session tokens are used without a
+// secret configured with WithSessionSecret.
+var ErrNoSessionSecret = errors.New("no session secret configured")
+
+// WithSessionSecret sets the secret used to sign and verify session tokens.
+// It must be kept private to the add-on and shared by all its instances.
+func WithSessionSecret(secret []byte) IntegrationOption {
+	return func(i *Integration) {
+		i.sessionSecret = secret
+	}
+}
+
+// IssueSessionToken returns a token signed by the add-on that carries params,
+// valid for ttl or DefaultSessionTTL if ttl is zero. Single-page apps served in
+// glances, sidebars or dialogs can send it back with their follow-up requests
+// instead of the signed_request they were loaded with.
+func (i *Integration) IssueSessionToken(params *SignedParams, ttl time.Duration) (string, error) {
+	if len(i.sessionSecret) == 0 {
+		return "", ErrNoSessionSecret
+	}
+	if ttl == 0 {
+		ttl = DefaultSessionTTL
+	}
+
+	now := time.Now()
+	return codec.Sign(map[string]interface{}{
+		"aud": sessionAudience,
+		"iss": params.Issuer,
+		"sub": params.UserID,
+		"iat": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+		"context": map[string]interface{}{
+			"room_id":   params.RoomID,
+			"room_name": params.RoomName,
+			"group_id":  params.GroupID,
+			"user_name": params.UserName,
+			"user_tz":   params.UserTimezone,
+		},
+	}, i.sessionSecret)
+}
+
+// ValidateSessionToken verifies a token issued by IssueSessionToken and
+// returns the SignedParams it carries. ErrTokenExpired is returned for
+// expired tokens.
+func (i *Integration) ValidateSessionToken(tokenStr string) (*SignedParams, error) {
+	if len(i.sessionSecret) == 0 {
+		return nil, ErrNoSessionSecret
+	}
+
+	rawClaims, err := codec.Parse(tokenStr, func(map[string]interface{}) ([][]byte, error) {
+		return [][]byte{i.sessionSecret}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	claims, err := decodeClaims(rawClaims)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.checkTimes(claims); err != nil {
+		return nil, err
+	}
+	if claims.Audience != sessionAudience {
+		return nil, fmt.Errorf("%w: not a session token", ErrInvalidClaims)
+	}
+	return NewSignedParams(claims)
+}
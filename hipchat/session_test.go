@@ -0,0 +1,62 @@
+package hipchat
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSessionToken(t *testing.T) {
+	i := NewIntegration(nil, WithSessionSecret([]byte("secret")))
+	params := &SignedParams{Issuer: "oauth", UserID: "1", GroupID: 2, RoomID: 3, UserTimezone: "UTC"}
+
+	tok, err := i.IssueSessionToken(params, 0)
+	if err != nil {
+		t.Fatalf("IssueSessionToken returned an error %v", err)
+	}
+	got, err := i.ValidateSessionToken(tok)
+	if err != nil {
+		t.Fatalf("ValidateSessionToken returned an error %v", err)
+	}
+	got.IssuedAt, got.ExpiresAt = params.IssuedAt, params.ExpiresAt
+	if !reflect.DeepEqual(got, params) {
+		t.Errorf("ValidateSessionToken returned %+v, want %+v", got, params)
+	}
+
+	other := NewIntegration(nil, WithSessionSecret([]byte("other")))
+	if _, err := other.ValidateSessionToken(tok); err == nil {
+		t.Errorf("ValidateSessionToken accepted a token signed with another secret")
+	}
+}
+
+func TestSessionToken_Errors(t *testing.T) {
+	i := NewIntegration(nil, WithSessionSecret([]byte("secret")))
+	params := &SignedParams{Issuer: "oauth", UserID: "1", GroupID: 2, RoomID: 3, UserTimezone: "UTC"}
+	expired, _ := i.IssueSessionToken(params, -time.Minute)
+	hipchatToken, _ := codec.Sign(testClaims("oauth"), []byte("secret"))
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"expired", expired, ErrTokenExpired},
+		{"token signed by HipChat", hipchatToken, ErrInvalidClaims},
+		{"malformed", "not.a.jwt", ErrMalformedToken},
+		{"empty", "", ErrMalformedToken},
+	}
+	for _, tt := range tests {
+		if _, err := i.ValidateSessionToken(tt.token); !errors.Is(err, tt.want) {
+			t.Errorf("ValidateSessionToken of %s token returned %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	noSecret := NewIntegration(nil)
+	if _, err := noSecret.IssueSessionToken(params, 0); err != ErrNoSessionSecret {
+		t.Errorf("IssueSessionToken without secret returned %v, want %v", err, ErrNoSessionSecret)
+	}
+	if _, err := noSecret.ValidateSessionToken(expired); err != ErrNoSessionSecret {
+		t.Errorf("ValidateSessionToken without secret returned %v, want %v", err, ErrNoSessionSecret)
+	}
+}
@@ -0,0 +1,80 @@
+package hipchat
+
+import "sync"
+
+// SettingsStore persists the settings of installations, e.g. those edited on
+// the configuration page of the add-on, keyed by their oauthId.
+type SettingsStore interface {
+	// GetSettings returns the settings of the installation, or nil if there
+	// are none.
+	GetSettings(oAuthID string) (map[string]string, error)
+	SaveSettings(oAuthID string, settings map[string]string) error
+	DeleteSettings(oAuthID string) error
+}
+
+// MemorySettingsStore is a SettingsStore safe for concurrent use that keeps
+// settings in memory.
+type MemorySettingsStore struct {
+	mu       sync.RWMutex
+	settings map[string]map[string]string
+}
+
+// NewMemorySettingsStore returns an empty MemorySettingsStore.
+func NewMemorySettingsStore() *MemorySettingsStore {
+	return &MemorySettingsStore{settings: make(map[string]map[string]string)}
+}
+
+// GetSettings returns a copy of the settings saved for oAuthID, if any.
+func (s *MemorySettingsStore) GetSettings(oAuthID string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copySettings(s.settings[oAuthID]), nil
+}
+
+// SaveSettings replaces the settings saved for oAuthID by a copy of settings.
+func (s *MemorySettingsStore) SaveSettings(oAuthID string, settings map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings[oAuthID] = copySettings(settings)
+	return nil
+}
+
+// DeleteSettings removes the settings saved for oAuthID.
+func (s *MemorySettingsStore) DeleteSettings(oAuthID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.settings, oAuthID)
+	return nil
+}
+
+func copySettings(settings map[string]string) map[string]string {
+	if settings == nil {
+		return nil
+	}
+	c := make(map[string]string, len(settings))
+	for k, v := range settings {
+		c[k] = v
+	}
+	return c
+}
+
+// WithSettingsStore sets the store of the settings of installations. By
+// default settings are kept in memory. The settings of an installation are
+// deleted when it is removed.
+func WithSettingsStore(store SettingsStore) IntegrationOption {
+	return func(i *Integration) {
+		i.settings = store
+	}
+}
+
+// Settings returns the settings of the installation a request was signed
+// for, e.g. in the handler of the configuration page.
+func (i *Integration) Settings(params *SignedParams) (map[string]string, error) {
+	return i.settings.GetSettings(params.Issuer)
+}
+
+// SaveSettings saves the settings of the installation a request was signed
+// for.
+func (i *Integration) SaveSettings(params *SignedParams, settings map[string]string) error {
+	return i.settings.SaveSettings(params.Issuer, settings)
+}
@@ -0,0 +1,29 @@
+package hipchat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIntegrationSettings(t *testing.T) {
+	i := NewIntegration(nil)
+	params := &SignedParams{Issuer: "oauth"}
+
+	settings := map[string]string{"channel": "builds"}
+	if err := i.SaveSettings(params, settings); err != nil {
+		t.Fatalf("SaveSettings returns an error %v", err)
+	}
+	settings["channel"] = "modified"
+
+	got, err := i.Settings(params)
+	if err != nil {
+		t.Fatalf("Settings returns an error %v", err)
+	}
+	if want := map[string]string{"channel": "builds"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Settings returned %v, want %v", got, want)
+	}
+
+	if got, _ := i.Settings(&SignedParams{Issuer: "other"}); got != nil {
+		t.Errorf("Settings of another installation returned %v, want nil", got)
+	}
+}
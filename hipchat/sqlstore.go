@@ -1,6 +1,7 @@
 package hipchat
 
 import (
+	"context"
 	"database/sql"
 	"log"
 )
@@ -19,6 +20,16 @@ func NewSqlStore(driverName string, dataSourceName string) (Store, error) {
 	return &SqlStore{db}, nil
 }
 
+// Ping verifies that the database of the SqlStore can be reached.
+func (s *SqlStore) Ping() error {
+	return s.db.Ping()
+}
+
+// PingContext is like Ping, but gives up when ctx is done.
+func (s *SqlStore) PingContext(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
 func (s *SqlStore) GetGroupID(roomID uint32) (uint32, error) {
 	var result uint32
 	log.Printf("Looking up group-id for room-id: %v", roomID)
@@ -36,14 +47,19 @@ func (s *SqlStore) GetGroupID(roomID uint32) (uint32, error) {
 	}
 }
 
-// SaveCredentials saves a group's credentials to the SqlStore
+// SaveCredentials saves a group's credentials to the SqlStore, replacing any
+// existing credentials with the same oauthId.
 func (s *SqlStore) SaveCredentials(i *InstallRecord) error {
 	_, err := s.db.Exec(
 		`INSERT INTO installation (
             capabilitiesUrl, oauthId, oauthSecret, groupId, roomId
         ) VALUES (
             $1, $2, $3, $4, $5
-        )`,
+        ) ON CONFLICT (oauthId) DO UPDATE SET
+            capabilitiesUrl = EXCLUDED.capabilitiesUrl,
+            oauthSecret = EXCLUDED.oauthSecret,
+            groupId = EXCLUDED.groupId,
+            roomId = EXCLUDED.roomId`,
 		i.CapabilitiesURL, i.OAuthID, i.OAuthSecret, i.GroupID, i.RoomID)
 	return err
 }
@@ -70,9 +86,63 @@ func (s *SqlStore) GetCredentials(groupID, roomID uint32) (*InstallRecord, error
 	}
 }
 
+// GetGlobalCredentials obtains the credentials of a group's global installation
+// from the SqlStore
+func (s *SqlStore) GetGlobalCredentials(groupID uint32) (*InstallRecord, error) {
+	c := &InstallRecord{}
+	err := s.db.QueryRow(
+		"SELECT capabilitiesUrl, oauthId, oauthSecret, groupId, roomId FROM installation WHERE groupId = $1 AND roomId IS NULL", groupID).Scan(
+		&c.CapabilitiesURL, &c.OAuthID, &c.OAuthSecret, &c.GroupID, &c.RoomID)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	default:
+		return c, nil
+	}
+}
+
+// GetCredentialsByOAuthID obtains an installation's credentials from the SqlStore
+func (s *SqlStore) GetCredentialsByOAuthID(oAuthID string) (*InstallRecord, error) {
+	c := &InstallRecord{}
+	err := s.db.QueryRow(
+		"SELECT capabilitiesUrl, oauthId, oauthSecret, groupId, roomId FROM installation WHERE oauthId = $1", oAuthID).Scan(
+		&c.CapabilitiesURL, &c.OAuthID, &c.OAuthSecret, &c.GroupID, &c.RoomID)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	default:
+		return c, nil
+	}
+}
+
+// ListCredentials obtains the credentials of all installations from the SqlStore
+func (s *SqlStore) ListCredentials() ([]*InstallRecord, error) {
+	rows, err := s.db.Query(
+		"SELECT capabilitiesUrl, oauthId, oauthSecret, groupId, roomId FROM installation ORDER BY groupId, roomId")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*InstallRecord
+	for rows.Next() {
+		c := &InstallRecord{}
+		err := rows.Scan(&c.CapabilitiesURL, &c.OAuthID, &c.OAuthSecret, &c.GroupID, &c.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, c)
+	}
+	return records, rows.Err()
+}
+
 func (s *SqlStore) GetOAuthSecret(oauthID string) (string, error) {
 	var result string
-	
+
 	err := s.db.QueryRow(
 		"SELECT oauthSecret FROM installation WHERE oauthId = $1", oauthID).Scan(&result)
 	switch {
@@ -83,4 +153,37 @@ func (s *SqlStore) GetOAuthSecret(oauthID string) (string, error) {
 	default:
 		return result, nil
 	}
-}
\ No newline at end of file
+}
+
+// RecordEvent saves an installation audit event to the SqlStore
+func (s *SqlStore) RecordEvent(e *AuditEvent) error {
+	_, err := s.db.Exec(
+		`INSERT INTO installation_event (
+            type, time, oauthId, groupId, roomId, source, result
+        ) VALUES (
+            $1, $2, $3, $4, $5, $6, $7
+        )`,
+		e.Type, e.Time, e.OAuthID, e.GroupID, e.RoomID, e.Source, e.Result)
+	return err
+}
+
+// ListEvents obtains the installation audit events of a group from the SqlStore
+func (s *SqlStore) ListEvents(groupID uint32) ([]*AuditEvent, error) {
+	rows, err := s.db.Query(
+		"SELECT type, time, oauthId, groupId, roomId, source, result FROM installation_event WHERE groupId = $1 ORDER BY time, id", groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*AuditEvent
+	for rows.Next() {
+		e := &AuditEvent{}
+		err := rows.Scan(&e.Type, &e.Time, &e.OAuthID, &e.GroupID, &e.RoomID, &e.Source, &e.Result)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -1,9 +1,15 @@
 package hipchat
 
+// Store persists the credentials of installations.
+//
+// SaveCredentials must replace any existing record with the same oauthId, as
+// HipChat may post the same installation more than once.
 type Store interface {
 	SaveCredentials(i *InstallRecord) error
 	DeleteCredentials(oAuthID string) error
 	GetCredentials(groupID, roomID uint32) (*InstallRecord, error)
-	GetGroupID(roomID uint32) (uint32, error) // temporary
+	GetCredentialsByOAuthID(oAuthID string) (*InstallRecord, error)
+	GetGlobalCredentials(groupID uint32) (*InstallRecord, error)
+	GetGroupID(roomID uint32) (uint32, error)      // temporary
 	GetOAuthSecret(oauthID string) (string, error) // Also temporary?
 }
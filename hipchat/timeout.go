@@ -0,0 +1,60 @@
+package hipchat
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+type timeoutKey struct{}
+
+// SetTimeout sets the default time limit of the API calls of the client,
+// retries included, so that a slow HipChat cannot hold a goroutine for
+// minutes. It applies to the calls whose context has no deadline, and can be
+// overridden per call with WithTimeout. Zero, the default, disables it.
+func (c *Client) SetTimeout(d time.Duration) {
+	c.timeout = d
+}
+
+// WithTimeout returns a context that overrides the default timeout of the
+// client for the ...Context API methods it is passed to, e.g.
+//
+//	ctx := hipchat.WithTimeout(context.Background(), 5*time.Second)
+//	_, err := client.Room.NotificationContext(ctx, "1", notifReq)
+//
+// A zero d disables the default timeout for these calls.
+func WithTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
+
+// applyTimeout returns req with the time limit that applies to it, and the
+// function releasing the resources of its context.
+func (c *Client) applyTimeout(req *http.Request) (*http.Request, context.CancelFunc) {
+	ctx := req.Context()
+	d, ok := ctx.Value(timeoutKey{}).(time.Duration)
+	if !ok {
+		if _, hasDeadline := ctx.Deadline(); hasDeadline {
+			return req, func() {}
+		}
+		d = c.timeout
+	}
+	if d <= 0 {
+		return req, func() {}
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	return req.WithContext(ctx), cancel
+}
+
+// cancelOnClose cancels the context of a request once its response body is
+// closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
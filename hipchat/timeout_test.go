@@ -0,0 +1,47 @@
+package hipchat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSetTimeout(t *testing.T) {
+	setup()
+	defer teardown()
+
+	done := make(chan struct{})
+	defer close(done)
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	})
+	client.SetTimeout(10 * time.Millisecond)
+
+	_, err := client.Room.Notification("1", &NotificationRequest{Message: "m"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Room.Notification returned %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/room/1/notification", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	client.SetTimeout(time.Millisecond)
+
+	ctx := WithTimeout(context.Background(), time.Minute)
+	resp, err := client.Room.NotificationContext(ctx, "1", &NotificationRequest{Message: "m"})
+	if err != nil {
+		t.Fatalf("Room.NotificationContext returns an error %v", err)
+	}
+	resp.Body.Close()
+}
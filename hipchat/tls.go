@@ -0,0 +1,108 @@
+package hipchat
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrCertificateNotPinned is returned by the connections configured with
+// PinPublicKeys when the server presents none of the pinned keys.
+var ErrCertificateNotPinned = errors.New("hipchat: server certificate does not match the pinned keys")
+
+// NewTLSConfig returns a TLS configuration trusting the certificates of the
+// system along with those of pemCerts, e.g. the private CA of a HipChat Server
+// or Data Center deployment.
+func NewTLSConfig(pemCerts []byte) (*tls.Config, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pemCerts) {
+		return nil, errors.New("hipchat: no certificate found in PEM data")
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
+
+// PinPublicKeys returns a copy of config, or of the default configuration if
+// config is nil, that only accepts the servers whose verified certificate
+// chain contains one of the given public keys. When the chain is not verified,
+// with InsecureSkipVerify, only the key of the leaf certificate can match.
+// The keys are checked on every handshake, including resumed sessions. pins
+// are the base64-encoded SHA-256 hashes of the DER-encoded
+// SubjectPublicKeyInfo of the keys, as printed by:
+//
+//	openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func PinPublicKeys(config *tls.Config, pins ...string) *tls.Config {
+	if config == nil {
+		config = new(tls.Config)
+	}
+	config = config.Clone()
+	pinned := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		pinned[pin] = true
+	}
+	verify := config.VerifyConnection
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if verify != nil {
+			if err := verify(cs); err != nil {
+				return err
+			}
+		}
+		var certs []*x509.Certificate
+		for _, chain := range cs.VerifiedChains {
+			certs = append(certs, chain...)
+		}
+		if len(cs.VerifiedChains) == 0 && len(cs.PeerCertificates) > 0 {
+			// Verification is disabled: nothing binds the other presented
+			// certificates to the leaf, so only the leaf can be pinned.
+			certs = append(certs, cs.PeerCertificates[0])
+		}
+		for _, cert := range certs {
+			sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			if pinned[base64.StdEncoding.EncodeToString(sum[:])] {
+				return nil
+			}
+		}
+		return ErrCertificateNotPinned
+	}
+	return config
+}
+
+// SetTLSConfig makes the client use config for its connections, e.g. one
+// returned by NewTLSConfig or PinPublicKeys. The HTTP client of the client is
+// replaced by a copy whose transport uses config, so the transport must be an
+// *http.Transport, as that of http.DefaultClient.
+func (c *Client) SetTLSConfig(config *tls.Config) error {
+	httpClient, err := withTransport(c.client, func(t *http.Transport) {
+		t.TLSClientConfig = config
+	})
+	if err != nil {
+		return err
+	}
+	c.client = httpClient
+	return nil
+}
+
+// withTransport returns a copy of httpClient whose transport is a copy of its
+// own configured by configure.
+func withTransport(httpClient *http.Client, configure func(*http.Transport)) (*http.Client, error) {
+	rt := httpClient.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	transport, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("hipchat: cannot configure a %T transport", rt)
+	}
+	transport = transport.Clone()
+	configure(transport)
+
+	hc := *httpClient
+	hc.Transport = transport
+	return &hc, nil
+}
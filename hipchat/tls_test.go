@@ -0,0 +1,195 @@
+package hipchat
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newTLSTestClient(t *testing.T) (*httptest.Server, *Client, string) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	c := NewClient("AuthToken")
+	c.BaseURL, _ = url.Parse(server.URL + "/")
+
+	cert := server.Certificate()
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return server, c, base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestClientSetTLSConfig(t *testing.T) {
+	server, c, pin := newTLSTestClient(t)
+	defer server.Close()
+
+	if _, err := c.Room.Notification("1", &NotificationRequest{Message: "m"}); err == nil {
+		t.Fatal("Room.Notification trusted an unknown CA")
+	}
+
+	config, err := NewTLSConfig(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	if err != nil {
+		t.Fatalf("NewTLSConfig returns an error %v", err)
+	}
+	if err := c.SetTLSConfig(PinPublicKeys(config, pin)); err != nil {
+		t.Fatalf("SetTLSConfig returns an error %v", err)
+	}
+	if _, err := c.Room.Notification("1", &NotificationRequest{Message: "m"}); err != nil {
+		t.Errorf("Room.Notification returns an error %v", err)
+	}
+
+	if err := c.SetTLSConfig(PinPublicKeys(config, "AAAA")); err != nil {
+		t.Fatalf("SetTLSConfig returns an error %v", err)
+	}
+	if _, err := c.Room.Notification("1", &NotificationRequest{Message: "m"}); !errors.Is(err, ErrCertificateNotPinned) {
+		t.Errorf("Room.Notification returned %v, want %v", err, ErrCertificateNotPinned)
+	}
+}
+
+func TestClientSetTLSConfig_CustomTransport(t *testing.T) {
+	c := NewClientWithHTTPClient("AuthToken", &http.Client{Transport: rewriteTransport{}})
+
+	if err := c.SetTLSConfig(nil); err == nil {
+		t.Error("SetTLSConfig accepted a transport it cannot configure")
+	}
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	server, _, pin := newTLSTestClient(t)
+	defer server.Close()
+
+	i := NewIntegration(nil, WithTLSConfig(PinPublicKeys(nil, pin)))
+	transport, ok := i.httpClient.Transport.(*http.Transport)
+	if !ok || transport.TLSClientConfig == nil || transport.TLSClientConfig.VerifyConnection == nil {
+		t.Errorf("WithTLSConfig did not configure the transport of the integration")
+	}
+	if config := http.DefaultTransport.(*http.Transport).TLSClientConfig; config != nil && config.VerifyConnection != nil {
+		t.Errorf("WithTLSConfig modified http.DefaultTransport")
+	}
+}
+
+// testCert returns a certificate for template, signed by parent and its key,
+// or self-signed if parent is nil.
+func testCert(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestPinPublicKeys_ForgedLeafUnverified(t *testing.T) {
+	ca := func(serial int64, name string) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber:          big.NewInt(serial),
+			Subject:               pkix.Name{CommonName: name},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}
+	}
+	root, rootKey := testCert(t, ca(1, "root"), nil, nil)
+	intermediate, _ := testCert(t, ca(2, "intermediate"), root, rootKey)
+	forged, forgedKey := testCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, nil, nil)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request reached a server presenting a forged leaf")
+	}))
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{
+		// The forged leaf comes with a copy of the real, public, intermediate.
+		Certificate: [][]byte{forged.Raw, intermediate.Raw},
+		PrivateKey:  forgedKey,
+	}}}
+	server.StartTLS()
+	defer server.Close()
+
+	sum := sha256.Sum256(intermediate.RawSubjectPublicKeyInfo)
+	pin := base64.StdEncoding.EncodeToString(sum[:])
+	c := NewClient("AuthToken")
+	c.BaseURL, _ = url.Parse(server.URL + "/")
+	if err := c.SetTLSConfig(PinPublicKeys(&tls.Config{InsecureSkipVerify: true}, pin)); err != nil {
+		t.Fatalf("SetTLSConfig returns an error %v", err)
+	}
+
+	if _, err := c.Room.Notification("1", &NotificationRequest{Message: "m"}); !errors.Is(err, ErrCertificateNotPinned) {
+		t.Errorf("Room.Notification returned %v, want %v", err, ErrCertificateNotPinned)
+	}
+}
+
+func TestPinPublicKeys_LeafUnverified(t *testing.T) {
+	server, c, pin := newTLSTestClient(t)
+	defer server.Close()
+
+	if err := c.SetTLSConfig(PinPublicKeys(&tls.Config{InsecureSkipVerify: true}, pin)); err != nil {
+		t.Fatalf("SetTLSConfig returns an error %v", err)
+	}
+	if _, err := c.Room.Notification("1", &NotificationRequest{Message: "m"}); err != nil {
+		t.Errorf("Room.Notification returns an error %v", err)
+	}
+}
+
+func TestPinPublicKeys_ResumedSession(t *testing.T) {
+	server, _, pin := newTLSTestClient(t)
+	defer server.Close()
+
+	config, err := NewTLSConfig(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	if err != nil {
+		t.Fatalf("NewTLSConfig returns an error %v", err)
+	}
+	// Both configurations share the session cache, so that the second
+	// handshake resumes the session of the first.
+	config.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+	get := func(config *tls.Config) (*http.Response, error) {
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: config, DisableKeepAlives: true}}
+		resp, err := c.Get(server.URL)
+		if err == nil {
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	if _, err := get(PinPublicKeys(config, pin)); err != nil {
+		t.Fatalf("GET returns an error %v", err)
+	}
+	resp, err := get(PinPublicKeys(config, "AAAA"))
+	if !errors.Is(err, ErrCertificateNotPinned) {
+		t.Errorf("GET returned %v, want %v", err, ErrCertificateNotPinned)
+	}
+	if resp != nil && resp.TLS != nil && resp.TLS.DidResume {
+		t.Errorf("GET resumed a session with unpinned keys")
+	}
+}
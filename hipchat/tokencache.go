@@ -0,0 +1,59 @@
+package hipchat
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenCache caches the access tokens of installations. Keys are "groupid:roomid"
+// for room installations and "groupid:" for global installations.
+type TokenCache interface {
+	// Get returns the token cached for key, unless it has expired.
+	Get(key string) (token string, ok bool)
+	// Set caches token for key until expiresAt. A zero expiresAt never
+	// expires.
+	Set(key, token string, expiresAt time.Time)
+	Delete(key string)
+}
+
+// MemoryTokenCache is a TokenCache safe for concurrent use that keeps tokens in memory.
+type MemoryTokenCache struct {
+	mu     sync.RWMutex
+	tokens map[string]cachedToken
+}
+
+// cachedToken is a token kept by a MemoryTokenCache.
+type cachedToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// NewMemoryTokenCache returns an empty MemoryTokenCache.
+func NewMemoryTokenCache() *MemoryTokenCache {
+	return &MemoryTokenCache{tokens: make(map[string]cachedToken)}
+}
+
+// Get returns the token cached for key, if any and not expired.
+func (c *MemoryTokenCache) Get(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cached, ok := c.tokens[key]
+	if !ok || (!cached.expiresAt.IsZero() && !time.Now().Before(cached.expiresAt)) {
+		return "", false
+	}
+	return cached.token, true
+}
+
+// Set caches token for key until expiresAt, or forever if expiresAt is zero.
+func (c *MemoryTokenCache) Set(key, token string, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens[key] = cachedToken{token: token, expiresAt: expiresAt}
+}
+
+// Delete removes the token cached for key.
+func (c *MemoryTokenCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tokens, key)
+}
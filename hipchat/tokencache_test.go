@@ -0,0 +1,34 @@
+package hipchat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryTokenCache(t *testing.T) {
+	c := NewMemoryTokenCache()
+	c.Set("1:", "forever", time.Time{})
+	c.Set("2:", "valid", time.Now().Add(time.Minute))
+	c.Set("3:", "expired", time.Now().Add(-time.Second))
+
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"1:", "forever", true},
+		{"2:", "valid", true},
+		{"3:", "", false},
+		{"4:", "", false},
+	}
+	for _, tt := range tests {
+		if token, ok := c.Get(tt.key); token != tt.want || ok != tt.wantOK {
+			t.Errorf("Get(%q) returned %q, %v, want %q, %v", tt.key, token, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	c.Delete("1:")
+	if _, ok := c.Get("1:"); ok {
+		t.Errorf("Get returned a deleted token")
+	}
+}
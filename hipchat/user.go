@@ -1,6 +1,7 @@
 package hipchat
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
@@ -14,10 +15,17 @@ type MessageRequest struct {
 
 // UserPresence represents the HipChat user's presence.
 type UserPresence struct {
-	Status   string `json:"status"`
-	Idle     int    `json:"idle"`
-	Show     string `json:"show"`
-	IsOnline bool   `json:"is_online"`
+	Status   string              `json:"status"`
+	Idle     int                 `json:"idle"`
+	Show     string              `json:"show"`
+	Client   *UserPresenceClient `json:"client,omitempty"`
+	IsOnline bool                `json:"is_online"`
+}
+
+// UserPresenceClient represents the client a HipChat user is connected with.
+type UserPresenceClient struct {
+	Type    string `json:"type"`
+	Version string `json:"version"`
 }
 
 // User represents the HipChat user.
@@ -36,15 +44,16 @@ type User struct {
 	IsGuest      bool         `json:"is_guest"`
 	Email        string       `json:"email"`
 	PhotoURL     string       `json:"photo_url"`
+	Group        *Group       `json:"group,omitempty"`
 	Links        Links        `json:"links"`
 }
 
 // Users represents the API return of a collection of Users plus metadata
 type Users struct {
-	Items      []User `json:"items"`
-	StartIndex int    `json:"start_index"`
-	MaxResults int    `json:"max_results"`
-	Links      Links  `json:"links"`
+	Items      []User    `json:"items"`
+	StartIndex int       `json:"startIndex"`
+	MaxResults int       `json:"maxResults"`
+	Links      PageLinks `json:"links"`
 }
 
 // UserService gives access to the user related methods of the API.
@@ -52,42 +61,63 @@ type UserService struct {
 	client *Client
 }
 
-// ShareFile sends a file to the user specified by the id.
+// ShareFile sends a file to the user specified by the id, email or @mention
+// name, in a private chat.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/share_file_with_user
 func (u *UserService) ShareFile(id string, shareFileReq *ShareFileRequest) (*http.Response, error) {
+	return u.ShareFileContext(context.Background(), id, shareFileReq)
+}
+
+// ShareFileContext is like ShareFile, with ctx controlling the request.
+func (u *UserService) ShareFileContext(ctx context.Context, id string, shareFileReq *ShareFileRequest) (*http.Response, error) {
 	req, err := u.client.NewFileUploadRequest("POST", fmt.Sprintf("user/%s/share/file", id), shareFileReq)
 	if err != nil {
 		return nil, err
 	}
 
-	return u.client.Do(req, nil)
+	return u.client.DoContext(ctx, req, nil)
 }
 
-// View fetches a user's details.
+// View fetches a user's details. The user is specified by id, email or
+// @mention name.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/view_user
 func (u *UserService) View(id string) (*User, *http.Response, error) {
+	return u.ViewContext(context.Background(), id)
+}
+
+// ViewContext is like View, with ctx controlling the request.
+func (u *UserService) ViewContext(ctx context.Context, id string) (*User, *http.Response, error) {
 	req, err := u.client.NewRequest("GET", fmt.Sprintf("user/%s", id), nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	userDetails := new(User)
-	resp, err := u.client.Do(req, &userDetails)
+	resp, err := u.client.DoContext(ctx, req, &userDetails)
 	if err != nil {
 		return nil, resp, err
 	}
 	return userDetails, resp, nil
 }
 
-// Message sends a private message to the user specified by the id.
+// Message sends a private message to the user specified by the id, which
+// can also be the email or the @mention name of the user.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/private_message_user
 func (u *UserService) Message(id string, msgReq *MessageRequest) (*http.Response, error) {
+	return u.MessageContext(context.Background(), id, msgReq)
+}
+
+// MessageContext is like Message, with ctx controlling the request.
+func (u *UserService) MessageContext(ctx context.Context, id string, msgReq *MessageRequest) (*http.Response, error) {
 	req, err := u.client.NewRequest("POST", fmt.Sprintf("user/%s/message", id), nil, msgReq)
 	if err != nil {
 		return nil, err
 	}
 
-	return u.client.Do(req, nil)
+	return u.client.DoContext(ctx, req, nil)
 }
 
 // UserListOptions specified the parameters to the UserService.List method.
@@ -99,16 +129,182 @@ type UserListOptions struct {
 	IncludeDeleted bool `url:"include-deleted,omitempty"`
 }
 
-// List returns all users in the group.
+// List returns the users in the group of the page given by opt. See
+// ListWithPage for the paging fields of the response.
 //
 // HipChat API docs: https://www.hipchat.com/docs/apiv2/method/get_all_users
 func (u *UserService) List(opt *UserListOptions) ([]User, *http.Response, error) {
+	return u.ListContext(context.Background(), opt)
+}
+
+// ListContext is like List, with ctx controlling the request.
+func (u *UserService) ListContext(ctx context.Context, opt *UserListOptions) ([]User, *http.Response, error) {
+	users, resp, err := u.ListWithPageContext(ctx, opt)
+	if err != nil {
+		return nil, resp, err
+	}
+	return users.Items, resp, nil
+}
+
+// ListWithPage is like List, returning the page of users along with its
+// start index, size and link to the next page.
+func (u *UserService) ListWithPage(opt *UserListOptions) (*Users, *http.Response, error) {
+	return u.ListWithPageContext(context.Background(), opt)
+}
+
+// ListWithPageContext is like ListWithPage, with ctx controlling the request.
+func (u *UserService) ListWithPageContext(ctx context.Context, opt *UserListOptions) (*Users, *http.Response, error) {
 	req, err := u.client.NewRequest("GET", "user", opt, nil)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	users := new(Users)
-	resp, err := u.client.Do(req, &users)
+	resp, err := u.client.DoContext(ctx, req, users)
 	if err != nil {
 		return nil, resp, err
 	}
-	return users.Items, resp, nil
+	return users, resp, nil
+}
+
+// CreateUserRequest represents a HipChat user creation request.
+type CreateUserRequest struct {
+	Name         string `json:"name"`
+	Title        string `json:"title,omitempty"`
+	MentionName  string `json:"mention_name,omitempty"`
+	IsGroupAdmin bool   `json:"is_group_admin,omitempty"`
+	Timezone     string `json:"timezone,omitempty"`
+	Password     string `json:"password,omitempty"`
+	Email        string `json:"email"`
+}
+
+// UpdateUserRequest represents a HipChat user update request. All the fields
+// are sent, so start from the current values of the user.
+type UpdateUserRequest struct {
+	Name         string             `json:"name"`
+	Roles        []string           `json:"roles,omitempty"`
+	Title        string             `json:"title"`
+	Presence     UpdateUserPresence `json:"presence"`
+	MentionName  string             `json:"mention_name"`
+	IsGroupAdmin bool               `json:"is_group_admin"`
+	Timezone     string             `json:"timezone"`
+	Password     string             `json:"password,omitempty"`
+	Email        string             `json:"email"`
+}
+
+// UpdateUserPresence represents the presence part of a HipChat user update request.
+type UpdateUserPresence struct {
+	Status string `json:"status,omitempty"`
+	Show   string `json:"show,omitempty"`
+}
+
+// Create creates a new user in the group.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/create_user
+func (u *UserService) Create(userReq *CreateUserRequest) (*User, *http.Response, error) {
+	return u.CreateContext(context.Background(), userReq)
+}
+
+// CreateContext is like Create, with ctx controlling the request.
+func (u *UserService) CreateContext(ctx context.Context, userReq *CreateUserRequest) (*User, *http.Response, error) {
+	req, err := u.client.NewRequest("POST", "user", nil, userReq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user := new(User)
+	resp, err := u.client.DoContext(ctx, req, user)
+	if err != nil {
+		return nil, resp, err
+	}
+	return user, resp, nil
+}
+
+// Update updates the user specified by the id, email or @mention name.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/update_user
+func (u *UserService) Update(id string, userReq *UpdateUserRequest) (*http.Response, error) {
+	return u.UpdateContext(context.Background(), id, userReq)
+}
+
+// UpdateContext is like Update, with ctx controlling the request.
+func (u *UserService) UpdateContext(ctx context.Context, id string, userReq *UpdateUserRequest) (*http.Response, error) {
+	req, err := u.client.NewRequest("PUT", fmt.Sprintf("user/%s", id), nil, userReq)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.client.DoContext(ctx, req, nil)
+}
+
+// Delete deletes the user specified by the id, email or @mention name.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/delete_user
+func (u *UserService) Delete(id string) (*http.Response, error) {
+	return u.DeleteContext(context.Background(), id)
+}
+
+// DeleteContext is like Delete, with ctx controlling the request.
+func (u *UserService) DeleteContext(ctx context.Context, id string) (*http.Response, error) {
+	req, err := u.client.NewRequest("DELETE", fmt.Sprintf("user/%s", id), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.client.DoContext(ctx, req, nil)
+}
+
+// Values of UserPresence.Show.
+const (
+	ShowAway      = "away"
+	ShowChat      = "chat"
+	ShowDND       = "dnd"
+	ShowExtAway   = "xa"
+	ShowAvailable = ""
+)
+
+// Presence returns the presence of the user specified by the id, email or
+// @mention name.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/view_user
+func (u *UserService) Presence(id string) (*UserPresence, *http.Response, error) {
+	return u.PresenceContext(context.Background(), id)
+}
+
+// PresenceContext is like Presence, with ctx controlling the request.
+func (u *UserService) PresenceContext(ctx context.Context, id string) (*UserPresence, *http.Response, error) {
+	user, resp, err := u.ViewContext(ctx, id)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &user.Presence, resp, nil
+}
+
+// SetStatus sets the presence of the user specified by the id, email or
+// @mention name, e.g. the user the token belongs to: show is one of the Show
+// constants and status a free text message. The other fields of the user are
+// read first so that they are kept unchanged.
+//
+// HipChat API docs: https://www.hipchat.com/docs/apiv2/method/update_user
+func (u *UserService) SetStatus(id, show, status string) (*http.Response, error) {
+	return u.SetStatusContext(context.Background(), id, show, status)
+}
+
+// SetStatusContext is like SetStatus, with ctx controlling the request.
+func (u *UserService) SetStatusContext(ctx context.Context, id, show, status string) (*http.Response, error) {
+	user, resp, err := u.ViewContext(ctx, id)
+	if err != nil {
+		return resp, err
+	}
+
+	userReq := &UpdateUserRequest{
+		Name:         user.Name,
+		Title:        user.Title,
+		Presence:     UpdateUserPresence{Status: status, Show: show},
+		MentionName:  user.MentionName,
+		IsGroupAdmin: user.IsGroupAdmin,
+		Timezone:     user.Timezone,
+		Email:        user.Email,
+	}
+	return u.UpdateContext(ctx, id, userReq)
 }
@@ -21,13 +21,13 @@ func TestUserShareFile(t *testing.T) {
 	want := "--hipfileboundary\n" +
 		"Content-Type: application/json; charset=UTF-8\n" +
 		"Content-Disposition: attachment; name=\"metadata\"\n\n" +
-		"{\"message\": \"Hello there\"}\n" +
+		"{\"message\":\"Hello there\"}\n" +
 		"--hipfileboundary\n" +
-		"Content-Type:  charset=UTF-8\n" +
+		"Content-Type: application/octet-stream\n" +
 		"Content-Transfer-Encoding: base64\n" +
-		"Content-Disposition: attachment; name=file; filename=hipfile\n\n" +
+		"Content-Disposition: attachment; name=\"file\"; filename=\"hipfile\"\n\n" +
 		"Z28gZ29waGVycw==\n" +
-		"--hipfileboundary\n"
+		"--hipfileboundary--\n"
 
 	mux.HandleFunc("/user/1/share/file", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "POST")
@@ -112,11 +112,15 @@ func TestUserView(t *testing.T) {
 			}`)
 	})
 	want := &User{XmppJid: "1@chat.hipchat.com",
-		IsDeleted:    false,
-		Name:         "First Last",
-		LastActive:   "1421029691",
-		Title:        "Test user",
-		Presence:     UserPresence{Show: "chat", IsOnline: true},
+		IsDeleted:  false,
+		Name:       "First Last",
+		LastActive: "1421029691",
+		Title:      "Test user",
+		Presence: UserPresence{
+			Show:     "chat",
+			IsOnline: true,
+			Client:   &UserPresenceClient{Type: "http://hipchat.com/client/mac", Version: "151"},
+		},
 		Created:      "2013-11-07T17:57:11+00:00",
 		ID:           1,
 		MentionName:  "FirstL",
@@ -125,6 +129,7 @@ func TestUserView(t *testing.T) {
 		IsGuest:      false,
 		Email:        "user@example.com",
 		PhotoURL:     "https://bitbucket-assetroot.s3.amazonaws.com/c/photos/2014/Mar/02/hipchat-pidgin-theme-logo-571708621-0_avatar.png",
+		Group:        &Group{ID: 1234, Name: "Example", Links: Links{Self: "https://api.hipchat.com/v2/group/1234"}},
 		Links:        Links{Self: "https://api.hipchat.com/v2/user/1"}}
 
 	user, _, err := client.User.View("@FirstL")
@@ -189,3 +194,145 @@ func TestUserList(t *testing.T) {
 		t.Errorf("User.List returned %+v, want %+v", users, want)
 	}
 }
+
+func TestUserCreate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	args := &CreateUserRequest{Name: "n", Email: "n@example.com"}
+
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		v := new(CreateUserRequest)
+		json.NewDecoder(r.Body).Decode(v)
+
+		if !reflect.DeepEqual(v, args) {
+			t.Errorf("Request body %+v, want %+v", v, args)
+		}
+		fmt.Fprintf(w, `{"id":1,"links":{"self":"s"}}`)
+	})
+	want := &User{ID: 1, Links: Links{Self: "s"}}
+
+	user, _, err := client.User.Create(args)
+	if err != nil {
+		t.Fatalf("User.Create returns an error %v", err)
+	}
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("User.Create returns %+v, want %+v", user, want)
+	}
+}
+
+func TestUserUpdate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	args := &UpdateUserRequest{Name: "n", Title: "t", Email: "n@example.com"}
+
+	mux.HandleFunc("/user/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PUT")
+		v := new(UpdateUserRequest)
+		json.NewDecoder(r.Body).Decode(v)
+
+		if !reflect.DeepEqual(v, args) {
+			t.Errorf("Request body %+v, want %+v", v, args)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	_, err := client.User.Update("1", args)
+	if err != nil {
+		t.Fatalf("User.Update returns an error %v", err)
+	}
+}
+
+func TestUserDelete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/user/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	_, err := client.User.Delete("1")
+	if err != nil {
+		t.Fatalf("User.Delete returns an error %v", err)
+	}
+}
+
+func TestUserSetStatus(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/user/1", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			fmt.Fprintf(w, `{"id":1,"name":"n","title":"t","mention_name":"m","email":"e","timezone":"UTC"}`)
+		case "PUT":
+			v := new(UpdateUserRequest)
+			json.NewDecoder(r.Body).Decode(v)
+
+			want := &UpdateUserRequest{
+				Name:        "n",
+				Title:       "t",
+				Presence:    UpdateUserPresence{Status: "At lunch", Show: ShowAway},
+				MentionName: "m",
+				Timezone:    "UTC",
+				Email:       "e",
+			}
+			if !reflect.DeepEqual(v, want) {
+				t.Errorf("Request body %+v, want %+v", v, want)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Request method %s, want GET or PUT", r.Method)
+		}
+	})
+
+	_, err := client.User.SetStatus("1", ShowAway, "At lunch")
+	if err != nil {
+		t.Fatalf("User.SetStatus returns an error %v", err)
+	}
+}
+
+func TestUserListWithPage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{
+			"start-index": "100",
+			"max-results": "100",
+		})
+		fmt.Fprintf(w, `
+            {
+              "items": [{"id": 101, "mention_name": "U101", "name": "User 101"}],
+              "startIndex": 100,
+              "maxResults": 100,
+              "links": {
+                "self": "https:\/\/api.hipchat.com\/v2\/user",
+                "prev": "https:\/\/api.hipchat.com\/v2\/user?start-index=0&max-results=100",
+                "next": "https:\/\/api.hipchat.com\/v2\/user?start-index=200&max-results=100"
+              }
+            }`)
+	})
+
+	users, _, err := client.User.ListWithPage(&UserListOptions{ListOptions: ListOptions{StartIndex: 100, MaxResults: 100}})
+	if err != nil {
+		t.Fatalf("User.ListWithPage returned an error %v", err)
+	}
+	want := &Users{
+		Items:      []User{{ID: 101, Name: "User 101", MentionName: "U101"}},
+		StartIndex: 100,
+		MaxResults: 100,
+		Links: PageLinks{
+			Links: Links{Self: "https://api.hipchat.com/v2/user"},
+			Prev:  "https://api.hipchat.com/v2/user?start-index=0&max-results=100",
+			Next:  "https://api.hipchat.com/v2/user?start-index=200&max-results=100",
+		},
+	}
+	if !reflect.DeepEqual(want, users) {
+		t.Errorf("User.ListWithPage returned %+v, want %+v", users, want)
+	}
+}